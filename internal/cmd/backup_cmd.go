@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Backup command flags
+var (
+	backupEnvFile string
+	backupForce   bool
+)
+
+// backupTimeLayout is the timestamp merge adds to backup file names
+const backupTimeLayout = "20060102150405"
+
+// envBackup is a backup of an env file found next to it
+type envBackup struct {
+	Path string    `json:"path"`
+	ID   string    `json:"id"` // Timestamp from the file name, or the file name if it has none
+	Time time.Time `json:"time"`
+	Size int64     `json:"size"`
+}
+
+// backupCmd is the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "List and restore backups of your .env file",
+	Long: `Manage the backups envi leaves next to your .env file, such as the
+.env.bak.TIMESTAMP files merge creates before changing it, and the .env.bak
+file written by 'envi validate --fix'.`,
+}
+
+// backupListCmd lists backups
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List backups of the .env file, newest first",
+	Args:  cobra.NoArgs,
+	Run:   runBackupListCommand,
+}
+
+// backupRestoreCmd restores a backup
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore [TIMESTAMP]",
+	Short: "Copy a backup back to the .env file",
+	Long: `Copy a backup back to the .env file, asking first. Choose the backup by the
+timestamp or file name shown by 'envi backup list'; without one, the newest
+backup is restored.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runBackupRestoreCommand,
+
+	ValidArgsFunction: completeBackupIDs,
+}
+
+// InitBackupCommand sets up the backup command and its subcommands
+func InitBackupCommand() {
+	// Initialize the command flags
+	backupCmd.PersistentFlags().StringVarP(&backupEnvFile, "file", "f", ".env", "Path to the .env file whose backups to manage")
+	backupRestoreCmd.Flags().BoolVar(&backupForce, "force", false, "Restore without asking for confirmation")
+
+	// Add subcommands
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+
+	// Add the backup command to the root command
+	rootCmd.AddCommand(backupCmd)
+}
+
+// runBackupListCommand handles the backup list command execution
+func runBackupListCommand(cmd *cobra.Command, args []string) {
+	backups, err := findBackups(backupEnvFile)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not look for backups: %s", err))
+	}
+
+	if jsonOutput {
+		printJSONResult(map[string]interface{}{"file": backupEnvFile, "backups": backups})
+		return
+	}
+
+	if len(backups) == 0 {
+		fmt.Printf("No backups of %s found\n", backupEnvFile)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIMESTAMP\tDATE\tSIZE\tFILE\t")
+	for _, backup := range backups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", backup.ID, backup.Time.Format("2006-01-02 15:04:05"), formatByteSize(backup.Size), backup.Path)
+	}
+	w.Flush()
+	fmt.Printf("\nRestore one with 'envi backup restore TIMESTAMP'\n")
+}
+
+// runBackupRestoreCommand handles the backup restore command execution
+func runBackupRestoreCommand(cmd *cobra.Command, args []string) {
+	backups, err := findBackups(backupEnvFile)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not look for backups: %s", err))
+	}
+	if len(backups) == 0 {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("No backups of %s found", backupEnvFile))
+	}
+
+	// The newest backup, or the one named by its timestamp or file name
+	backup := backups[0]
+	if len(args) == 1 {
+		found := false
+		for _, candidate := range backups {
+			if args[0] == candidate.ID || args[0] == candidate.Path || args[0] == filepath.Base(candidate.Path) {
+				backup, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("No backup of %s matches %q", backupEnvFile, args[0]),
+				"Run 'envi backup list' to see the available backups")
+		}
+	}
+
+	if !backupForce {
+		if !promptAnswered() && (jsonOutput || !stdinIsTerminal()) {
+			exitWithError(ErrCodeGeneric, "Restoring a backup needs confirmation", "Use --force to restore without asking")
+		}
+		restore, err := confirmPrompt("Restore backup?",
+			fmt.Sprintf("Replace %s with the backup from %s (%s)?", backupEnvFile, backup.Time.Format("2006-01-02 15:04:05"), backup.Path))
+		if err != nil || !restore {
+			fmt.Println("Restore canceled.")
+			return
+		}
+	}
+
+	if err := copyFile(backup.Path, backupEnvFile); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not restore %s: %s", backup.Path, err))
+	}
+
+	printJSONResult(map[string]interface{}{"file": backupEnvFile, "restored": backup})
+	fmt.Printf("Restored %s from %s\n", backupEnvFile, backup.Path)
+}
+
+// findBackups returns the backups of envFile, newest first: FILE.bak.TIMESTAMP and
+// FILE.backup.TIMESTAMP files, and a plain FILE.bak
+func findBackups(envFile string) ([]envBackup, error) {
+	var paths []string
+	for _, pattern := range []string{envFile + ".bak", envFile + ".bak.*", envFile + ".backup.*"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+
+	var backups []envBackup
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		// Use the timestamp in the name when there is one, otherwise when the file was written
+		backup := envBackup{Path: path, ID: filepath.Base(path), Time: info.ModTime(), Size: info.Size()}
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			if t, err := time.ParseInLocation(backupTimeLayout, path[i+1:], time.Local); err == nil {
+				backup.ID, backup.Time = path[i+1:], t
+			}
+		}
+		backups = append(backups, backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// completeBackupIDs completes backup timestamps for backup restore
+func completeBackupIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	backups, err := findBackups(backupEnvFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var suggestions []string
+	for _, backup := range backups {
+		if strings.HasPrefix(backup.ID, toComplete) {
+			suggestions = append(suggestions, backup.ID+"\t"+backup.Time.Format("2006-01-02 15:04:05"))
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+)
+
+// Bookmark command flags
+var (
+	bookmarkGistID string
+)
+
+// bookmarkCmd is the bookmark command
+var bookmarkCmd = &cobra.Command{
+	Use:   "bookmark",
+	Short: "Manage named bookmarks for Gists",
+	Long: `Give Gists short names so you can switch between them without remembering IDs.
+Use a bookmark with any --id flag by prefixing its name with @:
+
+  envi bookmark add staging --id GIST_ID
+  envi pull --id @staging`,
+}
+
+// bookmarkAddCmd adds or replaces a bookmark
+var bookmarkAddCmd = &cobra.Command{
+	Use:   "add NAME",
+	Short: "Bookmark a Gist under a name",
+	Long:  `Save a Gist ID under a name. An existing bookmark with the same name is replaced.`,
+	Args:  cobra.ExactArgs(1),
+	Run:   runBookmarkAddCommand,
+}
+
+// bookmarkListCmd lists bookmarks
+var bookmarkListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List bookmarked Gists",
+	Args:  cobra.NoArgs,
+	Run:   runBookmarkListCommand,
+}
+
+// bookmarkRemoveCmd removes a bookmark
+var bookmarkRemoveCmd = &cobra.Command{
+	Use:     "rm NAME",
+	Aliases: []string{"remove"},
+	Short:   "Remove a bookmark",
+	Long:    `Remove a bookmark. The Gist itself is not changed.`,
+	Args:    cobra.ExactArgs(1),
+	Run:     runBookmarkRemoveCommand,
+
+	ValidArgsFunction: completeBookmarkNames,
+}
+
+// InitBookmarkCommand sets up the bookmark command and its subcommands
+func InitBookmarkCommand() {
+	// Initialize the command flags
+	bookmarkAddCmd.Flags().StringVarP(&bookmarkGistID, "id", "i", "", "GitHub Gist ID to bookmark (defaults to saved Gist)")
+
+	// Add subcommands
+	bookmarkCmd.AddCommand(bookmarkAddCmd)
+	bookmarkCmd.AddCommand(bookmarkListCmd)
+	bookmarkCmd.AddCommand(bookmarkRemoveCmd)
+
+	// Add the bookmark command to the root command
+	rootCmd.AddCommand(bookmarkCmd)
+}
+
+// runBookmarkAddCommand handles the bookmark add command execution
+func runBookmarkAddCommand(cmd *cobra.Command, args []string) {
+	name := strings.TrimPrefix(args[0], "@")
+	if name == "" || strings.ContainsAny(name, " \t") {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid bookmark name %q", args[0]))
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+
+	gistID := bookmarkGistID
+	if gistID == "" {
+		gistID = cfg.LastGistID
+	}
+	if gistID == "" {
+		exitWithError(ErrCodeGeneric, "No Gist ID specified and no saved Gist ID found", "Use 'envi bookmark add NAME --id GIST_ID'")
+	}
+
+	// Allow bookmarking another bookmark's Gist
+	gistID = resolveGistRef(gistID)
+
+	if cfg.Bookmarks == nil {
+		cfg.Bookmarks = make(map[string]string)
+	}
+	previous, replaced := cfg.Bookmarks[name]
+	cfg.Bookmarks[name] = gistID
+
+	if err := config.SaveConfig(cfg); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not save config: %s", err))
+	}
+
+	if replaced && previous != gistID {
+		fmt.Printf("Bookmark @%s now points to Gist %s (was %s)\n", name, gistID, previous)
+	} else {
+		fmt.Printf("Bookmarked Gist %s as @%s\n", gistID, name)
+	}
+}
+
+// runBookmarkListCommand handles the bookmark list command execution
+func runBookmarkListCommand(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+
+	names := make([]string, 0, len(cfg.Bookmarks))
+	for name := range cfg.Bookmarks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if jsonOutput {
+		bookmarks := make(map[string]string, len(cfg.Bookmarks))
+		for name, gistID := range cfg.Bookmarks {
+			bookmarks[name] = gistID
+		}
+		printJSONResult(map[string]interface{}{"bookmarks": bookmarks})
+		return
+	}
+
+	if len(names) == 0 {
+		fmt.Println("No bookmarks saved")
+		fmt.Println("Add one with 'envi bookmark add NAME --id GIST_ID'")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tGIST ID\t")
+	for _, name := range names {
+		gistID := cfg.Bookmarks[name]
+		if gistID == cfg.LastGistID {
+			gistID += " *"
+		}
+		fmt.Fprintf(w, "@%s\t%s\t\n", name, gistID)
+	}
+	w.Flush()
+	fmt.Println("\n* = current Gist")
+}
+
+// runBookmarkRemoveCommand handles the bookmark rm command execution
+func runBookmarkRemoveCommand(cmd *cobra.Command, args []string) {
+	name := strings.TrimPrefix(args[0], "@")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+
+	if _, ok := cfg.Bookmarks[name]; !ok {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("No bookmark named @%s", name), "Run 'envi bookmark list' to see your bookmarks")
+	}
+	delete(cfg.Bookmarks, name)
+
+	if err := config.SaveConfig(cfg); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not save config: %s", err))
+	}
+
+	fmt.Printf("Removed bookmark @%s\n", name)
+}
+
+// resolveGistRef returns the Gist ID for a value given to --id, where "@NAME" refers
+// to a bookmark and a Gist URL to the Gist it links to. Other values must be a Gist ID.
+func resolveGistRef(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ref
+	}
+	if !strings.HasPrefix(ref, "@") && looksLikeGistURL(ref) {
+		gistID, err := gistIDFromURL(ref)
+		if err != nil {
+			exitWithError(ErrCodeGeneric, err.Error(),
+				"Pass a Gist ID, or a link such as https://gist.github.com/USER/ID")
+		}
+		return gistID
+	}
+	if !strings.HasPrefix(ref, "@") {
+		if !gistIDRegex.MatchString(ref) {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("%q is not a Gist ID", ref),
+				"Pass a Gist ID, a Gist URL or an @BOOKMARK")
+		}
+		return ref
+	}
+	name := ref[1:]
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config to resolve %s: %s", ref, err))
+	}
+
+	gistID, ok := cfg.Bookmarks[name]
+	if !ok {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("No bookmark named @%s", name), "Run 'envi bookmark list' to see your bookmarks")
+	}
+	return gistID
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// Check-gitignore command flags
+var (
+	checkGitignoreFix bool
+)
+
+// How git treats an env file
+const (
+	gitUnavailable = "unavailable" // git is not installed
+	gitNoRepo      = "no-repo"     // the file is not inside a git repository
+	gitIgnored     = "ignored"
+	gitNotIgnored  = "not-ignored" // untracked, but would be picked up by 'git add'
+	gitTracked     = "tracked"     // already committed or staged
+)
+
+// checkGitignoreCmd checks that env files are ignored by git
+var checkGitignoreCmd = &cobra.Command{
+	Use:   "check-gitignore [FILE...]",
+	Short: "Check that your .env files are ignored by git",
+	Long: `Check whether env files (default .env) are ignored by git, so secrets can't be
+committed by accident. Git is asked directly, so rules from every .gitignore, the
+repository's info/exclude and your global excludes file are taken into account.
+
+If a file isn't ignored, you are offered to add it to the .gitignore next to it
+(use --fix to add it without asking). A file that is already tracked stays tracked
+until it is removed with 'git rm --cached', and its secrets remain in the
+repository's history.
+
+Exits with a non-zero status if any file is tracked or not ignored. Outside a git
+repository, or without git installed, the check is skipped.`,
+	Run: runCheckGitignoreCommand,
+}
+
+// InitCheckGitignoreCommand sets up the check-gitignore command
+func InitCheckGitignoreCommand() {
+	// Initialize the command flags
+	checkGitignoreCmd.Flags().BoolVar(&checkGitignoreFix, "fix", false, "Add files that aren't ignored to .gitignore without asking")
+
+	// Add the check-gitignore command to the root command
+	rootCmd.AddCommand(checkGitignoreCmd)
+}
+
+// runCheckGitignoreCommand handles the check-gitignore command execution
+func runCheckGitignoreCommand(cmd *cobra.Command, args []string) {
+	files := args
+	if len(files) == 0 {
+		files = []string{".env"}
+	}
+
+	problems := 0
+	for _, file := range files {
+		status, err := gitIgnoreStatus(file)
+		if err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not check %s with git: %s", file, err))
+		}
+
+		switch status {
+		case gitUnavailable:
+			fmt.Println("git is not installed; nothing to check")
+			return
+		case gitNoRepo:
+			fmt.Printf("%s is not in a git repository; nothing to check\n", file)
+		case gitIgnored:
+			fmt.Printf("✓ %s is ignored by git\n", file)
+		case gitTracked:
+			problems++
+			fmt.Printf("✗ %s is tracked by git, so its secrets are in your commits\n", file)
+			fmt.Printf("  Stop tracking it with 'git rm --cached %s', add it to .gitignore and\n", file)
+			fmt.Println("  rotate the secrets it contains, since they remain in the history")
+		case gitNotIgnored:
+			problems++
+			fmt.Printf("✗ %s is not ignored by git and could be committed by accident\n", file)
+			if offerGitignore(file) {
+				problems--
+			}
+		}
+	}
+
+	if problems > 0 {
+		os.Exit(1)
+	}
+}
+
+// offerGitignore adds a file to the .gitignore in its directory, asking first unless
+// --fix is set. It returns true if the file was added.
+func offerGitignore(file string) bool {
+	gitignore := filepath.Join(filepath.Dir(file), ".gitignore")
+	if !checkGitignoreFix {
+		add, err := confirmPrompt("Update .gitignore?", fmt.Sprintf("Add %s to %s?", filepath.Base(file), gitignore))
+		if err != nil || !add {
+			fmt.Printf("  Add %s to %s to keep it out of commits\n", filepath.Base(file), gitignore)
+			return false
+		}
+	}
+
+	if err := appendGitignore(gitignore, filepath.Base(file)); err != nil {
+		fmt.Printf("  Could not update %s: %s\n", gitignore, err)
+		return false
+	}
+	fmt.Printf("  Added %s to %s\n", filepath.Base(file), gitignore)
+	return true
+}
+
+// appendGitignore appends a pattern to a .gitignore file, creating it if needed
+func appendGitignore(path, pattern string) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Start on a new line if the file doesn't end with one
+	entry := pattern + "\n"
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		entry = "\n" + entry
+	}
+	_, err = f.WriteString(entry)
+	return err
+}
+
+// gitIgnoreStatus asks git how it treats a file: tracked, ignored or neither. It
+// reports gitUnavailable or gitNoRepo instead of an error when git can't be asked.
+func gitIgnoreStatus(file string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return gitUnavailable, nil
+	}
+
+	dir, name := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		return gitNoRepo, nil
+	}
+
+	if err := exec.Command("git", "-C", dir, "ls-files", "--error-unmatch", "--", name).Run(); err == nil {
+		return gitTracked, nil
+	}
+
+	// check-ignore exits with 0 if the file is ignored, 1 if it isn't and 128 on errors
+	err := exec.Command("git", "-C", dir, "check-ignore", "-q", "--", name).Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return gitIgnored, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return gitNotIgnored, nil
+	default:
+		return "", err
+	}
+}
+
+// warnIfNotGitIgnored warns when a local env file is tracked by git or not ignored. It
+// never fails, since the check is only advice.
+func warnIfNotGitIgnored(file string) {
+	status, err := gitIgnoreStatus(file)
+	if err != nil {
+		return
+	}
+	switch status {
+	case gitTracked:
+		logWarn("%s is tracked by git, so its secrets are in your commits. Run 'envi check-gitignore %s' for how to fix it.", file, file)
+	case gitNotIgnored:
+		logWarn("%s is not ignored by git and could be committed by accident. Run 'envi check-gitignore %s' to add it to .gitignore.", file, file)
+	}
+}
+
+// pushGitignorePatterns are the files push offers to add to .gitignore: the env file and
+// the backups, key files and temporary files envi can leave next to it
+var pushGitignorePatterns = []string{".env", ".env.backup.*", ".env.bak*", "*.key", ".env.remote.tmp"}
+
+// offerGitignoreAfterPush offers, after the first successful push from a directory, to
+// add pushGitignorePatterns to the .gitignore there. Patterns already listed are left
+// out, and each directory is only asked once, whatever the answer.
+func offerGitignoreAfterPush(dir string) {
+	if !promptAnswered() && (jsonOutput || encryption.ReadsStdin() || !stdinIsTerminal()) {
+		return
+	}
+	if status, err := gitIgnoreStatus(filepath.Join(dir, ".env")); err != nil || status == gitUnavailable || status == gitNoRepo {
+		return
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	offered, err := gitignoreOfferedDirs()
+	if err != nil || offered[absDir] {
+		return
+	}
+
+	gitignore := filepath.Join(dir, ".gitignore")
+	missing := missingGitignorePatterns(gitignore, pushGitignorePatterns)
+	if len(missing) > 0 {
+		add, err := confirmPrompt("Update .gitignore?",
+			fmt.Sprintf("Add %s to %s, so your env file and envi's backups and keys can't be committed?", strings.Join(missing, ", "), gitignore))
+		if err == nil && add {
+			var err error
+			for _, pattern := range missing {
+				if err = appendGitignore(gitignore, pattern); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				logWarn("Could not update %s: %s", gitignore, err)
+			} else {
+				fmt.Printf("Added %s to %s\n", strings.Join(missing, ", "), gitignore)
+			}
+		} else {
+			fmt.Println("Not changing .gitignore. Run 'envi check-gitignore' to check it later.")
+		}
+	}
+
+	if err := recordGitignoreOffer(absDir); err != nil {
+		logWarn("Could not remember the .gitignore choice for %s: %s", absDir, err)
+	}
+}
+
+// missingGitignorePatterns returns the patterns that aren't listed in a .gitignore file,
+// with or without a leading slash
+func missingGitignorePatterns(path string, patterns []string) []string {
+	listed := make(map[string]bool)
+	if content, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			listed[strings.TrimPrefix(strings.TrimSpace(line), "/")] = true
+		}
+	}
+
+	var missing []string
+	for _, pattern := range patterns {
+		if !listed[pattern] {
+			missing = append(missing, pattern)
+		}
+	}
+	return missing
+}
+
+// gitignoreOfferedPath is the file listing the directories push has offered to update
+// the .gitignore of, one per line
+func gitignoreOfferedPath() (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "gitignore-offered"), nil
+}
+
+// gitignoreOfferedDirs returns the directories push has already asked about
+func gitignoreOfferedDirs() (map[string]bool, error) {
+	path, err := gitignoreOfferedPath()
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]bool)
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return dirs, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line != "" {
+			dirs[line] = true
+		}
+	}
+	return dirs, nil
+}
+
+// recordGitignoreOffer remembers that push has asked about a directory
+func recordGitignoreOffer(dir string) error {
+	path, err := gitignoreOfferedPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(dir + "\n")
+	return err
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+)
+
+// Comment command flags
+var (
+	commentGistID string
+	commentBody   string
+)
+
+// gistCommentItem is a comment as printed by 'comment list --json'
+type gistCommentItem struct {
+	ID        int64  `json:"id"`
+	User      string `json:"user"`
+	CreatedAt string `json:"created_at,omitempty"`
+	Body      string `json:"body"`
+}
+
+// commentCmd posts a comment on a Gist
+var commentCmd = &cobra.Command{
+	Use:   "comment",
+	Short: "Comment on a Gist or read its comments",
+	Long: `Post a comment on a Gist, for notes such as "rotated DB password on 2024-01-05"
+that belong with the env file without changing it. Use 'envi comment list' to read
+the comments.
+
+Comments are not encrypted, so don't put secret values in them.`,
+	Args: cobra.NoArgs,
+	Run:  runCommentCommand,
+}
+
+// commentListCmd lists the comments on a Gist
+var commentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the comments on a Gist",
+	Args:  cobra.NoArgs,
+	Run:   runCommentListCommand,
+}
+
+// InitCommentCommand sets up the comment command and its subcommands
+func InitCommentCommand() {
+	// Initialize the command flags
+	commentCmd.PersistentFlags().StringVarP(&commentGistID, "id", "i", "", "GitHub Gist ID or @BOOKMARK (defaults to saved Gist)")
+	commentCmd.Flags().StringVarP(&commentBody, "body", "b", "", "Text of the comment")
+
+	// Add subcommands
+	commentCmd.AddCommand(commentListCmd)
+
+	// Add the comment command to the root command
+	rootCmd.AddCommand(commentCmd)
+}
+
+// runCommentCommand handles the comment command execution
+func runCommentCommand(cmd *cobra.Command, args []string) {
+	if strings.TrimSpace(commentBody) == "" {
+		exitWithError(ErrCodeGeneric, "No comment text given", "Use 'envi comment --body \"...\"', or 'envi comment list' to read comments")
+	}
+
+	client, gistID := commentClient(cmd)
+
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	var comment *github.GistComment
+	err := withSpinner("Posting comment...", func() error {
+		var err error
+		comment, _, err = client.Gists.CreateComment(ctx, gistID, &github.GistComment{Body: github.String(commentBody)})
+		return err
+	})
+	if err != nil {
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not comment on Gist %s: %s", gistID, apiError(err)))
+	}
+
+	fmt.Printf("Posted comment on Gist %s\n", gistID)
+	fmt.Printf("Comments: %s#comments\n", gistWebURL(gistID))
+
+	printJSONResult(map[string]interface{}{
+		"gist_id":    gistID,
+		"comment_id": comment.GetID(),
+	})
+}
+
+// runCommentListCommand handles the comment list command execution
+func runCommentListCommand(cmd *cobra.Command, args []string) {
+	client, gistID := commentClient(cmd)
+
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	var comments []*github.GistComment
+	err := withSpinner("Fetching comments...", func() error {
+		var err error
+		comments, err = listGistComments(ctx, client, gistID)
+		return err
+	})
+	if err != nil {
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not list comments on Gist %s: %s", gistID, apiError(err)))
+	}
+
+	if jsonOutput {
+		items := make([]gistCommentItem, 0, len(comments))
+		for _, comment := range comments {
+			item := gistCommentItem{
+				ID:   comment.GetID(),
+				User: comment.GetUser().GetLogin(),
+				Body: comment.GetBody(),
+			}
+			if comment.CreatedAt != nil {
+				item.CreatedAt = comment.CreatedAt.Format(time.RFC3339)
+			}
+			items = append(items, item)
+		}
+		printJSONResult(map[string]interface{}{
+			"gist_id":  gistID,
+			"comments": items,
+		})
+		return
+	}
+
+	if len(comments) == 0 {
+		fmt.Printf("No comments on Gist %s\n", gistID)
+		return
+	}
+
+	for i, comment := range comments {
+		if i > 0 {
+			fmt.Println()
+		}
+		date := "unknown date"
+		if comment.CreatedAt != nil {
+			date = comment.CreatedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%s on %s:\n", comment.GetUser().GetLogin(), date)
+		for _, line := range strings.Split(strings.TrimRight(comment.GetBody(), "\n"), "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+}
+
+// commentClient returns a GitHub client and the Gist ID to use, from --id or the saved Gist
+func commentClient(cmd *cobra.Command) (*github.Client, string) {
+	token, err := config.GetGitHubToken()
+	if err != nil {
+		exitWithError(ErrCodeNoToken, err.Error())
+	}
+
+	gistID := resolveGistRef(commentGistID)
+	if gistID == "" {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			logWarn("Could not load config: %s", err)
+		} else {
+			gistID = cfg.LastGistID
+		}
+	}
+	if gistID == "" {
+		exitWithError(ErrCodeGeneric, "No Gist ID specified and no saved Gist ID found", "Use 'envi comment --id GIST_ID'")
+	}
+
+	return newGitHubClient(cmd.Context(), token), gistID
+}
+
+// listGistComments fetches every comment on a Gist, oldest first
+func listGistComments(ctx context.Context, client *github.Client, gistID string) ([]*github.GistComment, error) {
+	var all []*github.GistComment
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		comments, resp, err := client.Gists.ListComments(ctx, gistID, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, comments...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
 )
 
+// gistFlagNames are the flags that take a Gist ID or @BOOKMARK
+var gistFlagNames = []string{"id", "gist", "other"}
+
 var completionCmd = &cobra.Command{
 	Use:   "completion [bash|zsh|fish|powershell]",
 	Short: "Generate shell completion scripts",
@@ -63,4 +71,78 @@ PowerShell:
 // InitCompletionCommand initializes the completion command
 func InitCompletionCommand() {
 	rootCmd.AddCommand(completionCmd)
-} 
\ No newline at end of file
+
+	// Every other command is set up by now, so their Gist flags can be found
+	registerGistCompletions(rootCmd)
+}
+
+// registerGistCompletions completes Gist IDs and bookmarks for the Gist flags of a
+// command and its subcommands
+func registerGistCompletions(cmd *cobra.Command) {
+	for _, name := range gistFlagNames {
+		if cmd.Flags().Lookup(name) != nil || cmd.PersistentFlags().Lookup(name) != nil {
+			// Subcommands see a parent's persistent flag, which is already registered
+			_ = cmd.RegisterFlagCompletionFunc(name, completeGistRefs)
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		registerGistCompletions(sub)
+	}
+}
+
+// completeGistRefs suggests the saved Gist, bookmarks as @NAME, and Gists synced on
+// this machine. It only reads local files, so completion never waits for GitHub.
+func completeGistRefs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Completion skips PersistentPreRun, so --config-dir is applied here
+	config.SetBaseDir(configDir)
+	cfg := peekConfig()
+	seen := make(map[string]bool)
+	var suggestions []string
+	add := func(value, description string) {
+		if seen[value] || !strings.HasPrefix(value, toComplete) {
+			return
+		}
+		seen[value] = true
+		suggestions = append(suggestions, value+"\t"+description)
+	}
+
+	if cfg != nil {
+		for _, name := range sortedBookmarkNames(cfg) {
+			add("@"+name, fmt.Sprintf("bookmark for %s", cfg.Bookmarks[name]))
+		}
+		if cfg.LastGistID != "" {
+			add(cfg.LastGistID, "saved Gist")
+		}
+	}
+	for _, gistID := range syncedGistIDs() {
+		add(gistID, "synced Gist")
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeBookmarkNames suggests bookmark names for commands that take one as an argument
+func completeBookmarkNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// Completion skips PersistentPreRun, so --config-dir is applied here
+	config.SetBaseDir(configDir)
+	cfg := peekConfig()
+	if len(args) > 0 || cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var suggestions []string
+	for _, name := range sortedBookmarkNames(cfg) {
+		if strings.HasPrefix(name, strings.TrimPrefix(toComplete, "@")) {
+			suggestions = append(suggestions, name+"\t"+cfg.Bookmarks[name])
+		}
+	}
+	return suggestions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// sortedBookmarkNames returns the bookmark names in cfg in alphabetical order
+func sortedBookmarkNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Bookmarks))
+	for name := range cfg.Bookmarks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
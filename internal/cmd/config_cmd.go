@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
 )
 
 // Configuration command variables
@@ -22,16 +24,95 @@ var (
 	configDefaultKeyFile   string
 	configUseKeyFileByDefault bool
 	configDisableEncryption bool
+	rotateToken            string
+	configPlaintextSecrets string
+	configDescriptionTemplate string
+	configNoReadme         bool
+	configNoSaveID         bool
+	configNoGitignoreOffer bool
+	configTimeFormat       string
+	configValidate         bool
+	configGPGRecipient     string
 )
 
 // configCmd is the configuration command
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Configure the Envi CLI",
-	Long:  `Configure Envi CLI settings including your GitHub token and default Gist ID.`,
+	Long: `Configure Envi CLI settings including your GitHub token and default Gist ID.
+
+Use --validate to check a hand-edited config file without changing it: it reports
+YAML errors with their line, setting values envi can't use, such as a key file that
+doesn't exist, and unknown settings, which are usually typos.`,
 	Run:   runConfigCommand,
 }
 
+// configExportCmd writes the portable config to a file
+var configExportCmd = &cobra.Command{
+	Use:   "export [FILE]",
+	Short: "Export settings to a portable file",
+	Long: `Write your envi settings to a file that can be copied to another machine.
+The GitHub token is never included; set it separately on the new machine.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runConfigExportCommand,
+}
+
+// configImportCmd loads settings from an exported file
+var configImportCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Import settings from an exported file",
+	Long:  `Load envi settings from a file created with 'envi config export'. Your current GitHub token is kept.`,
+	Args:  cobra.ExactArgs(1),
+	Run:   runConfigImportCommand,
+}
+
+// configRotateTokenCmd replaces the stored GitHub token after checking the new one works
+var configRotateTokenCmd = &cobra.Command{
+	Use:   "rotate-token",
+	Short: "Replace the stored GitHub token with a new one",
+	Long: `Replace the stored GitHub token. The new token is checked against the GitHub API
+before the old one is replaced, so a mistyped token can't lock you out. It is stored
+in the same place as the current token.`,
+	Run: runConfigRotateTokenCommand,
+}
+
+// configGetCmd prints one setting
+var configGetCmd = &cobra.Command{
+	Use:   "get SETTING",
+	Short: "Print the value of a setting",
+	Long: `Print the value of a setting by its name in the config file, such as
+encrypt_by_default, default_key_file or last_gist_id. The GitHub token is never
+printed; only whether one is stored.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runConfigGetCommand,
+
+	ValidArgsFunction: completeSettingNames,
+}
+
+// configSetCmd changes one setting
+var configSetCmd = &cobra.Command{
+	Use:   "set SETTING VALUE",
+	Short: "Change a setting",
+	Long: `Change a setting by its name in the config file. Yes/no settings take true or
+false (or yes, no, on, off, 1, 0), and an empty value clears a text setting. Values
+are checked like 'envi config --validate' does before anything is saved.
+
+The token and bookmarks have their own commands: 'envi config --token' and
+'envi bookmark'.`,
+	Args: cobra.ExactArgs(2),
+	Run:  runConfigSetCommand,
+
+	ValidArgsFunction: completeSettingNames,
+}
+
+// configListCmd prints every setting
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print all settings and their values",
+	Args:  cobra.NoArgs,
+	Run:   runConfigListCommand,
+}
+
 // InitConfigCommand sets up the config command and its subcommands
 func InitConfigCommand() {
 	// Initialize the command flags
@@ -42,8 +123,26 @@ func InitConfigCommand() {
 	configCmd.Flags().BoolVar(&configEncryptByDefault, "encrypt-by-default", false, "Enable full encryption by default (entire file encrypted)")
 	configCmd.Flags().BoolVar(&configUnmaskByDefault, "unmask-by-default", false, "Automatically unmask/decrypt values when pulling (otherwise they remain encrypted)")
 	configCmd.Flags().StringVar(&configDefaultKeyFile, "default-key-file", "", "Set the default encryption key file path")
+	configCmd.Flags().StringVar(&configGPGRecipient, "gpg-recipient", "", "Encrypt a key file generated for --default-key-file to this GPG key (the file name must end in .gpg or .asc)")
 	configCmd.Flags().BoolVar(&configUseKeyFileByDefault, "use-key-file", false, "Use key file by default instead of password for encryption")
 	configCmd.Flags().BoolVar(&configDisableEncryption, "disable-encryption", false, "Disable encryption by default")
+	configCmd.Flags().StringVar(&configPlaintextSecrets, "plaintext-secrets", "", "What push does with unencrypted secrets: warn, block or allow")
+	configCmd.Flags().BoolVar(&configNoReadme, "no-readme", false, "Don't add a README to Gists with encrypted content (--no-readme=false to add it again)")
+	configCmd.Flags().BoolVar(&configNoSaveID, "no-save-id", false, "Don't save the Gist used by push and pull as the default (--no-save-id=false to save it again)")
+	configCmd.Flags().BoolVar(&configNoGitignoreOffer, "no-gitignore-offer", false, "Don't offer to update .gitignore after the first push from a directory (--no-gitignore-offer=false to offer again)")
+	configCmd.Flags().StringVar(&configTimeFormat, "time-format", "", "How list shows dates: relative, absolute or rfc3339")
+	configCmd.Flags().BoolVar(&configValidate, "validate", false, "Check the config file for errors and unknown settings without changing it")
+	configCmd.Flags().StringVar(&configDescriptionTemplate, "description-template", "", "Default description template for new Gists, e.g. \"Environment variables for {project} ({date})\"")
+
+	// Add subcommands
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+	configRotateTokenCmd.Flags().StringVarP(&rotateToken, "token", "t", "", "The new GitHub personal access token")
+	configRotateTokenCmd.MarkFlagRequired("token")
+	configCmd.AddCommand(configRotateTokenCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
 
 	// Add the config command to the root command
 	rootCmd.AddCommand(configCmd)
@@ -51,10 +150,20 @@ func InitConfigCommand() {
 
 // runConfigCommand handles the config command execution
 func runConfigCommand(cmd *cobra.Command, args []string) {
+	// Check the file before loading it, since loading fails on the errors it reports
+	if configValidate {
+		runConfigValidate()
+		return
+	}
+	if configGPGRecipient != "" && !encryption.IsGPGKeyFile(configDefaultKeyFile) {
+		exitWithError(ErrCodeGeneric, "--gpg-recipient needs --default-key-file with a name ending in .gpg or .asc",
+			"For example: envi config --default-key-file envi.key.gpg --gpg-recipient you@example.com")
+	}
+	
 	// Load existing config
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Error loading config: %s\n", err)
+		fmt.Fprintf(os.Stderr, "Error loading config: %s\n", err)
 		return
 	}
 	
@@ -62,56 +171,18 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 	if configToken != "" {
 		// Validate token format first
 		if !config.IsValidGitHubToken(configToken) {
-			fmt.Println("Error: The GitHub token you provided doesn't appear to be valid.")
-			fmt.Println("GitHub tokens should be at least 30 characters and follow specific formats.")
-			fmt.Println("Please check your token and try again.")
+			fmt.Fprintln(os.Stderr, "Error: The GitHub token you provided doesn't appear to be valid.")
+			fmt.Fprintln(os.Stderr, "Expected a classic token (ghp_ plus 36 characters), a fine-grained token (github_pat_...), or a 40-character legacy token.")
+			fmt.Fprintln(os.Stderr, "Please check your token and try again.")
 			return
 		}
 		
-		// Decide on storage method based on flags and capabilities
-		if configForceFileStorage {
-			cfg.GitHubToken = configToken
-			cfg.TokenInKeyring = false
-			fmt.Println("GitHub token stored in config file as requested.")
-			fmt.Println("Warning: This is less secure than system credential storage.")
-		} else {
-			// Try to store in keyring first
-			if err := config.SaveTokenToKeyring(configToken); err != nil {
-				fmt.Printf("Error storing token in system credentials: %s\n", err)
-				fmt.Println("Would you like to store the token in the config file instead? (y/N)")
-				
-				// Read user input
-				var response string
-				fmt.Scanln(&response)
-				
-				if response == "y" || response == "Y" {
-					cfg.GitHubToken = configToken
-					cfg.TokenInKeyring = false
-					fmt.Println("GitHub token stored in config file.")
-					fmt.Println("Warning: This is less secure than system credential storage.")
-				} else {
-					fmt.Println("Token not saved. You can try again or use environment variables.")
-					return
-				}
-			} else {
-				// Clear token from config file if successfully stored in keyring
-				if cfg.GitHubToken != "" {
-					// Securely wipe the token first
-					tempConfig := *cfg
-					tempConfig.GitHubToken = ""
-					if err := config.SaveConfig(&tempConfig); err != nil {
-						fmt.Printf("Warning: Could not securely remove old token from config: %s\n", err)
-					}
-				}
-				
-				cfg.GitHubToken = ""
-				cfg.TokenInKeyring = true
-				fmt.Println("GitHub token securely stored in system credential manager")
-			}
+		if !storeGitHubToken(cfg, configToken, configForceFileStorage) {
+			return
 		}
 		
 		if err := config.SaveConfig(cfg); err != nil {
-			fmt.Printf("Error saving config: %s\n", err)
+			fmt.Fprintf(os.Stderr, "Error saving config: %s\n", err)
 			return
 		}
 	}
@@ -123,7 +194,7 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 		// First try to remove from keyring
 		if cfg.TokenInKeyring {
 			if err := config.DeleteTokenFromKeyring(); err != nil {
-				fmt.Printf("Warning: Could not remove token from secure storage: %s\n", err)
+				logWarn("Could not remove token from secure storage: %s", err)
 			} else {
 				fmt.Println("GitHub token removed from secure storage")
 				successful = true
@@ -137,7 +208,7 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 			tempConfig := *cfg
 			tempConfig.GitHubToken = ""
 			if err := config.SaveConfig(&tempConfig); err != nil {
-				fmt.Printf("Warning: Could not securely wipe token: %s\n", err)
+				logWarn("Could not securely wipe token: %s", err)
 			}
 			
 			cfg.GitHubToken = ""
@@ -146,7 +217,7 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 		}
 		
 		if err := config.SaveConfig(cfg); err != nil {
-			fmt.Printf("Error saving config: %s\n", err)
+			fmt.Fprintf(os.Stderr, "Error saving config: %s\n", err)
 			return
 		}
 		
@@ -166,7 +237,7 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 			cfg.LastGistID = ""
 			
 			if err := config.SaveConfig(cfg); err != nil {
-				fmt.Printf("Error saving config: %s\n", err)
+				fmt.Fprintf(os.Stderr, "Error saving config: %s\n", err)
 				return
 			}
 			
@@ -181,7 +252,7 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 		fmt.Println("Full encryption will be enabled by default")
 		
 		if err := config.SaveConfig(cfg); err != nil {
-			fmt.Printf("Error saving config: %s\n", err)
+			fmt.Fprintf(os.Stderr, "Error saving config: %s\n", err)
 			return
 		}
 	}
@@ -195,7 +266,7 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 			fmt.Println("Masked encryption enabled by default")
 			
 			if err := config.SaveConfig(cfg); err != nil {
-				fmt.Printf("Error saving config: %s\n", err)
+				fmt.Fprintf(os.Stderr, "Error saving config: %s\n", err)
 				return
 			}
 		}
@@ -207,7 +278,7 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 		fmt.Println("Values will be automatically unmasked when pulling")
 		
 		if err := config.SaveConfig(cfg); err != nil {
-			fmt.Printf("Error saving config: %s\n", err)
+			fmt.Fprintf(os.Stderr, "Error saving config: %s\n", err)
 			return
 		}
 	}
@@ -218,26 +289,111 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 		fmt.Println("Encryption has been disabled by default")
 		
 		if err := config.SaveConfig(cfg); err != nil {
-			fmt.Printf("Error saving config: %s\n", err)
+			fmt.Fprintf(os.Stderr, "Error saving config: %s\n", err)
 			return
 		}
 	}
 	
+	if configPlaintextSecrets != "" {
+		switch configPlaintextSecrets {
+		case config.PlaintextSecretsWarn, config.PlaintextSecretsBlock, config.PlaintextSecretsAllow:
+			cfg.PlaintextSecrets = configPlaintextSecrets
+			fmt.Printf("Pushing unencrypted secrets set to: %s\n", configPlaintextSecrets)
+		default:
+			fmt.Fprintln(os.Stderr, "Error: --plaintext-secrets must be warn, block or allow")
+			return
+		}
+	}
+	
+	if configTimeFormat != "" {
+		if !validTimeFormat(configTimeFormat) {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid time format %q (use relative, absolute or rfc3339)", configTimeFormat))
+		}
+		cfg.TimeFormat = configTimeFormat
+		fmt.Printf("Time format set to: %s\n", configTimeFormat)
+	}
+	
+	// --cipher is the global encryption flag; here it sets the default
+	if cmd.Flags().Changed("cipher") {
+		if !encryption.ValidCipher(encryption.CipherName) {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid cipher %q (use %s)", encryption.CipherName, strings.Join(encryption.CipherNames(), " or ")))
+		}
+		cfg.Cipher = encryption.CipherName
+		fmt.Printf("Encryption cipher set to: %s\n", encryption.CipherName)
+	}
+	
+	// --github-url is the global flag, already checked and normalized; here it sets the default
+	if cmd.Flags().Changed("github-url") {
+		cfg.GitHubURL = githubURL
+		if githubURL == "" {
+			fmt.Println("GitHub server set to: public GitHub")
+		} else {
+			fmt.Printf("GitHub server set to: %s\n", githubURL)
+		}
+	}
+	
+	if cmd.Flags().Changed("no-readme") {
+		cfg.NoReadme = configNoReadme
+		if configNoReadme {
+			fmt.Println("README files will no longer be added to encrypted Gists")
+		} else {
+			fmt.Println("README files will be added to encrypted Gists")
+		}
+	}
+	
+	if cmd.Flags().Changed("no-save-id") {
+		cfg.NoSaveID = configNoSaveID
+		if configNoSaveID {
+			fmt.Println("Push and pull will no longer save the Gist they use as the default")
+		} else {
+			fmt.Println("Push and pull will save the Gist they use as the default")
+		}
+	}
+	
+	if cmd.Flags().Changed("no-gitignore-offer") {
+		cfg.NoGitignoreOffer = configNoGitignoreOffer
+		if configNoGitignoreOffer {
+			fmt.Println("Push will no longer offer to update .gitignore")
+		} else {
+			fmt.Println("Push will offer to update .gitignore after the first push from a directory")
+		}
+	}
+	
+	if cmd.Flags().Changed("description-template") {
+		cfg.DescriptionTemplate = configDescriptionTemplate
+		if configDescriptionTemplate == "" {
+			fmt.Println("Description template cleared")
+		} else {
+			fmt.Printf("Description template set to: %s\n", configDescriptionTemplate)
+		}
+	}
+	
 	if configDefaultKeyFile != "" {
 		cfg.DefaultKeyFile = configDefaultKeyFile
 		cfg.UseKeyFileByDefault = true
 		fmt.Printf("Default encryption key file set to: %s\n", configDefaultKeyFile)
 		
-		// Check if the key file exists, if not, ask to generate it
-		if _, err := os.Stat(configDefaultKeyFile); os.IsNotExist(err) {
-			fmt.Printf("Key file %s does not exist. Generate it? (y/n): ", configDefaultKeyFile)
-			var response string
-			fmt.Scanln(&response)
+		// Check if the key file exists, if not, ask to generate it. A bare file name
+		// refers to the data directory.
+		keyFilePath := encryption.ResolveKeyFile(configDefaultKeyFile)
+		if _, err := os.Stat(keyFilePath); os.IsNotExist(err) && encryption.IsGPGKeyFile(keyFilePath) && configGPGRecipient == "" {
+			fmt.Printf("Key file %s does not exist. To generate it encrypted with GPG, add --gpg-recipient YOUR_KEY_ID.\n", keyFilePath)
+		} else if os.IsNotExist(err) {
+			generate, err := confirmPrompt("Generate key file?", fmt.Sprintf("Key file %s does not exist. Generate it?", keyFilePath))
+			if err != nil {
+				fmt.Printf("Not generating key file: %s\n", err)
+			}
 			
-			if strings.ToLower(response) == "y" {
-				// TODO: Implement key generation - for now, just output a message
-				fmt.Println("Key generation functionality will be implemented in a future version")
-				fmt.Println("You can manually create a key file at this location")
+			if generate {
+				generateKeyFile := encryption.GenerateKeyFile
+				if configGPGRecipient != "" {
+					generateKeyFile = func(path string) error { return encryption.GenerateGPGKeyFile(path, configGPGRecipient) }
+				}
+				if err := generateKeyFile(keyFilePath); err != nil {
+					fmt.Fprintf(os.Stderr, "Error generating key file: %s\n", err)
+				} else {
+					fmt.Printf("Generated new key file at %s. Keep it safe; it is needed to decrypt your files.\n", keyFilePath)
+				}
 			}
 		}
 	}
@@ -247,12 +403,12 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 		fmt.Println("Key file will be used by default for encryption/decryption")
 		
 		if cfg.DefaultKeyFile == "" {
-			// Set default path
-			homeDir, err := os.UserHomeDir()
+			// Set default path in the data directory
+			dataDir, err := config.DataDir()
 			if err != nil {
-				fmt.Printf("Error getting home directory: %s\n", err)
+				fmt.Fprintf(os.Stderr, "Error getting data directory: %s\n", err)
 			} else {
-				cfg.DefaultKeyFile = filepath.Join(homeDir, ".envi", ".envi.key")
+				cfg.DefaultKeyFile = filepath.Join(dataDir, ".envi.key")
 				fmt.Printf("Default key file set to: %s\n", cfg.DefaultKeyFile)
 			}
 		}
@@ -261,7 +417,9 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 	// If no flags provided, show current configuration
 	if !cmd.Flags().Changed("token") && !configClearGistID && !configClearToken && 
 	   !configEncryptByDefault && !configUnmaskByDefault && !configDisableEncryption && 
-	   configDefaultKeyFile == "" && !configUseKeyFileByDefault && !configForceFileStorage {
+	   configDefaultKeyFile == "" && !configUseKeyFileByDefault && !configForceFileStorage &&
+	   configPlaintextSecrets == "" && !cmd.Flags().Changed("description-template") && !cmd.Flags().Changed("no-readme") && !cmd.Flags().Changed("no-save-id") && !cmd.Flags().Changed("no-gitignore-offer") && configTimeFormat == "" &&
+	   !cmd.Flags().Changed("cipher") && !cmd.Flags().Changed("github-url") {
 		
 		// Show current configuration
 		showCurrentConfig(cfg)
@@ -270,7 +428,7 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 	
 	// Save configuration after all changes
 	if err := config.SaveConfig(cfg); err != nil {
-		fmt.Printf("Error saving config: %s\n", err)
+		fmt.Fprintf(os.Stderr, "Error saving config: %s\n", err)
 		return
 	}
 	
@@ -279,6 +437,54 @@ func runConfigCommand(cmd *cobra.Command, args []string) {
 	showCurrentConfig(cfg)
 }
 
+// storeGitHubToken stores a token in the system credential manager, or in the config
+// file with forceFile or if the user agrees when the credential manager fails. It
+// updates cfg without saving it and returns false if the token was not stored.
+func storeGitHubToken(cfg *config.Config, token string, forceFile bool) bool {
+	// Decide on storage method based on flags and capabilities
+	if forceFile {
+		cfg.GitHubToken = token
+		cfg.TokenInKeyring = false
+		fmt.Println("GitHub token stored in config file as requested.")
+		logWarn("This is less secure than system credential storage.")
+	} else {
+		// Try to store in keyring first
+		if err := config.SaveTokenToKeyring(token); err != nil {
+			fmt.Fprintf(os.Stderr, "Error storing token in system credentials: %s\n", err)
+			storeInFile, err := confirmPrompt("Store token in config file?", "Would you like to store the token in the config file instead?")
+			if err != nil {
+				fmt.Printf("Not storing token in config file: %s\n", err)
+			}
+			
+			if storeInFile {
+				cfg.GitHubToken = token
+				cfg.TokenInKeyring = false
+				fmt.Println("GitHub token stored in config file.")
+				logWarn("This is less secure than system credential storage.")
+			} else {
+				fmt.Println("Token not saved. You can try again or use environment variables.")
+				return false
+			}
+		} else {
+			// Clear token from config file if successfully stored in keyring
+			if cfg.GitHubToken != "" {
+				// Securely wipe the token first
+				tempConfig := *cfg
+				tempConfig.GitHubToken = ""
+				if err := config.SaveConfig(&tempConfig); err != nil {
+					logWarn("Could not securely remove old token from config: %s", err)
+				}
+			}
+			
+			cfg.GitHubToken = ""
+			cfg.TokenInKeyring = true
+			fmt.Println("GitHub token securely stored in system credential manager")
+		}
+	}
+	
+	return true
+}
+
 // showCurrentConfig displays the current configuration settings
 func showCurrentConfig(cfg *config.Config) {
 	// Try to get token status
@@ -328,6 +534,13 @@ func showCurrentConfig(cfg *config.Config) {
 	}
 	fmt.Printf("Default Gist ID: %s\n", gistStatus)
 	
+	// Show the GitHub server
+	if cfg.GitHubURL != "" {
+		fmt.Printf("GitHub Server: %s\n", cfg.GitHubURL)
+	} else {
+		fmt.Println("GitHub Server: public GitHub")
+	}
+	
 	// Show encryption settings
 	fmt.Println("\nEncryption Settings:")
 	if cfg.EncryptByDefault {
@@ -342,6 +555,16 @@ func showCurrentConfig(cfg *config.Config) {
 		fmt.Println("    To enable masked encryption (recommended), run 'envi config' with no flags")
 	}
 
+	// Show the plaintext secrets policy
+	switch cfg.PlaintextSecrets {
+	case config.PlaintextSecretsBlock:
+		fmt.Println("  ✓ Pushing unencrypted secrets is blocked")
+	case config.PlaintextSecretsAllow:
+		fmt.Println("  • Unencrypted secrets are pushed without a warning")
+	default:
+		fmt.Println("  • You will be asked before unencrypted secrets are pushed")
+	}
+
 	// Show unmask by default setting
 	if cfg.UnmaskByDefault {
 		fmt.Println("  ✓ Values will be automatically unmasked when pulling")
@@ -355,12 +578,9 @@ func showCurrentConfig(cfg *config.Config) {
 			fmt.Printf("  • Default key file: %s\n", cfg.DefaultKeyFile)
 			
 			// Check if the key file exists
-			keyFilePath := cfg.DefaultKeyFile
-			if strings.HasPrefix(keyFilePath, "~/") {
-				homeDir, err := os.UserHomeDir()
-				if err == nil {
-					keyFilePath = filepath.Join(homeDir, keyFilePath[2:])
-				}
+			keyFilePath := encryption.ResolveKeyFile(cfg.DefaultKeyFile)
+			if keyFilePath != cfg.DefaultKeyFile {
+				fmt.Printf("  • Resolves to: %s\n", keyFilePath)
 			}
 			
 			if _, err := os.Stat(keyFilePath); err == nil {
@@ -375,6 +595,30 @@ func showCurrentConfig(cfg *config.Config) {
 		fmt.Println("  • Using password-based encryption")
 	}
 	
+	if cfg.NoReadme {
+		fmt.Println("  • No README is added to encrypted Gists")
+	}
+	
+	if cfg.NoSaveID {
+		fmt.Println("  • Push and pull don't save the Gist they use as the default")
+	}
+	
+	if cfg.NoGitignoreOffer {
+		fmt.Println("  • Push doesn't offer to update .gitignore")
+	}
+	
+	if cfg.Cipher != "" {
+		fmt.Printf("  • Cipher: %s\n", cfg.Cipher)
+	}
+	
+	if cfg.DescriptionTemplate != "" {
+		fmt.Printf("\nGist description template: %s\n", cfg.DescriptionTemplate)
+	}
+	
+	if cfg.TimeFormat != "" {
+		fmt.Printf("\nTime format: %s\n", cfg.TimeFormat)
+	}
+	
 	if cfg.LastGistID != "" {
 		fmt.Println("\nTo use the saved Gist ID:")
 		fmt.Println("  envi push              # will prompt to use the saved ID")
@@ -424,4 +668,272 @@ func showCurrentConfig(cfg *config.Config) {
 	fmt.Println("  envi config --disable-encryption        # Don't encrypt by default")
 	fmt.Println("  envi config --default-key-file ~/.envi.key # Set default key file")
 	fmt.Println("  envi config --use-key-file              # Use key file by default")
-} 
\ No newline at end of file
+} 
+
+// runConfigExportCommand handles the config export subcommand
+func runConfigExportCommand(cmd *cobra.Command, args []string) {
+	outputPath := "envi-config.yaml"
+	if len(args) > 0 {
+		outputPath = args[0]
+	}
+	
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+	
+	data, err := config.ExportConfig(cfg)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, err.Error())
+	}
+	
+	if err := os.WriteFile(outputPath, data, 0600); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not write %s: %s", outputPath, err))
+	}
+	
+	fmt.Printf("Settings exported to %s\n", outputPath)
+	fmt.Println("Note: Your GitHub token is not included. Set it on the new machine with 'envi config --token YOUR_TOKEN'.")
+}
+
+// runConfigImportCommand handles the config import subcommand
+func runConfigImportCommand(cmd *cobra.Command, args []string) {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not read %s: %s", args[0], err))
+	}
+	
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+	
+	if err := config.ImportConfig(cfg, data); err != nil {
+		exitWithError(ErrCodeGeneric, err.Error())
+	}
+	
+	if err := config.SaveConfig(cfg); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not save config: %s", err))
+	}
+	
+	fmt.Printf("Settings imported from %s\n", args[0])
+	if cfg.GitHubToken == "" && !cfg.TokenInKeyring {
+		fmt.Println("Reminder: No GitHub token is configured. Set one with 'envi config --token YOUR_TOKEN'.")
+	}
+}
+
+// runConfigRotateTokenCommand handles the config rotate-token subcommand
+func runConfigRotateTokenCommand(cmd *cobra.Command, args []string) {
+	if !config.IsValidGitHubToken(rotateToken) {
+		exitWithError(ErrCodeGeneric, "The new GitHub token doesn't appear to be valid.",
+			"Expected a classic token (ghp_ plus 36 characters), a fine-grained token (github_pat_...), or a 40-character legacy token.")
+	}
+	
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+	
+	// Check the new token works before replacing the old one
+	client := newGitHubClient(cmd.Context(), rotateToken)
+	
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	user, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		exitWithError(ErrCodeAPI, fmt.Sprintf("The new token was rejected by GitHub: %s", apiError(err)), "The stored token was not changed.")
+	}
+	fmt.Printf("New token authenticated as %s\n", user.GetLogin())
+	
+	if scopes, reported := tokenScopes(resp); reported && !hasScope(scopes, "gist") {
+		logWarn("The new token is missing the gist scope. Pushing and pulling will fail with 403 errors.")
+	}
+	
+	// Store the new token where the current one lives, preferring the keyring
+	if !cfg.TokenInKeyring && cfg.GitHubToken != "" {
+		cfg.GitHubToken = rotateToken
+		fmt.Println("GitHub token updated in config file.")
+	} else {
+		if err := config.SaveTokenToKeyring(rotateToken); err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not store token in system credentials: %s", err), "The stored token was not changed.")
+		}
+		cfg.TokenInKeyring = true
+		fmt.Println("GitHub token updated in system credential manager.")
+	}
+	
+	if err := config.SaveConfig(cfg); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not save config: %s", err))
+	}
+	
+	// GitHub has no API for a token to revoke a personal access token, so this step is manual
+	fmt.Println("\nThe previous token is still valid on GitHub.")
+	fmt.Println("Personal access tokens can't be revoked through the API; delete the old one at " + githubWebURL("settings/tokens"))
+	
+	if os.Getenv("GITHUB_TOKEN") != "" {
+		fmt.Println("Note: GITHUB_TOKEN is set in your environment and takes precedence over the stored token.")
+	}
+}
+
+// runConfigValidate checks the config file and reports what is wrong with it, exiting
+// with an error if it can't be used as it is
+func runConfigValidate() {
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not determine the config file location: %s", err))
+	}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		printJSONResult(map[string]interface{}{"file": configPath, "valid": true, "errors": []string{}, "warnings": []string{}})
+		fmt.Printf("No config file at %s; envi creates one with the default settings when needed\n", configPath)
+		return
+	} else if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not read %s: %s", configPath, err))
+	}
+	
+	var problems []string
+	cfg, err := config.ReadConfig(configPath)
+	if err != nil {
+		var parseErr *config.ParseError
+		if !errors.As(err, &parseErr) {
+			exitWithError(ErrCodeGeneric, err.Error())
+		}
+		problems = append(problems, parseErr.Details)
+	} else {
+		problems = configValueProblems(cfg)
+	}
+	warnings := []string{}
+	for _, setting := range config.UnknownSettings(data) {
+		warnings = append(warnings, setting.String())
+	}
+	
+	printJSONResult(map[string]interface{}{"file": configPath, "valid": len(problems) == 0, "errors": nonNil(problems), "warnings": warnings})
+	if !jsonOutput {
+		fmt.Printf("Checking %s\n", configPath)
+		for _, problem := range problems {
+			fmt.Printf("  ✗ %s\n", problem)
+		}
+		for _, warning := range warnings {
+			fmt.Printf("  ! %s\n", warning)
+		}
+		if len(problems) == 0 && len(warnings) == 0 {
+			fmt.Println("✓ The config file is valid")
+		} else {
+			fmt.Printf("\n%d error(s), %d warning(s)\n", len(problems), len(warnings))
+		}
+	}
+	
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// configValueProblems returns the settings in a parsed config that envi can't use
+func configValueProblems(cfg *config.Config) []string {
+	var problems []string
+	switch cfg.PlaintextSecrets {
+	case "", config.PlaintextSecretsWarn, config.PlaintextSecretsBlock, config.PlaintextSecretsAllow:
+	default:
+		problems = append(problems, fmt.Sprintf("plaintext_secrets: %q should be warn, block or allow", cfg.PlaintextSecrets))
+	}
+	if cfg.TimeFormat != "" && !validTimeFormat(cfg.TimeFormat) {
+		problems = append(problems, fmt.Sprintf("time_format: %q should be relative, absolute or rfc3339", cfg.TimeFormat))
+	}
+	if cfg.Cipher != "" && !encryption.ValidCipher(cfg.Cipher) {
+		problems = append(problems, fmt.Sprintf("cipher: %q should be %s", cfg.Cipher, strings.Join(encryption.CipherNames(), " or ")))
+	}
+	if _, err := normalizeGitHubURL(cfg.GitHubURL); err != nil {
+		problems = append(problems, "github_url: "+err.Error())
+	}
+	if cfg.GitHubToken != "" && !config.IsValidGitHubToken(cfg.GitHubToken) {
+		problems = append(problems, "github_token: the token doesn't look like a GitHub token")
+	}
+	if cfg.DefaultKeyFile != "" {
+		if _, err := os.Stat(encryption.ResolveKeyFile(cfg.DefaultKeyFile)); err != nil {
+			problems = append(problems, fmt.Sprintf("default_key_file: %s doesn't exist; create it with 'envi config --default-key-file %s'",
+				encryption.ResolveKeyFile(cfg.DefaultKeyFile), cfg.DefaultKeyFile))
+		}
+	}
+	if problem := updateCheckIntervalProblem(cfg.UpdateCheckInterval); problem != "" {
+		problems = append(problems, "update_check_interval: "+problem)
+	}
+	if cfg.LastGistID != "" && !gistIDRegex.MatchString(cfg.LastGistID) {
+		problems = append(problems, fmt.Sprintf("last_gist_id: %q is not a Gist ID", cfg.LastGistID))
+	}
+	for _, name := range sortedBookmarkNames(cfg) {
+		if !gistIDRegex.MatchString(cfg.Bookmarks[name]) {
+			problems = append(problems, fmt.Sprintf("bookmarks: %s points to %q, which is not a Gist ID", name, cfg.Bookmarks[name]))
+		}
+	}
+	return problems
+}
+
+// runConfigGetCommand handles the config get subcommand
+func runConfigGetCommand(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+	
+	value, err := config.GetSetting(cfg, args[0])
+	if err != nil {
+		exitWithError(ErrCodeGeneric, err.Error(), "Run 'envi config list' to see all settings")
+	}
+	
+	printJSONResult(map[string]interface{}{"setting": args[0], "value": value})
+	if !jsonOutput {
+		fmt.Println(value)
+	}
+}
+
+// runConfigSetCommand handles the config set subcommand
+func runConfigSetCommand(cmd *cobra.Command, args []string) {
+	name, value := args[0], args[1]
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+	
+	if err := config.SetSetting(cfg, name, value); err != nil {
+		exitWithError(ErrCodeGeneric, err.Error())
+	}
+	for _, problem := range configValueProblems(cfg) {
+		if strings.HasPrefix(problem, name+":") {
+			exitWithError(ErrCodeGeneric, problem)
+		}
+	}
+	
+	if err := config.SaveConfig(cfg); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not save config: %s", err))
+	}
+	
+	value, _ = config.GetSetting(cfg, name)
+	printJSONResult(map[string]interface{}{"setting": name, "value": value})
+	fmt.Printf("%s set to: %s\n", name, value)
+}
+
+// runConfigListCommand handles the config list subcommand
+func runConfigListCommand(cmd *cobra.Command, args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+	
+	settings := make(map[string]string)
+	for _, name := range config.KnownSettings() {
+		settings[name], _ = config.GetSetting(cfg, name)
+	}
+	printJSONResult(map[string]interface{}{"settings": settings})
+	if jsonOutput {
+		return
+	}
+	for _, name := range config.KnownSettings() {
+		fmt.Printf("%s=%s\n", name, settings[name])
+	}
+}
+
+// completeSettingNames completes setting names for config get and set
+func completeSettingNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return config.KnownSettings(), cobra.ShellCompDirectiveNoFileComp
+}
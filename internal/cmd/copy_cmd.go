@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+)
+
+// Copy command flags
+var (
+	copyGistID      string
+	copyDescription string
+	copyAll         bool
+	copyUse         bool
+)
+
+// copyCmd is the copy command
+var copyCmd = &cobra.Command{
+	Use:   "copy",
+	Short: "Duplicate a Gist as a new Gist",
+	Long: `Create a new Gist with the same .env content as an existing one, for example as
+a starting point for a new project. The source Gist is not modified.
+
+Encrypted or masked content is copied as-is, so no password is needed.`,
+	Run: runCopyCommand,
+}
+
+// InitCopyCommand sets up the copy command
+func InitCopyCommand() {
+	// Initialize the command flags
+	copyCmd.Flags().StringVarP(&copyGistID, "id", "i", "", "GitHub Gist ID to copy (defaults to saved Gist)")
+	copyCmd.Flags().StringVarP(&copyDescription, "description", "d", "", "Description for the new Gist (defaults to the source description)")
+	copyCmd.Flags().BoolVarP(&copyAll, "all", "a", false, "Copy every file in the Gist, not just .env")
+	copyCmd.Flags().BoolVar(&copyUse, "use", false, "Save the new Gist as your default Gist")
+
+	// Add the copy command to the root command
+	rootCmd.AddCommand(copyCmd)
+}
+
+// runCopyCommand handles the copy command execution
+func runCopyCommand(cmd *cobra.Command, args []string) {
+	// Get GitHub token
+	token, err := config.GetGitHubToken()
+	if err != nil {
+		exitWithError(ErrCodeNoToken, err.Error())
+	}
+
+	// Load config
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logWarn("Could not load config: %s", err)
+	}
+
+	// Get Gist ID (from flag, bookmark or config)
+	copyGistID = resolveGistRef(copyGistID)
+	if copyGistID == "" && cfg != nil {
+		copyGistID = cfg.LastGistID
+	}
+	if copyGistID == "" {
+		exitWithError(ErrCodeGeneric, "No Gist ID specified and no saved Gist ID found", "Use 'envi copy --id GIST_ID'")
+	}
+
+	// Create GitHub client
+	client := newGitHubClient(cmd.Context(), token)
+
+	// Get the source Gist
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	source, err := fetchGist(ctx, client, copyGistID)
+	if err != nil {
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not retrieve Gist with ID %s: %s", copyGistID, apiError(err)))
+	}
+
+	// Copy the file contents unchanged; ciphertext stays ciphertext
+	files := make(map[github.GistFilename]github.GistFile)
+	if copyAll {
+		for filename, file := range source.Files {
+			if file.Content != nil {
+				files[filename] = github.GistFile{Content: file.Content}
+			}
+		}
+	} else {
+		envContent, err := getGistEnvContent(source)
+		if err != nil {
+			exitWithError(ErrCodeNoEnvFile, err.Error())
+		}
+		files[github.GistFilename(".env")] = github.GistFile{Content: github.String(string(envContent))}
+	}
+
+	description := copyDescription
+	if description == "" {
+		description = source.GetDescription()
+	}
+
+	newGist := &github.Gist{
+		Description: github.String(description),
+		Public:      github.Bool(source.GetPublic()),
+		Files:       files,
+	}
+
+	created, err := createGist(ctx, client, newGist)
+	if err != nil {
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not create Gist: %s", apiError(err)))
+	}
+
+	fmt.Printf("Copied Gist %s (%d files) to new Gist: %s\n", copyGistID, len(files), created.GetID())
+	fmt.Printf("Gist URL: %s\n", gistWebURL(created.GetID()))
+
+	// Optionally make the copy the default Gist
+	if copyUse && cfg != nil {
+		cfg.LastGistID = created.GetID()
+		if err := config.SaveConfig(cfg); err != nil {
+			logWarn("Could not save Gist ID to config: %s", err)
+		} else {
+			fmt.Println("Saved new Gist ID for future use")
+		}
+	}
+
+	printJSONResult(map[string]interface{}{
+		"source_id": copyGistID,
+		"gist_id":   created.GetID(),
+		"url":       gistWebURL(created.GetID()),
+	})
+}
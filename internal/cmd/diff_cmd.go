@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// Diff command flags
+var (
+	diffGistID   string
+	diffEnvFile  string
+	diffSearchUp bool
+	diffValues   bool
+	diffUnmask   bool
+	diffReveal   string
+	diffOtherID  string
+)
+
+// How diff shows values that differ
+const (
+	revealNone    = "none"
+	revealPartial = "partial"
+	revealFull    = "full"
+)
+
+// diffCmd is the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare local .env with a remote Gist",
+	Long: `Compare your local .env file with the .env file in a GitHub Gist.
+
+By default only variable names are compared, so no value is written to disk or
+shown on screen. Use --values to also compare values; differing variables are then
+listed by name only. --reveal partial shows the first and last few characters of
+each differing value, enough to tell which secret was rotated, and --reveal full
+shows them completely. --reveal implies --values.
+
+With --other, the .env files of two Gists are compared instead, for example
+staging and production: --id (or the saved Gist) against --other. Nothing is read
+from or written to the local disk.`,
+	Run: runDiffCommand,
+}
+
+// InitDiffCommand sets up the diff command
+func InitDiffCommand() {
+	// Initialize the command flags
+	diffCmd.Flags().StringVarP(&diffGistID, "id", "i", "", "GitHub Gist ID or @BOOKMARK to compare against (defaults to saved Gist)")
+	diffCmd.Flags().StringVarP(&diffEnvFile, "file", "f", ".env", "Path to the local .env file")
+	diffCmd.Flags().BoolVar(&diffSearchUp, "search-up", false, "Search parent directories for the nearest .env file")
+	diffCmd.Flags().BoolVar(&diffValues, "values", false, "Also compare values, not just variable names")
+	diffCmd.Flags().BoolVarP(&diffUnmask, "unmask", "u", false, "Decrypt/unmask remote values before comparing")
+	diffCmd.Flags().StringVar(&diffReveal, "reveal", revealNone, "How to show differing values: none (names only), partial (first and last characters) or full")
+	diffCmd.Flags().StringVar(&diffOtherID, "other", "", "Compare the Gist with this Gist ID or @BOOKMARK instead of the local .env file")
+
+	// Add the diff command to the root command
+	rootCmd.AddCommand(diffCmd)
+}
+
+// runDiffCommand handles the diff command execution
+func runDiffCommand(cmd *cobra.Command, args []string) {
+	// --show-values asks for full values unless --reveal says otherwise
+	if !cmd.Flags().Changed("reveal") && showValues {
+		diffReveal = revealFull
+	}
+	switch diffReveal {
+	case revealNone:
+	case revealPartial, revealFull:
+		diffValues = true
+	default:
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Unknown --reveal mode %q (use none, partial or full)", diffReveal))
+	}
+	
+	// Get GitHub token
+	token, err := config.GetGitHubToken()
+	if err != nil {
+		exitWithError(ErrCodeNoToken, err.Error())
+	}
+
+	// Load config
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logWarn("Could not load config: %s", err)
+	} else {
+		applyEncryptionDefaults(cmd, cfg)
+	}
+
+	// Get Gist ID (from flag or config)
+	diffGistID = resolveGistRef(diffGistID)
+	if diffGistID == "" && cfg != nil {
+		diffGistID = cfg.LastGistID
+	}
+	if diffGistID == "" {
+		exitWithError(ErrCodeGeneric, "No Gist ID specified and no saved Gist ID found", "Use 'envi diff --id GIST_ID'")
+	}
+
+	// Create GitHub client
+	client := newGitHubClient(cmd.Context(), token)
+	compareValues := diffValues
+
+	// The local .env file, or the other Gist with --other
+	var localVars map[string]string
+	var localName string
+	diffOtherID = resolveGistRef(diffOtherID)
+	if diffOtherID != "" {
+		if diffOtherID == diffGistID {
+			exitWithError(ErrCodeGeneric, "--id and --other name the same Gist")
+		}
+		localVars = fetchDiffVars(cmd, client, diffGistID, &compareValues)
+		localName = "Gist " + diffGistID
+	} else {
+		diffEnvFile = resolveEnvPath(diffEnvFile, diffSearchUp)
+		localName = diffEnvFile
+		localVars, _, err = parseEnvFile(diffEnvFile)
+		if err != nil {
+			exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Could not read %s: %s", diffEnvFile, err))
+		}
+	}
+
+	remoteID := diffGistID
+	if diffOtherID != "" {
+		remoteID = diffOtherID
+	}
+	remoteName := "Gist " + remoteID
+	remoteVars := fetchDiffVars(cmd, client, remoteID, &compareValues)
+	diff := compareEnvVars(localVars, remoteVars, compareValues)
+
+	// With --other, "local" stands for the --id Gist and "remote" for the other one
+	result := map[string]interface{}{
+		"gist_id":         diffGistID,
+		"values_compared": compareValues,
+		"only_local":      nonNil(diff.OnlyLocal),
+		"only_remote":     nonNil(diff.OnlyRemote),
+		"changed":         nonNil(diff.Changed),
+	}
+	if diffOtherID != "" {
+		result["other_gist_id"] = diffOtherID
+	} else {
+		result["file"] = diffEnvFile
+	}
+	if compareValues && diffReveal != revealNone {
+		changedValues := make(map[string]map[string]string, len(diff.Changed))
+		for _, key := range diff.Changed {
+			changedValues[key] = map[string]string{
+				"local":  revealValue(localVars[key], diffReveal),
+				"remote": revealValue(remoteVars[key], diffReveal),
+			}
+		}
+		result["changed_values"] = changedValues
+	}
+	printJSONResult(result)
+	if jsonOutput {
+		return
+	}
+
+	if diff.Count() == 0 {
+		if compareValues {
+			fmt.Printf("No differences between %s and %s\n", localName, remoteName)
+		} else {
+			fmt.Printf("No differences in variable names between %s and %s\n", localName, remoteName)
+		}
+		return
+	}
+
+	if len(diff.OnlyLocal) > 0 {
+		fmt.Printf("Only in %s (%d):\n", localName, len(diff.OnlyLocal))
+		for _, key := range diff.OnlyLocal {
+			fmt.Printf("  + %s\n", key)
+		}
+	}
+
+	if len(diff.OnlyRemote) > 0 {
+		fmt.Printf("Only in %s (%d):\n", remoteName, len(diff.OnlyRemote))
+		for _, key := range diff.OnlyRemote {
+			fmt.Printf("  - %s\n", key)
+		}
+	}
+
+	if len(diff.Changed) > 0 {
+		localLabel, remoteLabel := "local: ", "remote:"
+		if diffOtherID != "" {
+			localLabel, remoteLabel = diffGistID+":", diffOtherID+":"
+		}
+		fmt.Printf("Different values (%d):\n", len(diff.Changed))
+		for _, key := range diff.Changed {
+			fmt.Printf("  ~ %s\n", key)
+			if diffReveal != revealNone {
+				fmt.Printf("      %s %s\n", localLabel, revealValue(localVars[key], diffReveal))
+				fmt.Printf("      %s %s\n", remoteLabel, revealValue(remoteVars[key], diffReveal))
+			}
+		}
+	}
+
+	fmt.Printf("\n%d differences found\n", diff.Count())
+}
+
+// fetchDiffVars fetches a Gist's .env file and parses it in memory, decrypting it with
+// --unmask. Masked values can't be compared without --unmask, so compareValues is
+// turned off for them.
+func fetchDiffVars(cmd *cobra.Command, client *github.Client, gistID string, compareValues *bool) map[string]string {
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	gist, err := fetchGist(ctx, client, gistID)
+	if err != nil {
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not retrieve Gist with ID %s: %s", gistID, apiError(err)))
+	}
+
+	content, err := getGistEnvContent(gist)
+	if err != nil {
+		exitWithError(ErrCodeNoEnvFile, err.Error())
+	}
+
+	// Decrypt in memory if requested; encrypted content is never written to disk
+	isEncrypted := encryption.IsEncrypted(content)
+	isMasked := encryption.IsMasked(content)
+
+	if (isEncrypted || isMasked) && diffUnmask {
+		content, err = decryptEnvContent(content)
+		if err != nil {
+			exitOnKeyFileError(err)
+			exitWithError(ErrCodeDecryptFailed, fmt.Sprintf("Could not decrypt Gist %s. Please check the encryption key or password and try again.", gistID))
+		}
+	} else if isEncrypted {
+		exitWithError(ErrCodeDecryptFailed, fmt.Sprintf("Gist %s is fully encrypted. Use --unmask to decrypt it before comparing.", gistID))
+	} else if isMasked && *compareValues {
+		fmt.Printf("Note: Values in Gist %s are masked. Comparing variable names only (use --unmask to compare values).\n", gistID)
+		*compareValues = false
+	}
+
+	vars, _ := parseEnvContent(content)
+	return vars
+}
+
+// revealValue returns a value as shown by --reveal. Partial shows up to 4 characters
+// at each end, but never more than a quarter of the value each, so short values are
+// hidden completely. Characters are counted as runes, so none is split.
+func revealValue(value string, mode string) string {
+	switch mode {
+	case revealFull:
+		return value
+	case revealPartial:
+		runes := []rune(value)
+		n := len(runes) / 4
+		if n > 4 {
+			n = 4
+		}
+		if n == 0 {
+			return "****"
+		}
+		return string(runes[:n]) + "..." + string(runes[len(runes)-n:])
+	}
+	return ""
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// Doctor check results
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// doctorCheck is the result of a single doctor check
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// doctorCmd is the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check your envi setup for problems",
+	Long: `Check the things envi depends on and suggest fixes for anything that is wrong:
+the config file and its permissions, the GitHub token and where it comes from,
+the system credential manager, the encryption key file, access to GitHub and the
+token's scopes, and whether a .env file exists here.
+
+Doctor only reports; it never changes files, settings or the credential manager.
+It exits with a non-zero status if any check fails.`,
+	Run: runDoctorCommand,
+}
+
+// InitDoctorCommand sets up the doctor command
+func InitDoctorCommand() {
+	// Add the doctor command to the root command
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// runDoctorCommand handles the doctor command execution
+func runDoctorCommand(cmd *cobra.Command, args []string) {
+	var checks []doctorCheck
+	add := func(name, status, message, hint string) {
+		checks = append(checks, doctorCheck{Name: name, Status: status, Message: message, Hint: hint})
+	}
+
+	// Config file. It is read directly, since loading it normally may create or move it.
+	var cfg *config.Config
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		add("config", checkFail, fmt.Sprintf("Could not determine the config file location: %s", err), "")
+	} else if info, err := os.Stat(configPath); os.IsNotExist(err) {
+		add("config", checkWarn, fmt.Sprintf("No config file at %s", configPath), "Run 'envi config' to create one")
+	} else if err != nil {
+		add("config", checkFail, fmt.Sprintf("Could not access %s: %s", configPath, err), "")
+	} else if cfg, err = config.ReadConfig(configPath); err != nil {
+		add("config", checkFail, fmt.Sprintf("Could not read %s: %s", configPath, err), "Fix or remove the file, then run 'envi config'")
+	} else if perm := info.Mode().Perm(); perm != 0600 {
+		add("config", checkWarn, fmt.Sprintf("%s has permissions %o, so others may read your token", configPath, perm),
+			fmt.Sprintf("Run 'chmod 600 %s'", configPath))
+	} else {
+		add("config", checkPass, fmt.Sprintf("Config file %s (permissions 600)", configPath), "")
+	}
+
+	// System credential manager
+	keyringHasToken, keyringErr := config.KeyringStatus()
+	if keyringErr != nil {
+		add("keyring", checkWarn, fmt.Sprintf("System credential manager is not available: %s", keyringErr),
+			"envi stores the token in the config file instead, or set GITHUB_TOKEN")
+	} else {
+		add("keyring", checkPass, "System credential manager is available", "")
+	}
+
+	// GitHub token and where it comes from, in the order envi looks for it
+	token, tokenSource := os.Getenv("GITHUB_TOKEN"), config.TokenSourceEnv
+	if token == "" && cfg != nil && cfg.TokenInKeyring && keyringHasToken {
+		token, _ = config.GetTokenFromKeyring()
+		tokenSource = config.TokenSourceKeyring
+	}
+	if token == "" && cfg != nil && cfg.GitHubToken != "" {
+		token, tokenSource = cfg.GitHubToken, config.TokenSourceFile
+	}
+	switch {
+	case token == "" && cfg != nil && cfg.TokenInKeyring:
+		add("token", checkFail, "The config expects a token in the system credential manager, but none was found",
+			"Run 'envi config --token YOUR_TOKEN' to store it again")
+	case token == "":
+		add("token", checkFail, "No GitHub token found", "Run 'envi config --token YOUR_TOKEN' or set GITHUB_TOKEN")
+	case !config.IsValidGitHubToken(token):
+		add("token", checkFail, fmt.Sprintf("The token from the %s doesn't look like a GitHub token", tokenSource),
+			"Create a token at " + githubWebURL("settings/tokens"))
+		token = ""
+	default:
+		add("token", checkPass, fmt.Sprintf("GitHub token found in the %s", tokenSource), "")
+	}
+
+	// GitHub access and token scopes
+	if token != "" {
+		client := newGitHubClient(cmd.Context(), token)
+
+		ctx, cancel := apiContext(cmd)
+		defer cancel()
+		var user *github.User
+		var resp *github.Response
+		err := withSpinner("Contacting GitHub...", func() error {
+			var err error
+			user, resp, err = client.Users.Get(ctx, "")
+			return err
+		})
+
+		scopes, reported := tokenScopes(resp)
+		switch {
+		case err != nil:
+			add("github", checkFail, fmt.Sprintf("Could not reach GitHub with the token: %s", apiError(err)),
+				"Check your connection, and that the token hasn't expired or been revoked")
+		case !reported:
+			add("github", checkWarn, fmt.Sprintf("Connected as %s; scopes are not reported for fine-grained tokens", user.GetLogin()),
+				"Make sure the token has the \"Gists\" account permission set to read and write")
+		case !hasScope(scopes, "gist"):
+			add("github", checkFail, fmt.Sprintf("Connected as %s, but the token is missing the gist scope", user.GetLogin()),
+				"Create a token with the gist scope at " + githubWebURL("settings/tokens"))
+		default:
+			add("github", checkPass, fmt.Sprintf("Connected as %s with the gist scope", user.GetLogin()), "")
+		}
+	}
+
+	// Encryption key file, if one is configured
+	if cfg != nil && (cfg.UseKeyFileByDefault || cfg.DefaultKeyFile != "") {
+		keyFile := cfg.DefaultKeyFile
+		if keyFile == "" {
+			keyFile = encryption.EncryptionKeyFile
+		}
+		keyFile = encryption.ResolveKeyFile(keyFile)
+		if info, err := os.Stat(keyFile); err != nil {
+			add("key-file", checkFail, fmt.Sprintf("Key file %s is missing", keyFile),
+				"Run 'envi config --default-key-file PATH' to create one")
+		} else if _, err := encryption.ReadKeyFile(keyFile); err != nil {
+			add("key-file", checkFail, err.Error(), "")
+		} else if perm := info.Mode().Perm(); perm&0077 != 0 {
+			add("key-file", checkWarn, fmt.Sprintf("Key file %s has permissions %o, so others may read it", keyFile, perm),
+				fmt.Sprintf("Run 'chmod 600 %s'", keyFile))
+		} else {
+			add("key-file", checkPass, fmt.Sprintf("Key file %s is valid", keyFile), "")
+		}
+	}
+
+	// Local .env file
+	if _, err := os.Stat(".env"); err == nil {
+		add("env-file", checkPass, "Found .env in the current directory", "")
+	} else {
+		add("env-file", checkWarn, "No .env in the current directory",
+			"Create one, or run 'envi pull' to fetch it from a Gist")
+	}
+
+	// Report
+	failed := 0
+	for _, check := range checks {
+		if check.Status == checkFail {
+			failed++
+		}
+	}
+
+	if jsonOutput {
+		printJSON(map[string]interface{}{
+			"ok":     failed == 0,
+			"checks": checks,
+		})
+	} else {
+		printDoctorChecks(checks)
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printDoctorChecks prints the checks as a checklist with a summary line
+func printDoctorChecks(checks []doctorCheck) {
+	counts := make(map[string]int)
+	for _, check := range checks {
+		counts[check.Status]++
+
+		mark := "✓"
+		switch check.Status {
+		case checkWarn:
+			mark = "!"
+		case checkFail:
+			mark = "✗"
+		}
+		fmt.Printf("  %s %s\n", mark, check.Message)
+		if check.Hint != "" {
+			fmt.Printf("      %s\n", check.Hint)
+		}
+	}
+
+	summary := []string{fmt.Sprintf("%d passed", counts[checkPass])}
+	if counts[checkWarn] > 0 {
+		summary = append(summary, fmt.Sprintf("%d warning(s)", counts[checkWarn]))
+	}
+	if counts[checkFail] > 0 {
+		summary = append(summary, fmt.Sprintf("%d failed", counts[checkFail]))
+	}
+	fmt.Printf("\n%s\n", strings.Join(summary, ", "))
+}
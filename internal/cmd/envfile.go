@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/pkg/envi"
+)
+
+// sampleEnvContent is the starter .env written by 'envi push --auto' and 'envi init'
+const sampleEnvContent = "# Sample .env file created by envi\n" +
+	"# Replace these with your actual environment variables\n\n" +
+	"DB_HOST=localhost\n" +
+	"DB_PORT=5432\n" +
+	"DB_USER=username\n" +
+	"DB_PASSWORD=password\n" +
+	"API_KEY=your_api_key_here\n"
+
+// Substrings that mark a key as likely holding a secret
+var sensitiveKeyPatterns = []string{"KEY", "SECRET", "TOKEN", "PASSWORD", "PRIVATE"}
+
+// inlineComments enables stripping of `# comment` text after unquoted values
+var inlineComments bool
+
+// isSensitiveKey reports whether a variable name looks like it holds a secret
+func isSensitiveKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, pattern := range sensitiveKeyPatterns {
+		if strings.Contains(upper, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultKeyPattern is the naming convention --strict-keys enforces unless --key-pattern is set
+const defaultKeyPattern = `^[A-Z][A-Z0-9_]*$`
+
+// compileKeyPattern compiles a --key-pattern value, exiting with an error if it is invalid
+func compileKeyPattern(pattern string) *regexp.Regexp {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid --key-pattern %q: %s", pattern, err))
+	}
+	return re
+}
+
+// normalizeKeyName converts a key to UPPER_SNAKE_CASE, e.g. apiKey and api.key become API_KEY
+func normalizeKeyName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(rune(key[i-1])) || unicode.IsDigit(rune(key[i-1]))):
+			b.WriteRune('_')
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			b.WriteRune(unicode.ToUpper(r))
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// resolveEnvPath returns the .env path to act on. When searchUp is set and the path
+// is relative, parent directories are walked to find the nearest match, the same way
+// git finds its .git directory. The resolved path is printed so users know which
+// file was used.
+func resolveEnvPath(path string, searchUp bool) string {
+	if !searchUp || filepath.IsAbs(path) {
+		return path
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+
+	dir := cwd
+	for {
+		candidate := filepath.Join(dir, path)
+		if _, err := os.Stat(candidate); err == nil {
+			resolved := candidate
+			if rel, err := filepath.Rel(cwd, candidate); err == nil {
+				resolved = rel
+			}
+			fmt.Printf("Using env file: %s\n", resolved)
+			return resolved
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	fmt.Printf("No %s found in any parent directory, using %s\n", path, path)
+	return path
+}
+
+// formatEnvLine builds a KEY=value line, re-applying a shell prefix if one is given.
+// The value is quoted if it needs to be, see canonicalEnvValue.
+func formatEnvLine(prefix, key, value string) string {
+	return prefix + key + "=" + canonicalEnvValue(value)
+}
+
+// canonicalEnvValue returns a raw value as it should be written to a .env file. Values
+// that are already quoted, masked or safe as they are stay unchanged; anything else is
+// quoted so the file can be parsed and sourced without surprises.
+func canonicalEnvValue(value string) string {
+	if isQuotedValue(value) || quotedWithComment(value) || strings.HasPrefix(value, encryption.MaskedPrefix) || !envValueNeedsQuoting(value) {
+		return value
+	}
+	return quoteEnvValue(value)
+}
+
+// quotedWithComment reports whether a value is quoted and followed by a comment, as in
+// "a b" # note, which dotenv parsers read without --inline-comments too
+func quotedWithComment(value string) bool {
+	if len(value) < 2 || (value[0] != '"' && value[0] != '\'') {
+		return false
+	}
+	end := strings.IndexByte(value[1:], value[0])
+	if end < 0 {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(value[end+2:]), "#")
+}
+
+// envValueNeedsQuoting reports whether a value would be misread unquoted: it has
+// whitespace at either end or contains spaces, quotes, backticks, # or newlines.
+// $, = and backslashes are read the same either way, so ${VAR} references and
+// base64 padding are left alone.
+func envValueNeedsQuoting(value string) bool {
+	if value == "" {
+		return false
+	}
+	if strings.TrimSpace(value) != value {
+		return true
+	}
+	return strings.ContainsAny(value, " \t\n\r#\"'`")
+}
+
+// quoteEnvValue quotes a value for a .env file. Single quotes keep it literal, both for
+// dotenv parsers and for `source .env`. Values containing single quotes, newlines or $
+// are double-quoted instead, escaping backslashes, double quotes, backticks and
+// newlines; $ is not escaped, so ${VAR} references are still expanded.
+func quoteEnvValue(value string) string {
+	if !strings.ContainsAny(value, "'\n\r$") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// formatEnvContent rewrites every KEY=value line in the content with canonicalEnvValue,
+// keeping comments, blank lines, shell prefixes and inline comments as they are
+func formatEnvContent(content []byte) []byte {
+	lines, newline := encryption.SplitLines(content)
+	for i, line := range lines {
+		variable, ok := envi.ParseLine(strings.TrimSpace(line), envParseOptions())
+		if !ok {
+			continue
+		}
+		value := strings.TrimSpace(variable.Value)
+		lines[i] = withInlineComment(formatEnvLine(variable.Prefix, variable.Key, value), variable.Comment)
+	}
+	return encryption.JoinLines(lines, newline)
+}
+
+// applyExportStyle rewrites every KEY=value line in the content to use the `export ` prefix.
+// Values are quoted where needed too, since the result is meant to be sourced.
+func applyExportStyle(content []byte) []byte {
+	lines, newline := encryption.SplitLines(formatEnvContent(content))
+	for i, line := range lines {
+		stripped, _ := envi.SplitPrefix(line)
+		if _, ok := envi.ParseLine(stripped, envParseOptions()); !ok {
+			continue
+		}
+		lines[i] = "export " + stripped
+	}
+	return encryption.JoinLines(lines, newline)
+}
+
+// envParseOptions returns how .env content is parsed, following --inline-comments
+func envParseOptions() envi.ParseOptions {
+	return envi.ParseOptions{InlineComments: inlineComments}
+}
+
+// parseEnvEntries parses .env content into variables in file order, keeping
+// every occurrence and its line number, plus a slice of comments
+func parseEnvEntries(content []byte) ([]envi.Variable, []string) {
+	return envi.Parse(content, envParseOptions())
+}
+
+// parseEnvContent parses .env content into a map of variables and a slice of comments.
+// When a key is defined more than once, the last definition wins.
+func parseEnvContent(content []byte) (map[string]string, []string) {
+	entries, comments := parseEnvEntries(content)
+
+	variables := make(map[string]string)
+	for _, entry := range entries {
+		variables[entry.Key] = entry.Value
+	}
+
+	return variables, comments
+}
+
+// findDuplicateKeys returns the line numbers of every key defined more than once
+func findDuplicateKeys(entries []envi.Variable) map[string][]int {
+	lines := make(map[string][]int)
+	for _, entry := range entries {
+		lines[entry.Key] = append(lines[entry.Key], entry.Line)
+	}
+
+	duplicates := make(map[string][]int)
+	for key, keyLines := range lines {
+		if len(keyLines) > 1 {
+			duplicates[key] = keyLines
+		}
+	}
+	return duplicates
+}
+
+// envDiff describes how two sets of variables differ
+type envDiff struct {
+	OnlyLocal  []string
+	OnlyRemote []string
+	Changed    []string
+}
+
+// Count returns the total number of differences
+func (d envDiff) Count() int {
+	return len(d.OnlyLocal) + len(d.OnlyRemote) + len(d.Changed)
+}
+
+// compareEnvVars compares local and remote variables, optionally ignoring values
+func compareEnvVars(local, remote map[string]string, compareValues bool) envDiff {
+	var diff envDiff
+
+	for key, value := range local {
+		remoteValue, exists := remote[key]
+		if !exists {
+			diff.OnlyLocal = append(diff.OnlyLocal, key)
+		} else if compareValues && remoteValue != value {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+
+	for key := range remote {
+		if _, exists := local[key]; !exists {
+			diff.OnlyRemote = append(diff.OnlyRemote, key)
+		}
+	}
+
+	sort.Strings(diff.OnlyLocal)
+	sort.Strings(diff.OnlyRemote)
+	sort.Strings(diff.Changed)
+
+	return diff
+}
+
+// yamlKeyRegex matches a YAML mapping line such as "key: value" or "key:"
+var yamlKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+:(\s|$)`)
+
+// envFormatProblem describes why content doesn't look like .env data, such as a JSON or
+// YAML file stored under the name .env. It returns "" for content that looks fine,
+// including empty content and content with only comments.
+func envFormatProblem(content []byte) string {
+	if bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content) {
+		return "it looks like binary data"
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return "it looks like JSON"
+	}
+
+	entries, _ := parseEnvEntries(content)
+	if len(entries) > 0 {
+		return ""
+	}
+
+	// No variables: find out what the other lines are
+	yamlLines, otherLines := 0, 0
+	lines, _ := encryption.SplitLines(content)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case line == "---" || yamlKeyRegex.MatchString(line) || strings.HasPrefix(line, "- "):
+			yamlLines++
+		default:
+			otherLines++
+		}
+	}
+	if yamlLines > 0 && otherLines == 0 {
+		return "it looks like YAML"
+	}
+	if yamlLines+otherLines > 0 {
+		return "it has no KEY=value lines"
+	}
+	return ""
+}
+
+// Orders for sortEnvContent
+const (
+	sortNone   = "none"   // keep the file order
+	sortAlpha  = "alpha"  // alphabetical by key
+	sortPrefix = "prefix" // alphabetical, grouped by the key's first _-separated token
+)
+
+// keyPrefix returns the leading token of a key that groups it with related keys, e.g.
+// DB for DB_HOST. A key without an underscore is its own prefix.
+func keyPrefix(key string) string {
+	if i := strings.Index(key, "_"); i > 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// groupEnvKeys orders keys for a sort mode, returning them in groups to separate with a
+// blank line. Alphabetical order is a single group; prefix order gives each prefix
+// shared by several keys its own group, and collects the remaining keys between them.
+func groupEnvKeys(keys []string, mode string) [][]string {
+	sorted := append([]string(nil), keys...)
+	if mode == sortNone {
+		return [][]string{sorted}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if mode == sortPrefix && keyPrefix(sorted[i]) != keyPrefix(sorted[j]) {
+			return keyPrefix(sorted[i]) < keyPrefix(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	if mode != sortPrefix {
+		return [][]string{sorted}
+	}
+
+	counts := make(map[string]int)
+	for _, key := range sorted {
+		counts[keyPrefix(key)]++
+	}
+	var groups [][]string
+	for i, key := range sorted {
+		// Start a new group where a shared prefix begins or ends
+		grouped := counts[keyPrefix(key)] > 1
+		if i == 0 || (keyPrefix(key) != keyPrefix(sorted[i-1]) && (grouped || counts[keyPrefix(sorted[i-1])] > 1)) {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], key)
+	}
+	return groups
+}
+
+// sortEnvContent reorders the variable lines in content. Comment lines directly above a
+// variable move with it, comments before the first variable stay at the top, and blank
+// lines are replaced by the ones between groups.
+func sortEnvContent(content []byte, mode string) []byte {
+	if mode == sortNone {
+		return content
+	}
+
+	lines, newline := encryption.SplitLines(content)
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+
+	var header, pending, trailer []string
+	blocks := make(map[string][]string)
+	var keys []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		stripped, _ := envi.SplitPrefix(line)
+		eq := strings.Index(stripped, "=")
+		switch {
+		case trimmed == "":
+			// A blank line ends the header
+			if len(keys) == 0 {
+				header = append(header, pending...)
+				header = append(header, line)
+				pending = nil
+			}
+		case strings.HasPrefix(trimmed, "#") || eq < 0:
+			pending = append(pending, line)
+		default:
+			key := strings.TrimSpace(stripped[:eq])
+			if _, seen := blocks[key]; !seen {
+				keys = append(keys, key)
+			}
+			// Duplicates stay together, in file order
+			blocks[key] = append(blocks[key], append(pending, line)...)
+			pending = nil
+		}
+	}
+	trailer = pending
+
+	var out []string
+	out = append(out, header...)
+	for i, group := range groupEnvKeys(keys, mode) {
+		if i > 0 {
+			out = append(out, "")
+		}
+		for _, key := range group {
+			out = append(out, blocks[key]...)
+		}
+	}
+	out = append(out, trailer...)
+	return encryption.JoinLines(append(out, ""), newline)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dexterity-inc/envi/pkg/envi"
+)
+
+func TestCanonicalEnvValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"plain", "localhost", "localhost"},
+		{"interpolation", "${HOST}/db", "${HOST}/db"},
+		{"base64 padding", "c2VjcmV0==", "c2VjcmV0=="},
+		{"backslashes", `C:\path\to`, `C:\path\to`},
+		{"already single-quoted", "'a b'", "'a b'"},
+		{"already double-quoted", `"a b"`, `"a b"`},
+		{"quoted with a comment", `"a b" # note`, `"a b" # note`},
+		{"masked", "ENVI_MASKED:abc def", "ENVI_MASKED:abc def"},
+		{"spaces", "hello world", "'hello world'"},
+		{"hash", "a#b", "'a#b'"},
+		{"surrounding whitespace", " padded ", "' padded '"},
+		{"single quote", "it's", `"it's"`},
+		{"double quotes", `say "hi"`, `'say "hi"'`},
+		{"backtick", "`cmd`", "'`cmd`'"},
+		{"interpolation with spaces", "${GREETING} world", `"${GREETING} world"`},
+		{"newline", "line1\nline2", `"line1\nline2"`},
+		{"everything", "it's \"$HOME\"\\`x`\r\n", `"it's \"$HOME\"\\\` + "`x\\`" + `\r\n"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalEnvValue(tt.value); got != tt.want {
+				t.Errorf("canonicalEnvValue(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// unquotedVariables parses content and undoes dotenv quoting, giving the values the
+// variables stand for
+func unquotedVariables(content []byte) map[string]string {
+	variables, _ := parseEnvContent(content)
+	for key, value := range variables {
+		variables[key] = envi.Unquote(value)
+	}
+	return variables
+}
+
+func TestFormatEnvContentRoundTrip(t *testing.T) {
+	content := strings.Join([]string{
+		"# Tricky values",
+		"PLAIN=value",
+		"URL=${HOST}/db",
+		"TOKEN=c2VjcmV0==",
+		"GREETING=hello world",
+		"HASH=a #b",
+		"APOSTROPHE=it's",
+		`QUOTES=say "hi"`,
+		`ESCAPED="say \"hi\" to $USER"`,
+		"LITERAL='$NOT_EXPANDED'",
+		`MULTILINE="line1\nline2"`,
+		`WINDOWS=C:\path\to`,
+		"BACKTICK=`date`",
+		"MIXED=it's ${USER}'s \"turn\"",
+		"export EXPORTED=a b",
+		"EMPTY=",
+		"",
+	}, "\n")
+
+	formatted := formatEnvContent([]byte(content))
+	want := unquotedVariables([]byte(content))
+	if got := unquotedVariables(formatted); !reflect.DeepEqual(got, want) {
+		t.Errorf("formatted content parses to %q, want %q\nformatted:\n%s", got, want, formatted)
+	}
+
+	// Formatting is stable, and values that are valid unquoted are left alone
+	if again := formatEnvContent(formatted); string(again) != string(formatted) {
+		t.Errorf("formatting twice changed the content:\n%s\nthen:\n%s", formatted, again)
+	}
+	for _, line := range []string{"URL=${HOST}/db", "TOKEN=c2VjcmV0==", `WINDOWS=C:\path\to`, "# Tricky values"} {
+		if !strings.Contains(string(formatted), line+"\n") {
+			t.Errorf("formatted content is missing the unchanged line %q:\n%s", line, formatted)
+		}
+	}
+}
+
+func TestFormatEnvLineRoundTrip(t *testing.T) {
+	// Values typed into push --interactive may contain anything, including newlines
+	values := []string{
+		"simple",
+		"${HOST}:${PORT}",
+		"two words",
+		"it's",
+		`"quoted" inside`,
+		"dollar $ and `backtick`",
+		"line1\nline2\r\nline3",
+		`back\slash`,
+		"  padded\t",
+	}
+	for _, value := range values {
+		line := formatEnvLine("export ", "KEY", value)
+		if strings.ContainsAny(line, "\n\r") {
+			t.Errorf("formatEnvLine(%q) = %q spans more than one line", value, line)
+		}
+		got := unquotedVariables([]byte(line + "\n"))
+		if want := map[string]string{"KEY": value}; !reflect.DeepEqual(got, want) {
+			t.Errorf("formatEnvLine(%q) = %q, which parses to %q", value, line, got)
+		}
+	}
+}
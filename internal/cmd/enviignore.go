@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// This file contains the .enviignore support used when pushing a directory of env files
+
+// enviIgnoreFile is the name of the ignore file read from a pushed directory
+const enviIgnoreFile = ".enviignore"
+
+// ignoreRule is a single pattern from a .enviignore file
+type ignoreRule struct {
+	pattern string
+	negate  bool
+}
+
+// ignoreRules holds the rules of a .enviignore file in file order
+type ignoreRules []ignoreRule
+
+// parseIgnoreRules parses gitignore-style patterns. Blank lines and lines starting with
+// '#' are skipped, '!' re-includes files matched by an earlier pattern, and '\#' or '\!'
+// match a literal leading character.
+func parseIgnoreRules(content []byte) ignoreRules {
+	var rules ignoreRules
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+
+		// Only the directory itself is scanned, so anchored patterns match names there
+		rule.pattern = strings.TrimPrefix(line, "/")
+		if rule.pattern != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// ignored reports whether a file name is excluded. The last matching rule wins.
+func (rules ignoreRules) ignored(name string) bool {
+	ignored := false
+	for _, rule := range rules {
+		if matched, _ := filepath.Match(rule.pattern, name); matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// isEnvFileName reports whether a file name looks like an env file: .env, .env.* or *.env
+func isEnvFileName(name string) bool {
+	return name == ".env" || strings.HasPrefix(name, ".env.") || strings.HasSuffix(name, ".env")
+}
+
+// envFilesInDir returns the env files in a directory in name order, leaving out those
+// excluded by the directory's .enviignore. Skipped files are returned separately.
+func envFilesInDir(dir string) (files, skipped []string, err error) {
+	var rules ignoreRules
+	content, err := os.ReadFile(filepath.Join(dir, enviIgnoreFile))
+	if err == nil {
+		rules = parseIgnoreRules(content)
+	} else if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !isEnvFileName(name) {
+			continue
+		}
+		if rules.ignored(name) {
+			skipped = append(skipped, name)
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files, skipped, nil
+}
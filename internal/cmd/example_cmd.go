@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/pkg/envi"
+)
+
+// Example command flags
+var (
+	exampleEnvFile string
+	exampleOutput  string
+	exampleForce   bool
+)
+
+// exampleCmd is the example generation command
+var exampleCmd = &cobra.Command{
+	Use:   "example",
+	Short: "Generate .env.example from an existing .env file",
+	Long: `Generate an .env.example file from your .env file. Every key is kept with an empty
+value, comments and ordering are preserved, and secret-looking keys are flagged with a TODO comment.`,
+	Run: runExampleCommand,
+}
+
+// InitExampleCommand sets up the example command
+func InitExampleCommand() {
+	// Initialize the command flags
+	exampleCmd.Flags().StringVarP(&exampleEnvFile, "file", "f", ".env", "Path to the source .env file")
+	exampleCmd.Flags().StringVarP(&exampleOutput, "output", "o", ".env.example", "Output file path")
+	exampleCmd.Flags().BoolVar(&exampleForce, "force", false, "Overwrite an existing example file")
+
+	// Add the example command to the root command
+	rootCmd.AddCommand(exampleCmd)
+}
+
+// runExampleCommand handles the example command execution
+func runExampleCommand(cmd *cobra.Command, args []string) {
+	// Read the source .env file
+	content, err := os.ReadFile(exampleEnvFile)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not read %s: %s", exampleEnvFile, err))
+	}
+
+	// Refuse to overwrite an existing example unless forced
+	if _, err := os.Stat(exampleOutput); err == nil && !exampleForce {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("%s already exists", exampleOutput), "Use --force to overwrite it")
+	}
+
+	exampleContent, count := generateExampleContent(content)
+
+	if err := os.WriteFile(exampleOutput, exampleContent, 0644); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not write %s: %s", exampleOutput, err))
+	}
+
+	fmt.Printf("Generated %s with %d variables from %s\n", exampleOutput, count, exampleEnvFile)
+}
+
+// generateExampleContent strips values from .env content, keeping keys, comments and blank lines
+func generateExampleContent(content []byte) ([]byte, int) {
+	var lines []string
+	count := 0
+
+	contentLines, newline := encryption.SplitLines(content)
+	for _, line := range contentLines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Keep blank lines and comments as they are
+		if trimmedLine == "" || strings.HasPrefix(trimmedLine, "#") {
+			lines = append(lines, line)
+			continue
+		}
+
+		// Keep only the key of each variable, dropping anything unparseable
+		variable, ok := envi.ParseLine(line, envParseOptions())
+		if !ok {
+			continue
+		}
+
+		if isSensitiveKey(variable.Key) {
+			lines = append(lines, "# TODO: set this")
+		}
+		lines = append(lines, formatEnvLine(variable.Prefix, variable.Key, ""))
+		count++
+	}
+
+	return encryption.JoinLines(lines, newline), count
+}
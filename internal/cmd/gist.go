@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v37/github"
+
+	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/internal/logging"
+)
+
+// This file contains helpers for fetching Gists and reading .env content from them
+
+// gistIDRegex matches Gist IDs, which are made of letters and digits (hexadecimal
+// for all but the oldest Gists)
+var gistIDRegex = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+
+// looksLikeGistURL reports whether a Gist reference is a link rather than an ID
+func looksLikeGistURL(ref string) bool {
+	return strings.Contains(ref, "://") || strings.Contains(ref, "/")
+}
+
+// gistIDFromURL returns the ID of the Gist a link points to. It understands
+// gist.github.com/USER/ID and gist.github.com/ID pages, with or without a revision,
+// file anchor or .git suffix, gist.githubusercontent.com/USER/ID/raw/... raw files,
+// api.github.com/gists/ID, and the /gist/ and /api/v3/gists/ forms of GitHub
+// Enterprise Server.
+func gistIDFromURL(ref string) (string, error) {
+	raw := ref
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("%q is not a valid Gist URL", ref)
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	// Find where the USER/ID or ID part starts
+	host := strings.ToLower(u.Hostname())
+	var id string
+	switch {
+	case len(segments) >= 2 && segments[len(segments)-2] == "gists":
+		// API URL: /gists/ID or /api/v3/gists/ID
+		id = segments[len(segments)-1]
+	case strings.HasPrefix(host, "gist.") || (len(segments) > 0 && segments[0] == "gist"):
+		if !strings.HasPrefix(host, "gist.") {
+			segments = segments[1:]
+		}
+		switch {
+		case len(segments) == 0:
+			return "", fmt.Errorf("%q does not link to a Gist", ref)
+		case len(segments) == 1 || segments[1] == "raw":
+			// ID or ID/raw/...
+			id = segments[0]
+		default:
+			// USER/ID, USER/ID/REVISION or USER/ID/raw/...
+			id = segments[1]
+		}
+	default:
+		return "", fmt.Errorf("%q is not a Gist URL", ref)
+	}
+
+	id = strings.TrimSuffix(id, ".git")
+	if !gistIDRegex.MatchString(id) {
+		return "", fmt.Errorf("could not find a Gist ID in %q", ref)
+	}
+	return id, nil
+}
+
+// gistAPIFileLimit is the most content the GitHub API returns for a Gist file; larger
+// files come back truncated
+const gistAPIFileLimit = 1 << 20
+
+// fetchGist retrieves a Gist, showing a spinner while waiting for the API
+func fetchGist(ctx context.Context, client *github.Client, id string) (*github.Gist, error) {
+	var gist *github.Gist
+	err := withSpinner("Fetching Gist...", func() error {
+		var err error
+		gist, _, err = client.Gists.Get(ctx, id)
+		return err
+	})
+	return gist, err
+}
+
+// gistFetchWorkers is how many Gists fetchGists requests from GitHub at once
+const gistFetchWorkers = 8
+
+// fetchGists retrieves several Gists concurrently, showing one spinner for all of them.
+// Each ID is fetched once, however often it is given. Gists that couldn't be fetched
+// are left out of the result and their errors returned by ID.
+func fetchGists(ctx context.Context, client *github.Client, ids []string) (map[string]*github.Gist, map[string]error) {
+	gists := make(map[string]*github.Gist, len(ids))
+	errs := make(map[string]error)
+	
+	queue := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	_ = withSpinner(fmt.Sprintf("Fetching %d Gists...", len(ids)), func() error {
+		for i := 0; i < gistFetchWorkers && i < len(ids); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for id := range queue {
+					gist, _, err := client.Gists.Get(ctx, id)
+					mu.Lock()
+					if err != nil {
+						errs[id] = err
+					} else {
+						gists[id] = gist
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+		
+		queued := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			if !queued[id] {
+				queued[id] = true
+				queue <- id
+			}
+		}
+		close(queue)
+		wg.Wait()
+		return nil
+	})
+	return gists, errs
+}
+
+// createGist creates a Gist, showing a spinner while waiting for the API
+func createGist(ctx context.Context, client *github.Client, gist *github.Gist) (*github.Gist, error) {
+	var created *github.Gist
+	err := withSpinner("Creating Gist...", func() error {
+		var err error
+		created, _, err = client.Gists.Create(ctx, gist)
+		return err
+	})
+	return created, err
+}
+
+// getGistEnvContent returns the content of the .env file in a Gist
+func getGistEnvContent(gist *github.Gist) ([]byte, error) {
+	file, ok := gist.Files[github.GistFilename(".env")]
+	if !ok || file.Content == nil {
+		return nil, errors.New("no .env file found in this Gist")
+	}
+	if gistFileTruncated(file) {
+		return nil, fmt.Errorf("the .env file in this Gist is %s, and the GitHub API only returns the first %s of a file, so it can't be read intact",
+			formatByteSize(int64(file.GetSize())), formatByteSize(gistAPIFileLimit))
+	}
+	content := []byte(*file.Content)
+	logging.Debug("Detected encryption mode", "gist_id", gist.GetID(), "file", ".env", "mode", contentMode(content))
+	return content, nil
+}
+
+// contentMode names how content is protected: "full", "masked" or "none"
+func contentMode(content []byte) string {
+	switch {
+	case encryption.IsEncrypted(content):
+		return "full"
+	case encryption.IsMasked(content):
+		return "masked"
+	}
+	return "none"
+}
+
+// gistFileTruncated reports whether the API returned only part of a file's content
+func gistFileTruncated(file github.GistFile) bool {
+	return file.Content != nil && len(*file.Content) < file.GetSize()
+}
+
+// decryptEnvContent decrypts fully encrypted or masked content, returning plain content unchanged
+func decryptEnvContent(content []byte) ([]byte, error) {
+	if encryption.IsEncrypted(content) {
+		return encryption.DecryptContent(content)
+	}
+	if encryption.IsMasked(content) {
+		return encryption.UnmaskEnvContent(content)
+	}
+	return content, nil
+}
+
+// encryptionState describes how content is protected: "encrypted", "masked" or "plain text"
+func encryptionState(content []byte) string {
+	switch {
+	case encryption.IsEncrypted(content):
+		return "encrypted"
+	case encryption.IsMasked(content):
+		return "masked"
+	default:
+		return "plain text"
+	}
+}
+
+// exitOnKeyFileError exits with a key file error if err is one, so an unusable key file
+// or key from --key-stdin or ENVI_KEY isn't reported as a wrong password or corrupted content
+func exitOnKeyFileError(err error) {
+	var keyErr *encryption.KeyFileError
+	if errors.As(err, &keyErr) {
+		exitWithError(ErrCodeBadKeyFile, keyErr.Error(),
+			"Check --key-file or 'envi config --default-key-file', and that the file was copied intact from the machine that encrypted the content")
+	}
+	var rawKeyErr *encryption.RawKeyError
+	if errors.As(err, &rawKeyErr) {
+		exitWithError(ErrCodeBadKeyFile, rawKeyErr.Error(),
+			"Check that the secret holds the whole key, as printed by 'openssl rand -base64 32' or stored in a key file")
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+
+	"github.com/dexterity-inc/envi/internal/logging"
+)
+
+// This file contains the GitHub Enterprise Server support. Every GitHub client and
+// github.com link goes through these helpers, so --github-url applies everywhere.
+
+// githubURL is the GitHub Enterprise Server address (--github-url or github_url in the
+// config), e.g. https://github.example.com. Public GitHub is used when it is empty.
+var githubURL string
+
+// resolveGitHubURL applies the github_url config setting unless --github-url was given,
+// and checks the result
+func resolveGitHubURL(cmd *cobra.Command) {
+	// 'envi config --validate' reports a bad github_url setting itself
+	if cmd == configCmd && configValidate && !cmd.Flags().Changed("github-url") {
+		return
+	}
+	if !cmd.Flags().Changed("github-url") {
+		if cfg := peekConfig(); cfg != nil {
+			githubURL = cfg.GitHubURL
+		}
+	}
+
+	normalized, err := normalizeGitHubURL(githubURL)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, err.Error(), "Use the address of your GitHub Enterprise Server, e.g. --github-url https://github.example.com")
+	}
+	githubURL = normalized
+	if githubURL != "" {
+		logging.Debug("Using GitHub Enterprise Server", "url", githubURL)
+	}
+}
+
+// normalizeGitHubURL checks a GitHub Enterprise Server address and reduces it to the
+// web address, so an API address such as https://github.example.com/api/v3 works too.
+// Public GitHub addresses become empty, the default.
+func normalizeGitHubURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("invalid GitHub URL %q: expected an http or https address", raw)
+	}
+	if u.Host == "github.com" || u.Host == "api.github.com" {
+		return "", nil
+	}
+
+	path := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/api/v3")
+	return u.Scheme + "://" + u.Host + strings.TrimSuffix(path, "/"), nil
+}
+
+// newGitHubClient returns a GitHub client authenticated with token, for public GitHub
+// or the GitHub Enterprise Server given by --github-url
+func newGitHubClient(ctx context.Context, token string) *github.Client {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	httpClient := oauth2.NewClient(ctx, ts)
+	if githubURL == "" {
+		return github.NewClient(httpClient)
+	}
+
+	// This only fails for an unparsable URL, which resolveGitHubURL has already ruled out
+	client, err := github.NewEnterpriseClient(githubURL, githubURL, httpClient)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid GitHub URL %s: %s", githubURL, err))
+	}
+	return client
+}
+
+// githubWebURL returns the web address of a page on GitHub, e.g. "settings/tokens"
+func githubWebURL(path string) string {
+	if githubURL == "" {
+		return "https://github.com/" + path
+	}
+	return githubURL + "/" + path
+}
+
+// gistWebURL returns the web address of a Gist. GitHub Enterprise Server serves Gists
+// under /gist on the server's own host.
+func gistWebURL(gistID string) string {
+	if githubURL == "" {
+		return "https://gist.github.com/" + gistID
+	}
+	return githubURL + "/gist/" + gistID
+}
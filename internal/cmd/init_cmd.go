@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/internal/tui"
+)
+
+// Encryption defaults offered by init, in the order they are listed
+var initEncryptionModes = []string{
+	"Masked: variable names visible, values encrypted (recommended)",
+	"Full: the whole file encrypted",
+	"None: push files as they are",
+}
+
+// initCmd is the init command
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Set up envi for a new project",
+	Long: `Walk through setting up envi step by step: the GitHub token, encryption defaults,
+an optional key file, and a starter .env and .env.example if the project has none.
+
+An existing config is updated, not replaced: each step shows the current setting and
+can keep it. Every setting can also be changed later with 'envi config'.`,
+	Args: cobra.NoArgs,
+	Run:  runInitCommand,
+}
+
+// InitInitCommand sets up the init command
+func InitInitCommand() {
+	// Add the init command to the root command
+	rootCmd.AddCommand(initCmd)
+}
+
+// runInitCommand handles the init command execution
+func runInitCommand(cmd *cobra.Command, args []string) {
+	if jsonOutput || !stdinIsTerminal() {
+		exitWithError(ErrCodeGeneric, "envi init is interactive and needs a terminal",
+			"Use 'envi config' flags to set up envi from a script")
+	}
+
+	// Note whether this is a first run before loading creates the default config
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not determine the config file location: %s", err))
+	}
+	_, statErr := os.Stat(configPath)
+	existing := statErr == nil
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not load config: %s", err))
+	}
+	if existing {
+		fmt.Printf("Found existing config at %s; it will be updated, not replaced.\n\n", configPath)
+	}
+
+	initToken(cfg)
+	initEncryption(cfg)
+
+	if err := config.SaveConfig(cfg); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not save config: %s", err))
+	}
+	fmt.Printf("Saved config to %s\n", configPath)
+
+	initEnvFiles()
+
+	fmt.Println("\nenvi is set up. Next steps:")
+	fmt.Println("  envi push      # Store your .env in a new secret Gist")
+	fmt.Println("  envi doctor    # Check the setup")
+}
+
+// initToken asks for a GitHub token, offering to keep one that is already set
+func initToken(cfg *config.Config) {
+	if _, source, err := config.ResolveGitHubToken(); err == nil {
+		replace, err := confirmPrompt("GitHub token", fmt.Sprintf("A GitHub token is already set in the %s. Replace it?", source))
+		if err != nil {
+			exitWithError(ErrCodeGeneric, "Setup canceled.")
+		}
+		if !replace {
+			return
+		}
+	}
+
+	fmt.Println("envi needs a GitHub personal access token with the gist scope.")
+	fmt.Printf("Create one at %s\n", githubWebURL("settings/tokens/new?scopes=gist&description=envi"))
+
+	token, err := initReadToken()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, "Setup canceled.")
+	}
+	if token == "" {
+		fmt.Println("No token given; set one later with 'envi config --token YOUR_TOKEN'")
+		return
+	}
+	if !config.IsValidGitHubToken(token) {
+		exitWithError(ErrCodeNoToken, "That doesn't look like a GitHub token",
+			"Expected a classic token (ghp_ plus 36 characters), a fine-grained token (github_pat_...), or a 40-character legacy token")
+	}
+	if !storeGitHubToken(cfg, token, false) {
+		exitWithError(ErrCodeNoToken, "Setup canceled: the token was not stored.")
+	}
+	fmt.Println()
+}
+
+// initReadToken reads a token without echoing it. An empty token skips the step.
+func initReadToken() (string, error) {
+	if encryption.UseTUI {
+		result, err := tui.New("GitHub token", "The token will not be displayed", []tui.InputField{{
+			Label:       "Token",
+			Placeholder: "ghp_...",
+			Secret:      true,
+			Help:        "Leave empty to skip",
+		}}).Start()
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(result["Token"]), nil
+	}
+
+	fmt.Print("GitHub token (leave empty to skip): ")
+	token, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// initEncryption asks for the default encryption mode and whether to use a key file
+func initEncryption(cfg *config.Config) {
+	current := 0
+	switch {
+	case !cfg.EncryptByDefault:
+		current = 2
+	case !cfg.UseMaskedEncryption:
+		current = 1
+	}
+
+	choice, err := initChoose("Encryption", "How should 'envi push' protect your files by default?", initEncryptionModes, current)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, "Setup canceled.")
+	}
+	cfg.EncryptByDefault = choice != 2
+	cfg.UseMaskedEncryption = choice == 0
+	fmt.Printf("Default encryption: %s\n", strings.SplitN(initEncryptionModes[choice], ":", 2)[0])
+	if choice == 2 {
+		return
+	}
+
+	useKeyFile, err := confirmPrompt("Key file", "Use a key file instead of a password? A key file doesn't need typing, but must be copied to every machine that decrypts.")
+	if err != nil {
+		exitWithError(ErrCodeGeneric, "Setup canceled.")
+	}
+	if !useKeyFile {
+		cfg.UseKeyFileByDefault = false
+		fmt.Println("You will be asked for a password, or it can be set in ENVI_PASSWORD")
+		return
+	}
+
+	keyFile := encryption.ResolveKeyFile(cfg.DefaultKeyFile)
+	if keyFile == "" {
+		dataDir, err := config.DataDir()
+		if err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not determine the data directory: %s", err))
+		}
+		keyFile = filepath.Join(dataDir, ".envi.key")
+	}
+
+	if _, err := os.Stat(keyFile); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not create %s: %s", filepath.Dir(keyFile), err))
+		}
+		if err := encryption.GenerateKeyFile(keyFile); err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not generate key file: %s", err))
+		}
+		fmt.Printf("Generated new key file at %s. Keep it safe; it is needed to decrypt your files.\n", keyFile)
+	} else {
+		fmt.Printf("Using existing key file %s\n", keyFile)
+	}
+	cfg.UseKeyFileByDefault = true
+	cfg.DefaultKeyFile = keyFile
+}
+
+// initChoose asks the user to pick one of options, in the TUI or as a numbered list
+func initChoose(title, question string, options []string, current int) (int, error) {
+	if encryption.UseTUI {
+		return tui.Choose(title, question, options, current)
+	}
+
+	fmt.Println(question)
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, option)
+	}
+	fmt.Printf("Choice [%d]: ", current+1)
+	var response string
+	fmt.Scanln(&response)
+	if response == "" {
+		return current, nil
+	}
+	choice, err := strconv.Atoi(response)
+	if err != nil || choice < 1 || choice > len(options) {
+		return 0, fmt.Errorf("invalid choice %q", response)
+	}
+	return choice - 1, nil
+}
+
+// initEnvFiles offers a starter .env and a matching .env.example when they are missing,
+// and to keep .env out of git
+func initEnvFiles() {
+	_, envErr := os.Stat(".env")
+	_, exampleErr := os.Stat(".env.example")
+
+	if os.IsNotExist(envErr) {
+		create, err := confirmPrompt("Starter files", "No .env here. Create a starter .env with sample variables?")
+		if err != nil {
+			exitWithError(ErrCodeGeneric, "Setup canceled.")
+		}
+		if create {
+			if err := os.WriteFile(".env", []byte(sampleEnvContent), 0600); err != nil {
+				exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not create .env: %s", err))
+			}
+			fmt.Println("Created .env; replace the sample values with your own")
+			envErr = nil
+		}
+	}
+
+	if envErr == nil && os.IsNotExist(exampleErr) {
+		create, err := confirmPrompt("Starter files", "Create .env.example from .env, without its values, to commit for your team?")
+		if err != nil {
+			exitWithError(ErrCodeGeneric, "Setup canceled.")
+		}
+		if create {
+			content, err := os.ReadFile(".env")
+			if err != nil {
+				exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not read .env: %s", err))
+			}
+			example, count := generateExampleContent(content)
+			if err := os.WriteFile(".env.example", example, 0644); err != nil {
+				exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not create .env.example: %s", err))
+			}
+			fmt.Printf("Created .env.example with %d variables\n", count)
+		}
+	}
+
+	if envErr == nil {
+		if status, err := gitIgnoreStatus(".env"); err == nil && status == gitNotIgnored {
+			offerGitignore(".env")
+		}
+	}
+}
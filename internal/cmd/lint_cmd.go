@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/pkg/envi"
+)
+
+// Lint command flags
+var (
+	lintEnvFile        string
+	lintSearchUp       bool
+	lintFix            bool
+	lintAllowLowercase bool
+	lintStrictKeys     bool
+	lintKeyPattern     string
+)
+
+// Lint issue severities
+const (
+	lintError   = "error"
+	lintWarning = "warning"
+)
+
+// Keys must start with a letter or underscore and contain only letters, digits and underscores
+var lintKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// lintIssue is a single problem found in a .env file
+type lintIssue struct {
+	Line     int
+	Severity string
+	Message  string
+	Fixable  bool
+}
+
+// lintCmd is the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check a .env file for common mistakes",
+	Long: `Check a .env file for structural problems such as lines without '=', invalid
+or lowercase keys, values that look like they contain an accidental inline
+comment, duplicate keys, trailing whitespace and CRLF line endings.
+
+With --strict-keys, keys that don't match --key-pattern (UPPER_SNAKE_CASE by
+default) are errors.
+
+Use --fix to correct the safe issues (trailing whitespace and line endings), and
+with --strict-keys to rename keys to UPPER_SNAKE_CASE where the result matches the
+pattern and doesn't clash with an existing key. Exits with a non-zero status when
+any errors are found.`,
+	Run: runLintCommand,
+}
+
+// InitLintCommand sets up the lint command
+func InitLintCommand() {
+	// Initialize the command flags
+	lintCmd.Flags().StringVarP(&lintEnvFile, "file", "f", ".env", "Path to the .env file to lint")
+	lintCmd.Flags().BoolVar(&lintSearchUp, "search-up", false, "Search parent directories for the nearest .env file")
+	lintCmd.Flags().BoolVar(&lintFix, "fix", false, "Fix trailing whitespace and CRLF line endings in place")
+	lintCmd.Flags().BoolVar(&lintAllowLowercase, "allow-lowercase", false, "Don't warn about keys containing lowercase letters")
+	lintCmd.Flags().BoolVar(&lintStrictKeys, "strict-keys", false, "Report keys that don't match --key-pattern as errors")
+	lintCmd.Flags().StringVar(&lintKeyPattern, "key-pattern", defaultKeyPattern, "Regular expression keys must match with --strict-keys")
+
+	// Add the lint command to the root command
+	rootCmd.AddCommand(lintCmd)
+}
+
+// runLintCommand handles the lint command execution
+func runLintCommand(cmd *cobra.Command, args []string) {
+	var keyPattern *regexp.Regexp
+	if lintStrictKeys {
+		keyPattern = compileKeyPattern(lintKeyPattern)
+	}
+
+	lintEnvFile = resolveEnvPath(lintEnvFile, lintSearchUp)
+
+	info, err := os.Stat(lintEnvFile)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, err.Error())
+	}
+
+	content, err := os.ReadFile(lintEnvFile)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not read %s: %s", lintEnvFile, err))
+	}
+
+	issues := lintEnvContent(content, lintAllowLowercase, keyPattern)
+
+	if lintFix {
+		fixed := renameKeys(fixEnvContent(content), strictKeyRenames(content, keyPattern))
+		if string(fixed) != string(content) {
+			if err := os.WriteFile(lintEnvFile, fixed, info.Mode().Perm()); err != nil {
+				exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not write %s: %s", lintEnvFile, err))
+			}
+		}
+	}
+
+	errorCount, warningCount, fixedCount := 0, 0, 0
+	for _, issue := range issues {
+		status := ""
+		if lintFix && issue.Fixable {
+			status = " (fixed)"
+			fixedCount++
+		} else if issue.Severity == lintError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+		fmt.Printf("%s:%d: %s: %s%s\n", lintEnvFile, issue.Line, issue.Severity, issue.Message, status)
+	}
+
+	if len(issues) == 0 {
+		fmt.Printf("✓ No issues found in %s\n", lintEnvFile)
+		return
+	}
+
+	fmt.Printf("\n%d errors, %d warnings", errorCount, warningCount)
+	if fixedCount > 0 {
+		fmt.Printf(", %d fixed", fixedCount)
+	}
+	fmt.Println()
+
+	if errorCount > 0 {
+		os.Exit(1)
+	}
+}
+
+// lintEnvContent checks .env content line by line and returns the issues found, ordered by line.
+// If keyPattern is non-nil, keys that don't match it are errors.
+func lintEnvContent(content []byte, allowLowercase bool, keyPattern *regexp.Regexp) []lintIssue {
+	var issues []lintIssue
+	renames := strictKeyRenames(content, keyPattern)
+
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		lineNum := i + 1
+
+		if strings.HasSuffix(line, "\r") {
+			issues = append(issues, lintIssue{lineNum, lintWarning, "CRLF line ending", true})
+			line = strings.TrimSuffix(line, "\r")
+		}
+
+		if line != strings.TrimRight(line, " \t") {
+			issues = append(issues, lintIssue{lineNum, lintWarning, "trailing whitespace", true})
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		stripped, _ := envi.SplitPrefix(line)
+		eq := strings.Index(stripped, "=")
+		if eq < 0 {
+			issues = append(issues, lintIssue{lineNum, lintError, "missing '=' between key and value", false})
+			continue
+		}
+
+		key := stripped[:eq]
+		value := strings.TrimRight(stripped[eq+1:], " \t")
+
+		if !lintKeyRegex.MatchString(key) {
+			issues = append(issues, lintIssue{lineNum, lintError, fmt.Sprintf("invalid key %q (use letters, digits and underscores)", key), false})
+			continue
+		}
+
+		if keyPattern != nil && !keyPattern.MatchString(key) {
+			message := fmt.Sprintf("key %s doesn't match %s", key, keyPattern)
+			if renamed, ok := renames[key]; ok {
+				message += fmt.Sprintf(" (--fix renames it to %s)", renamed)
+			}
+			issues = append(issues, lintIssue{lineNum, lintError, message, renames[key] != ""})
+		} else if !allowLowercase && key != strings.ToUpper(key) {
+			issues = append(issues, lintIssue{lineNum, lintWarning, fmt.Sprintf("key %s contains lowercase letters", key), false})
+		}
+
+		if !inlineComments && !isQuotedValue(value) && strings.Contains(value, " #") {
+			issues = append(issues, lintIssue{lineNum, lintWarning, fmt.Sprintf("value of %s contains ' #', which may be an accidental inline comment", key), false})
+		}
+	}
+
+	// Duplicate keys, reported at each later definition
+	entries, _ := parseEnvEntries(content)
+	for key, lineNums := range findDuplicateKeys(entries) {
+		for _, lineNum := range lineNums[1:] {
+			issues = append(issues, lintIssue{lineNum, lintWarning, fmt.Sprintf("duplicate key %s (first defined on line %d)", key, lineNums[0]), false})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+
+	return issues
+}
+
+// fixEnvContent normalizes line endings to LF and trims trailing whitespace
+func fixEnvContent(content []byte) []byte {
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// strictKeyRenames returns the new name of each key that doesn't match keyPattern and can
+// be fixed by normalizing it to UPPER_SNAKE_CASE. Keys whose normalized name still doesn't
+// match, or is already used by another key, are left out.
+func strictKeyRenames(content []byte, keyPattern *regexp.Regexp) map[string]string {
+	renames := make(map[string]string)
+	if keyPattern == nil {
+		return renames
+	}
+
+	entries, _ := parseEnvEntries(content)
+	used := make(map[string]bool)
+	for _, entry := range entries {
+		used[entry.Key] = true
+	}
+
+	for _, entry := range entries {
+		if keyPattern.MatchString(entry.Key) {
+			continue
+		}
+		renamed := normalizeKeyName(entry.Key)
+		if keyPattern.MatchString(renamed) && !used[renamed] {
+			renames[entry.Key] = renamed
+			used[renamed] = true
+		}
+	}
+	return renames
+}
+
+// renameKeys renames keys in place, keeping any export/set prefix, the value and comments
+func renameKeys(content []byte, renames map[string]string) []byte {
+	if len(renames) == 0 {
+		return content
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		stripped, _ := envi.SplitPrefix(line)
+		eq := strings.Index(stripped, "=")
+		if eq < 0 {
+			continue
+		}
+		if renamed, ok := renames[stripped[:eq]]; ok {
+			lines[i] = line[:len(line)-len(stripped)] + renamed + stripped[eq:]
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// isQuotedValue reports whether a value is wrapped in matching single or double quotes
+func isQuotedValue(value string) bool {
+	if len(value) < 2 {
+		return false
+	}
+	first, last := value[0], value[len(value)-1]
+	return (first == '"' || first == '\'') && first == last
+}
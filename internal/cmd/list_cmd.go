@@ -1,17 +1,20 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/google/go-github/v37/github"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2"
 
 	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
 )
 
 // List command flags
@@ -20,13 +23,48 @@ var (
 	listLimit     int
 	listFormat    string
 	listShowURLs  bool
+	listSince     string
+	listBefore    string
+	listAfter     string
+	listUser      string
+	listSort      string
+	listReverse   bool
+	listTimeFormat string
+	listDetails   bool
 )
 
+// gistListItem is a Gist as printed by 'list --format json'
+type gistListItem struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	CreatedAt   string   `json:"created_at,omitempty"`
+	UpdatedAt   string   `json:"updated_at,omitempty"`
+	Files       []string `json:"files"`
+	URL         string   `json:"url,omitempty"`
+	Current     bool     `json:"current"`
+	Details     *gistEnvDetails `json:"details,omitempty"`
+}
+
+// gistEnvDetails describes a Gist's .env file for 'list --details'
+type gistEnvDetails struct {
+	Variables  *int   `json:"variables"` // Unknown (null) when the file is fully encrypted
+	Encryption string `json:"encryption,omitempty"`
+	Size       int    `json:"size"`
+	Error      string `json:"error,omitempty"`
+}
+
 // listCmd is the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List your .env file Gists",
-	Long:  `List all your GitHub Gists containing .env files.`,
+	Long: `List all your GitHub Gists containing .env files.
+
+Use --user to list another user's Gists, such as a shared bot account.
+Only that user's public Gists are visible.
+
+With --details, the .env file of each listed Gist is fetched to show how many
+variables it has, how it is protected and its size. Variable names are visible in
+masked files, so they are counted too; fully encrypted files are not decrypted.`,
 	Run:   runListCommand,
 }
 
@@ -37,6 +75,15 @@ func InitListCommand() {
 	listCmd.Flags().IntVarP(&listLimit, "limit", "l", 10, "Limit number of Gists to show")
 	listCmd.Flags().StringVarP(&listFormat, "format", "f", "table", "Output format (table, json)")
 	listCmd.Flags().BoolVarP(&listShowURLs, "urls", "u", false, "Show Gist URLs in output")
+	listCmd.Flags().StringVar(&listSince, "since", "", "Only show Gists updated within this duration (e.g. 24h, 7d, 2w)")
+	listCmd.Flags().StringVar(&listBefore, "before", "", "Only show Gists updated before this date (YYYY-MM-DD or RFC3339)")
+	listCmd.Flags().StringVar(&listAfter, "after", "", "Only show Gists updated after this date (YYYY-MM-DD or RFC3339)")
+	listCmd.Flags().StringVar(&listUser, "user", "", "List another user's public Gists instead of your own")
+	listCmd.Flags().StringVar(&listUser, "owner", "", "Alias for --user")
+	listCmd.Flags().StringVar(&listSort, "sort", "updated", "Sort order (updated, created, name)")
+	listCmd.Flags().BoolVar(&listReverse, "reverse", false, "Reverse the sort order")
+	listCmd.Flags().BoolVar(&listDetails, "details", false, "Fetch each Gist's .env file to show its variable count, encryption and size")
+	listCmd.Flags().StringVar(&listTimeFormat, "time-format", "", "How to show dates: relative, absolute or rfc3339 (default from config, else absolute)")
 
 	// Add the list command to the root command
 	rootCmd.AddCommand(listCmd)
@@ -47,49 +94,104 @@ func runListCommand(cmd *cobra.Command, args []string) {
 	// Get GitHub token
 	token, err := config.GetGitHubToken()
 	if err != nil {
-		fmt.Println("Error:", err)
-		os.Exit(1)
+		exitWithError(ErrCodeNoToken, err.Error())
 	}
 	
 	// Load config to get last used Gist ID
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Warning: Could not load config: %s\n", err)
+		logWarn("Could not load config: %s", err)
+	}
+	
+	// The global --json flag implies JSON output
+	if jsonOutput {
+		listFormat = "json"
+	}
+	
+	if listSort != "updated" && listSort != "created" && listSort != "name" {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid --sort value %q (use updated, created or name)", listSort))
+	}
+	
+	// Pick the time format from the flag, then config
+	if listTimeFormat == "" && cfg != nil {
+		listTimeFormat = cfg.TimeFormat
+	}
+	if listTimeFormat == "" {
+		listTimeFormat = config.TimeFormatAbsolute
+	}
+	if !validTimeFormat(listTimeFormat) {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid --time-format value %q (use relative, absolute or rfc3339)", listTimeFormat))
 	}
 	
+	// Parse time filters
+	var after, before time.Time
+	if listSince != "" {
+		duration, err := parseRelativeDuration(listSince)
+		if err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid --since value: %s", err))
+		}
+		after = time.Now().Add(-duration)
+	}
+	if listAfter != "" {
+		t, err := parseDate(listAfter)
+		if err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid --after value: %s", err))
+		}
+		if t.After(after) {
+			after = t
+		}
+	}
+	if listBefore != "" {
+		before, err = parseDate(listBefore)
+		if err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid --before value: %s", err))
+		}
+	}
+	timeFilterActive := !after.IsZero() || !before.IsZero()
+	
 	// Create GitHub client
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(cmd.Context(), ts)
-	client := github.NewClient(tc)
+	client := newGitHubClient(cmd.Context(), token)
 	
 	// Get user's Gists
 	var allGists []*github.Gist
 	page := 1
 	perPage := 30 // GitHub's default per page
 	
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
 	for {
 		opts := &github.GistListOptions{
+			Since: after,
 			ListOptions: github.ListOptions{
 				Page:    page,
 				PerPage: perPage,
 			},
 		}
 		
-		gists, resp, err := client.Gists.List(cmd.Context(), "", opts)
+		// An empty username lists the authenticated user's own Gists
+		var gists []*github.Gist
+		var resp *github.Response
+		err := withSpinner("Listing Gists...", func() error {
+			var err error
+			gists, resp, err = client.Gists.List(ctx, listUser, opts)
+			return err
+		})
 		if err != nil {
-			fmt.Printf("Error fetching Gists: %s\n", err)
-			os.Exit(1)
+			exitWithError(ErrCodeAPI, fmt.Sprintf("Could not fetch Gists: %s", apiError(err)))
 		}
 		
 		allGists = append(allGists, gists...)
 		
-		if resp.NextPage == 0 || len(allGists) >= listLimit {
+		// Keep paging when a --before filter may exclude results already fetched
+		if resp.NextPage == 0 || (len(allGists) >= listLimit && before.IsZero()) {
 			break
 		}
 		
 		page = resp.NextPage
 	}
 	
+	sortGists(allGists, listSort, listReverse)
+	
 	// Filter Gists if needed
 	var filteredGists []*github.Gist
 	for _, gist := range allGists {
@@ -106,13 +208,31 @@ func runListCommand(cmd *cobra.Command, args []string) {
 			}
 		}
 		
+		// Apply time filters, skipping Gists without an update time
+		if timeFilterActive {
+			if gist.UpdatedAt == nil {
+				continue
+			}
+			if !after.IsZero() && gist.UpdatedAt.Before(after) {
+				continue
+			}
+			if !before.IsZero() && !gist.UpdatedAt.Before(before) {
+				continue
+			}
+		}
+		
 		if listAll || hasEnvFile {
 			filteredGists = append(filteredGists, gist)
 		}
 	}
 	
+	// GitHub only returns public Gists when listing another user
+	if listUser != "" && listFormat != "json" {
+		fmt.Printf("Showing public Gists for %s (secret Gists are only visible to their owner)\n\n", listUser)
+	}
+	
 	// Display Gists
-	if len(filteredGists) == 0 {
+	if len(filteredGists) == 0 && listFormat != "json" {
 		fmt.Println("No Gists found")
 		if !listAll {
 			fmt.Println("Try using --all to show all your Gists, not just those with .env files")
@@ -120,64 +240,57 @@ func runListCommand(cmd *cobra.Command, args []string) {
 		return
 	}
 	
+	// Fetch the .env files for --details, all at once
+	details := make(map[string]*gistEnvDetails)
+	if listDetails {
+		details = fetchEnvDetails(cmd, client, filteredGists)
+	}
+	
 	// Print output in requested format
 	if listFormat == "json" {
-		// Simple JSON output (in a real app, you'd use json.Marshal)
-		fmt.Println("[")
-		for i, gist := range filteredGists {
-			fmt.Printf("  {\n    \"id\": \"%s\",\n", *gist.ID)
-			
-			// Description
-			desc := "No description"
-			if gist.Description != nil && *gist.Description != "" {
-				desc = *gist.Description
+		output := make([]gistListItem, 0, len(filteredGists))
+		for _, gist := range filteredGists {
+			item := gistListItem{
+				ID:          gist.GetID(),
+				Description: gist.GetDescription(),
+				Files:       []string{},
+				Current:     cfg != nil && cfg.LastGistID == gist.GetID(),
 			}
-			fmt.Printf("    \"description\": \"%s\",\n", desc)
-			
-			// Created date
-			fmt.Printf("    \"created_at\": \"%s\",\n", gist.CreatedAt.Format(time.RFC3339))
-			
-			// URL
-			if listShowURLs {
-				fmt.Printf("    \"url\": \"https://gist.github.com/%s\",\n", *gist.ID)
+			if gist.CreatedAt != nil {
+				item.CreatedAt = gist.CreatedAt.Format(time.RFC3339)
 			}
-			
-			// Files
-			fmt.Printf("    \"files\": [")
-			fileCount := 0
-			for filename := range gist.Files {
-				if fileCount > 0 {
-					fmt.Print(", ")
-				}
-				fmt.Printf("\"%s\"", filename)
-				fileCount++
+			if gist.UpdatedAt != nil {
+				item.UpdatedAt = gist.UpdatedAt.Format(time.RFC3339)
 			}
-			fmt.Print("]\n")
-			
-			// Current Gist indicator
-			if cfg != nil && cfg.LastGistID == *gist.ID {
-				fmt.Print("    \"current\": true\n")
-			} else {
-				fmt.Print("    \"current\": false\n")
+			if listShowURLs {
+				item.URL = gistWebURL(gist.GetID())
 			}
-			
-			if i < len(filteredGists)-1 {
-				fmt.Println("  },")
-			} else {
-				fmt.Println("  }")
+			for filename := range gist.Files {
+				item.Files = append(item.Files, string(filename))
 			}
+			sort.Strings(item.Files)
+			item.Details = details[gist.GetID()]
+			output = append(output, item)
+		}
+		
+		encoder := json.NewEncoder(resultStdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not encode JSON: %s", err))
 		}
-		fmt.Println("]")
 	} else {
 		// Table format
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		
 		// Print header
+		header := "ID\tDESCRIPTION\tFILES\tCREATED\t"
+		if listDetails {
+			header += "VARS\tENCRYPTION\tSIZE\t"
+		}
 		if listShowURLs {
-			fmt.Fprintln(w, "ID\tDESCRIPTION\tFILES\tCREATED\tURL\t")
-		} else {
-			fmt.Fprintln(w, "ID\tDESCRIPTION\tFILES\tCREATED\t")
+			header += "URL\t"
 		}
+		fmt.Fprintln(w, header)
 		
 		// Print each Gist
 		for _, gist := range filteredGists {
@@ -191,16 +304,14 @@ func runListCommand(cmd *cobra.Command, args []string) {
 			}
 			
 			// Format created time
-			createdTime := "Unknown"
-			if gist.CreatedAt != nil {
-				createdTime = gist.CreatedAt.Format("2006-01-02")
-			}
+			createdTime := formatTime(gist.GetCreatedAt(), listTimeFormat, time.Now())
 			
 			// Build file list
 			var fileList []string
 			for filename := range gist.Files {
 				fileList = append(fileList, string(filename))
 			}
+			sort.Strings(fileList)
 			filesStr := strings.Join(fileList, ", ")
 			if len(filesStr) > 30 {
 				filesStr = filesStr[:27] + "..."
@@ -213,16 +324,178 @@ func runListCommand(cmd *cobra.Command, args []string) {
 			}
 			
 			// Print row
+			row := fmt.Sprintf("%s\t%s\t%s\t%s\t", idStr, desc, filesStr, createdTime)
+			if listDetails {
+				row += formatEnvDetails(details[*gist.ID])
+			}
 			if listShowURLs {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\thttps://gist.github.com/%s\t\n",
-					idStr, desc, filesStr, createdTime, *gist.ID)
-			} else {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n",
-					idStr, desc, filesStr, createdTime)
+				row += gistWebURL(*gist.ID) + "\t"
 			}
+			fmt.Fprintln(w, row)
 		}
 		
 		w.Flush()
 		fmt.Println("\n* = current Gist")
 	}
-} 
\ No newline at end of file
+} 
+// fetchEnvDetails fetches the listed Gists that have a .env file and describes each
+// file, keyed by Gist ID. The list API doesn't return file contents, so every Gist is
+// fetched, concurrently.
+func fetchEnvDetails(cmd *cobra.Command, client *github.Client, gists []*github.Gist) map[string]*gistEnvDetails {
+	var ids []string
+	for _, gist := range gists {
+		if _, ok := gist.Files[github.GistFilename(".env")]; ok {
+			ids = append(ids, gist.GetID())
+		}
+	}
+	
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	fetched, errs := fetchGists(ctx, client, ids)
+	
+	details := make(map[string]*gistEnvDetails, len(ids))
+	for _, id := range ids {
+		if err, failed := errs[id]; failed {
+			logWarn("Could not fetch Gist %s: %s", id, apiError(err))
+			details[id] = &gistEnvDetails{Error: apiError(err).Error()}
+			continue
+		}
+		content, err := getGistEnvContent(fetched[id])
+		if err != nil {
+			logWarn("Could not read the .env file of Gist %s: %s", id, err)
+			details[id] = &gistEnvDetails{Error: err.Error()}
+			continue
+		}
+		detail := &gistEnvDetails{Encryption: encryptionState(content), Size: len(content)}
+		if !encryption.IsEncrypted(content) {
+			entries, _ := parseEnvEntries(content)
+			keys := make(map[string]bool, len(entries))
+			for _, entry := range entries {
+				keys[entry.Key] = true
+			}
+			count := len(keys)
+			detail.Variables = &count
+		}
+		details[id] = detail
+	}
+	return details
+}
+
+// formatEnvDetails returns the VARS, ENCRYPTION and SIZE cells of a 'list --details' row
+func formatEnvDetails(detail *gistEnvDetails) string {
+	switch {
+	case detail == nil:
+		return "-\t-\t-\t"
+	case detail.Error != "":
+		return "?\terror\t-\t"
+	}
+	vars := "?"
+	if detail.Variables != nil {
+		vars = strconv.Itoa(*detail.Variables)
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t", vars, detail.Encryption, formatByteSize(int64(detail.Size)))
+}
+
+// parseRelativeDuration parses a duration, extending Go's syntax with days (d) and weeks (w)
+func parseRelativeDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	
+	unit := value[len(value)-1]
+	if unit == 'd' || unit == 'w' {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		days := n
+		if unit == 'w' {
+			days = n * 7
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
+
+// validTimeFormat reports whether format is one of the formats accepted by formatTime
+func validTimeFormat(format string) bool {
+	switch format {
+	case config.TimeFormatRelative, config.TimeFormatAbsolute, config.TimeFormatRFC3339:
+		return true
+	}
+	return false
+}
+
+// formatTime shows a time as a local date (absolute), an RFC3339 timestamp, or relative
+// to now such as "3 hours ago". Relative times older than 30 days fall back to the date,
+// and times in the future, e.g. from clock skew, are shown as "just now".
+func formatTime(t time.Time, format string, now time.Time) string {
+	if t.IsZero() {
+		return "Unknown"
+	}
+	
+	switch format {
+	case config.TimeFormatRFC3339:
+		return t.Format(time.RFC3339)
+	case config.TimeFormatRelative:
+		age := now.Sub(t)
+		switch {
+		case age < time.Minute:
+			return "just now"
+		case age < time.Hour:
+			return timeAgo(int(age/time.Minute), "minute")
+		case age < 24*time.Hour:
+			return timeAgo(int(age/time.Hour), "hour")
+		case age < 30*24*time.Hour:
+			return timeAgo(int(age/(24*time.Hour)), "day")
+		}
+	}
+	return t.Local().Format("2006-01-02")
+}
+
+// timeAgo formats a count of units as "1 hour ago" or "5 hours ago"
+func timeAgo(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s ago", unit)
+	}
+	return fmt.Sprintf("%d %ss ago", n, unit)
+}
+
+// parseDate parses a date in YYYY-MM-DD or RFC3339 format
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD or RFC3339)", value)
+}
+
+// sortGists sorts Gists by update time or creation time (newest first) or by description
+// (case-insensitive, A-Z). The sort is stable, so Gists that compare equal keep the
+// order GitHub returned them in.
+func sortGists(gists []*github.Gist, by string, reverse bool) {
+	less := func(a, b *github.Gist) bool {
+		switch by {
+		case "created":
+			return a.GetCreatedAt().After(b.GetCreatedAt())
+		case "name":
+			return strings.ToLower(a.GetDescription()) < strings.ToLower(b.GetDescription())
+		default:
+			return a.GetUpdatedAt().After(b.GetUpdatedAt())
+		}
+	}
+	sort.SliceStable(gists, func(i, j int) bool {
+		if reverse {
+			return less(gists[j], gists[i])
+		}
+		return less(gists[i], gists[j])
+	})
+}
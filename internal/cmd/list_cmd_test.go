@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v37/github"
+)
+
+func TestSortGists(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC)
+		return &t
+	}
+	gist := func(id, description string, created, updated int) *github.Gist {
+		return &github.Gist{
+			ID:          github.String(id),
+			Description: github.String(description),
+			CreatedAt:   day(created),
+			UpdatedAt:   day(updated),
+		}
+	}
+	newGists := func() []*github.Gist {
+		return []*github.Gist{
+			gist("a", "staging", 1, 5),
+			gist("b", "Production", 3, 3),
+			gist("c", "dev", 2, 9),
+			gist("d", "production", 4, 3), // Same update time and name as b
+		}
+	}
+
+	tests := []struct {
+		by      string
+		reverse bool
+		want    []string
+	}{
+		{"updated", false, []string{"c", "a", "b", "d"}},
+		{"updated", true, []string{"b", "d", "a", "c"}},
+		{"created", false, []string{"d", "b", "c", "a"}},
+		{"created", true, []string{"a", "c", "b", "d"}},
+		{"name", false, []string{"c", "b", "d", "a"}},
+		{"name", true, []string{"a", "b", "d", "c"}},
+	}
+	for _, tt := range tests {
+		gists := newGists()
+		sortGists(gists, tt.by, tt.reverse)
+
+		var got []string
+		for _, g := range gists {
+			got = append(got, g.GetID())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sortGists(%s, reverse=%v) = %v, want %v", tt.by, tt.reverse, got, tt.want)
+		}
+	}
+}
+
+func TestSortGistsEmpty(t *testing.T) {
+	var gists []*github.Gist
+	sortGists(gists, "updated", false)
+	if len(gists) != 0 {
+		t.Errorf("sortGists() of no Gists = %v", gists)
+	}
+}
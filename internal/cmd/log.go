@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/internal/tui"
+)
+
+// Progress and warning messages go to stderr so stdout only carries data and results,
+// e.g. the content printed by 'envi pull --stdout' or the JSON printed with --json.
+
+// quietOutput silences progress messages (--quiet)
+var quietOutput bool
+
+// logInfo prints a progress message to stderr unless --quiet is set
+func logInfo(format string, args ...interface{}) {
+	if quietOutput {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+// logWarn prints a warning to stderr. Warnings are shown even with --quiet.
+func logWarn(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// withSpinner runs fn while showing a spinner on stderr. The spinner is only shown with
+// the TUI enabled, without --quiet or --json, and when both stdout and stderr are terminals,
+// so piped output such as 'envi pull --stdout' is never mixed with it.
+func withSpinner(message string, fn func() error) error {
+	if !encryption.UseTUI || quietOutput || jsonOutput ||
+		!term.IsTerminal(int(resultStdout.Fd())) || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return fn()
+	}
+	return tui.WithSpinner(message, fn)
+}
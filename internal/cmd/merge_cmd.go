@@ -2,37 +2,58 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/google/go-github/v37/github"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2"
 
 	"github.com/dexterity-inc/envi/internal/config"
 	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/internal/tui"
+	"github.com/dexterity-inc/envi/pkg/envi"
 )
 
 // Merge command flags
 var (
 	mergeFiles          []string
 	mergeOutput         string
-	mergeGistID         string
+	mergeGistIDs        []string
 	mergeSkipDuplicates bool
 	mergeOverwrite      bool
 	mergeKeepComments   bool
 	mergeSort           bool
 	mergeCreateBackup   bool
 	mergeUnmask         bool
+	mergeSearchUp       bool
+	mergeWipeBackup     bool
+	mergeAnnotate       bool
+	mergeThreeWay       bool
+	mergeNoHeader       bool
 )
 
+// annotationRegex matches the provenance comments written by --annotate, so they are
+// not collected again when an annotated file is merged
+var annotationRegex = regexp.MustCompile(`^#\s*(from (local|remote) \(|conflict: kept )`)
+
+// mergeHeaderRegex matches the header lines written at the top of merged output, so a
+// merged file that is merged again gets one fresh header instead of stacked old ones
+var mergeHeaderRegex = regexp.MustCompile(`^#\s*(\.env file created by envi merge|Created on \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}|Merged local \.env with remote Gists?: |Merged from \d+ files: |Merged comments from source files:)`)
+
 // mergeCmd is the merge command
 var mergeCmd = &cobra.Command{
 	Use:   "merge",
 	Short: "Merge multiple .env files",
-	Long:  `Merge multiple .env files or merge with a remote Gist .env file.`,
+	Long: `Merge multiple .env files or merge with the .env files of one or more Gists.
+
+Sources are read in order: local files first, then each Gist in the order given.
+Repeat --gist (or separate IDs with commas) to layer Gists, such as a shared base
+followed by a team overlay. With --overwrite, a later Gist overrides earlier ones.
+With --skip-duplicates, local files always win, and among Gists the later one wins.`,
 	Run:   runMergeCommand,
 }
 
@@ -40,14 +61,22 @@ var mergeCmd = &cobra.Command{
 func InitMergeCommand() {
 	// Initialize the command flags
 	mergeCmd.Flags().StringSliceVarP(&mergeFiles, "files", "f", []string{}, "Paths to local .env files to merge (comma-separated)")
-	mergeCmd.Flags().StringVarP(&mergeGistID, "gist", "g", "", "GitHub Gist ID to merge with (will fetch remote .env)")
-	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", ".env", "Output file path")
+	mergeCmd.Flags().StringSliceVarP(&mergeGistIDs, "gist", "g", []string{}, "GitHub Gist IDs or @BOOKMARKs to merge with, in precedence order (repeatable or comma-separated)")
+	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", ".env", "Output file path (- for stdout)")
+	mergeCmd.Flags().StringVar(&mergeOutput, "file", ".env", "Path to the local .env file to write (alias for --output)")
 	mergeCmd.Flags().BoolVarP(&mergeSkipDuplicates, "skip-duplicates", "s", false, "Skip duplicates (local file takes precedence)")
 	mergeCmd.Flags().BoolVarP(&mergeOverwrite, "overwrite", "w", false, "Overwrite duplicates (remote file takes precedence)")
-	mergeCmd.Flags().BoolVarP(&mergeKeepComments, "keep-comments", "c", true, "Keep comments from all files")
+	mergeCmd.Flags().BoolVarP(&mergeKeepComments, "keep-comments", "c", true, "Keep comments from all files, each above its variable")
 	mergeCmd.Flags().BoolVar(&mergeSort, "sort", false, "Sort variables alphabetically")
 	mergeCmd.Flags().BoolVar(&mergeCreateBackup, "backup", true, "Create backup of output file if it exists")
 	mergeCmd.Flags().BoolVar(&mergeUnmask, "unmask", false, "Unmask/decrypt values from remote Gist when merging")
+	mergeCmd.Flags().BoolVar(&mergeSearchUp, "search-up", false, "Search parent directories for the nearest output .env file")
+	mergeCmd.Flags().BoolVar(&mergeAnnotate, "annotate", false, "Add a comment above variables from other sources and above resolved conflicts")
+	mergeCmd.Flags().BoolVar(&mergeNoHeader, "no-header", false, "Don't write the header comment saying how the file was merged")
+	mergeCmd.Flags().BoolVar(&mergeThreeWay, "three-way", false, "Compare both sides with the last synced state and only ask about variables changed on both")
+	mergeCmd.Flags().BoolVar(&mergeWipeBackup, "wipe-backup", false, "Securely delete the backup file once the merge succeeds")
+	mergeCmd.Flags().BoolVar(&encryption.PasswordFromStdin, "password-stdin", false, "Read the encryption password from the first line of stdin")
+	mergeCmd.Flags().BoolVar(&encryption.KeyFromStdin, "key-stdin", false, "Read the base64-encoded encryption key from the first line of stdin, instead of a password or key file")
 
 	// Add the merge command to the root command
 	rootCmd.AddCommand(mergeCmd)
@@ -56,239 +85,555 @@ func InitMergeCommand() {
 // runMergeCommand handles the merge command execution
 func runMergeCommand(cmd *cobra.Command, args []string) {
 	// Check if we're merging with a Gist or local files
-	if mergeGistID == "" && len(mergeFiles) == 0 {
-		fmt.Println("Error: You must specify either local files to merge (--files) or a Gist ID to merge with (--gist)")
-		fmt.Println("Run 'envi merge --help' for usage information")
-		os.Exit(1)
+	if len(mergeGistIDs) == 0 && len(mergeFiles) == 0 {
+		exitWithError(ErrCodeGeneric, "You must specify either local files to merge (--files) or a Gist ID to merge with (--gist)",
+			"Run 'envi merge --help' for usage information")
+	}
+
+	// --file is an alias for --output, so only one of them can say where to write
+	if cmd.Flags().Changed("file") && cmd.Flags().Changed("output") {
+		exitWithError(ErrCodeGeneric, "--file and --output can't be used together", "--file is an alias for --output")
+	}
+
+	// A three-way merge compares with the last synced state of a Gist
+	if mergeThreeWay && len(mergeGistIDs) != 1 {
+		exitWithError(ErrCodeGeneric, "--three-way needs exactly one Gist to merge with (--gist)")
+	}
+
+	// Conflicts are resolved by asking on stdin, which holds the password or key with
+	// --password-stdin or --key-stdin
+	checkStdinSecretFlags()
+	if encryption.ReadsStdin() && !mergeSkipDuplicates && !mergeOverwrite {
+		exitWithError(ErrCodeGeneric, encryption.StdinFlag()+" needs --skip-duplicates or --overwrite",
+			"Conflicts can't be resolved interactively while stdin holds the password or key")
+	}
+
+	// With --output -, the merged content goes to stdout and messages to stderr
+	toStdout := mergeOutput == "-"
+	if toStdout {
+		routeInfoToStderr()
+	} else {
+		// Resolve the output path
+		mergeOutput = resolveEnvPath(mergeOutput, mergeSearchUp)
 	}
 
 	// Create backup if output file exists
-	if _, err := os.Stat(mergeOutput); err == nil && mergeCreateBackup {
-		backupFile := fmt.Sprintf("%s.bak.%s", mergeOutput, time.Now().Format("20060102150405"))
+	backupFile := ""
+	if _, err := os.Stat(mergeOutput); err == nil && mergeCreateBackup && !toStdout {
+		backupFile = fmt.Sprintf("%s.bak.%s", mergeOutput, time.Now().Format("20060102150405"))
 		err := copyFile(mergeOutput, backupFile)
 		if err != nil {
-			fmt.Printf("Warning: Could not create backup file: %s\n", err)
+			logWarn("Could not create backup file: %s", err)
+			backupFile = ""
 		} else {
-			fmt.Printf("Created backup of existing file at %s\n", backupFile)
+			logInfo("Created backup of existing file at %s", backupFile)
 		}
 	}
 
 	// Variables to store merged content
 	variables := make(map[string]string)
-	comments := []string{}
+	var fileComments []string                // Comments at the top of each file, before its first variable
+	var trailingComments []string            // Comments after the last variable of each file
+	keyComments := make(map[string][]string) // Comment lines directly above each variable
 	variableOrder := []string{} // To preserve order if not sorting
+	prefixes := make(map[string]string) // Shell prefix (export/set) each variable was declared with
+	sources := make(map[string]string)  // Source each variable's current value came from
+	valueComments := make(map[string]map[string]string) // Inline comment attached to each value of a variable
+	resolutions := make(map[string][2]string)           // Winning and losing source of each duplicate with different values
+	var conflicts []tui.Conflict
+	var base *envSnapshot          // Last synced state of the Gist, for --three-way
+	remoteKeys := make(map[string]bool) // Variables defined in the remote Gist
+	localKeys := make(map[string]bool)  // Variables defined in the local files
+	var syncedContent []byte            // Plain remote content, the new synced state once merged
 	filesToProcess := mergeFiles
 
-	// If merging with a Gist, fetch the remote .env file
-	var remoteContent []byte
-	if mergeGistID != "" {
-		fmt.Printf("Fetching Gist with ID: %s\n", mergeGistID)
-		
-		// Get GitHub token
-		token, err := config.GetGitHubToken()
-		if err != nil {
-			fmt.Printf("Error: %s\n", err)
-			os.Exit(1)
+	// Verify all local files exist and read them
+	var mergeSources []mergeSource
+	for _, file := range filesToProcess {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			exitWithError(ErrCodeNoEnvFile, fmt.Sprintf(".env file not found at %s", file))
 		}
 		
-		// Create GitHub client
-		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-		tc := oauth2.NewClient(cmd.Context(), ts)
-		client := github.NewClient(tc)
-		
-		// Get Gist
-		gist, _, err := client.Gists.Get(cmd.Context(), mergeGistID)
+		content, err := os.ReadFile(file)
 		if err != nil {
-			fmt.Printf("Error retrieving Gist with ID %s: %s\n", mergeGistID, err)
-			os.Exit(1)
+			exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Could not open file %s: %s", file, err))
 		}
-		
-		// Find .env file in Gist
-		var envFile *github.GistFile
-		for filename, file := range gist.Files {
-			if string(filename) == ".env" {
-				envFile = &file
-				break
-			}
+		mergeSources = append(mergeSources, mergeSource{name: file, content: content})
+	}
+
+	// Fetch the .env file of each Gist, in order, and keep it in memory
+	if len(mergeGistIDs) > 0 {
+		// Get GitHub token
+		token, err := config.GetGitHubToken()
+		if err != nil {
+			exitWithError(ErrCodeNoToken, err.Error())
 		}
 		
-		if envFile == nil {
-			fmt.Println("Error: No .env file found in this Gist")
-			os.Exit(1)
+		for i, ref := range mergeGistIDs {
+			mergeGistIDs[i] = resolveGistRef(ref)
 		}
-		
-		// Get content
-		remoteContent = []byte(*envFile.Content)
-		
-		// Check if content is encrypted and needs decryption
-		isEncrypted := encryption.IsEncrypted(remoteContent)
-		isMasked := encryption.IsMasked(remoteContent)
-		
-		if (isEncrypted || isMasked) && mergeUnmask {
-			fmt.Println("Detected encrypted content. Attempting to decrypt...")
-			
-			var decryptedContent []byte
-			var err error
+		for _, gistID := range mergeGistIDs {
+			remoteContent := fetchMergeGist(cmd, token, gistID)
 			
-			if isEncrypted {
-				decryptedContent, err = encryption.DecryptContent(remoteContent)
-			} else if isMasked {
-				decryptedContent, err = encryption.UnmaskEnvContent(remoteContent)
+			// The last synced state is the common ancestor of both sides
+			if mergeThreeWay {
+				base = loadMergeBase(gistID, remoteContent)
 			}
 			
-			if err != nil {
-				fmt.Println("Error decrypting content. Please check your encryption settings and try again.")
-				os.Exit(1)
+			// Only a single Gist's content is the synced state once merged
+			if len(mergeGistIDs) == 1 && !encryption.IsEncrypted(remoteContent) && !encryption.IsMasked(remoteContent) {
+				syncedContent = remoteContent
 			}
 			
-			remoteContent = decryptedContent
-			fmt.Println("Successfully decrypted remote content!")
-		} else if (isEncrypted || isMasked) && !mergeUnmask {
-			fmt.Println("Warning: Remote content is encrypted/masked but --unmask flag not specified.")
-			fmt.Println("Merging encrypted content - this may not be what you want.")
-		}
-		
-		// Save to a temporary file
-		tempFile := ".env.remote.tmp"
-		if err := os.WriteFile(tempFile, remoteContent, 0600); err != nil {
-			fmt.Printf("Error writing temporary file: %s\n", err)
-			os.Exit(1)
-		}
-		defer os.Remove(tempFile) // Clean up temporary file
-		
-		// Add to files to process
-		filesToProcess = append(filesToProcess, tempFile)
-		fmt.Println("Remote .env file added to merge")
-	}
-
-	// Verify all local files exist
-	for _, file := range filesToProcess {
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			fmt.Printf("Error: .env file not found at %s\n", file)
-			os.Exit(1)
+			// Add to sources to process
+			mergeSources = append(mergeSources, mergeSource{name: "Gist " + gistID, content: remoteContent, remote: true, gistID: gistID})
+			logInfo("Remote .env file of Gist %s added to merge", gistID)
 		}
 	}
 
-	// Process each file
-	for _, file := range filesToProcess {
-		fmt.Printf("Processing file: %s\n", file)
+	// Process each source
+	for _, source := range mergeSources {
+		logInfo("Processing file: %s", source.name)
 		
-		// Open file
-		f, err := os.Open(file)
-		if err != nil {
-			fmt.Printf("Error opening file %s: %s\n", file, err)
-			os.Exit(1)
-		}
+		// Comments read since the last variable, which belong to the next one
+		var pending []string
+		seenVariable := false
 		
-		// Read file line by line
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			line := scanner.Text()
+		// Read content line by line; lines may be of any length
+		lines, _ := encryption.SplitLines(source.content)
+		for _, line := range lines {
 			trimmedLine := strings.TrimSpace(line)
 			
-			// Handle empty lines
+			// Handle empty lines; before the first variable, a blank line ends the file's own comments
 			if trimmedLine == "" {
+				if !seenVariable {
+					fileComments = appendNewComments(fileComments, pending)
+					pending = nil
+				}
 				continue
 			}
 			
 			// Handle comments
 			if strings.HasPrefix(trimmedLine, "#") {
-				if mergeKeepComments {
-					comments = append(comments, line)
+				if mergeKeepComments && !annotationRegex.MatchString(trimmedLine) && !mergeHeaderRegex.MatchString(trimmedLine) {
+					pending = append(pending, line)
 				}
 				continue
 			}
 			
-			// Handle environment variables (KEY=value)
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				key := parts[0]
-				value := parts[1]
+			// Handle environment variables (KEY=value), remembering any export/set prefix
+			if variable, ok := envi.ParseLine(line, envParseOptions()); ok {
+				key, value, prefix, comment := variable.Key, variable.Value, variable.Prefix, variable.Comment
+				seenVariable = true
+				
+				// The comments above a variable stay with it, once however many sources have them
+				keyComments[key] = appendNewComments(keyComments[key], pending)
+				pending = nil
+				if source.remote {
+					remoteKeys[key] = true
+				} else {
+					localKeys[key] = true
+				}
+				
+				// Remember the comment so it stays with its value whichever value wins
+				if comment != "" {
+					if valueComments[key] == nil {
+						valueComments[key] = make(map[string]string)
+					}
+					if _, ok := valueComments[key][value]; !ok {
+						valueComments[key][value] = comment
+					}
+				}
 				
 				// Check for duplicates
 				_, exists := variables[key]
 				if exists {
 					// Handling duplicates differently based on whether this is from Gist
-					isRemoteFile := file == ".env.remote.tmp"
+					isRemoteFile := source.remote
+					changed := variables[key] != value
 					
-					if mergeOverwrite && isRemoteFile {
+					if base != nil && isRemoteFile && changed && base.unchanged(key, variables[key]) {
+						// Only the remote side changed since the last sync
+						logInfo("Taking remote change to variable: %s", key)
+						variables[key] = value
+						prefixes[key] = prefix
+						sources[key] = source.label()
+					} else if base != nil && isRemoteFile && changed && base.unchanged(key, value) {
+						// Only the local side changed since the last sync
+						logInfo("Keeping local change to variable: %s", key)
+					} else if mergeOverwrite && isRemoteFile {
 						// If we're overwriting and this is the remote file, it takes precedence
-						fmt.Printf("Overwriting with remote value for variable: %s\n", key)
+						logInfo("Overwriting with remote value for variable: %s", key)
+						if variables[key] != value {
+							resolutions[key] = [2]string{source.label(), sources[key]}
+						}
+						variables[key] = value
+						prefixes[key] = prefix
+						sources[key] = source.label()
+					} else if mergeSkipDuplicates && isRemoteFile && !localKeys[key] {
+						// No local file sets it, so a later Gist overrides an earlier one
+						logInfo("Overriding earlier Gist value for variable: %s", key)
+						if variables[key] != value {
+							resolutions[key] = [2]string{source.label(), sources[key]}
+						}
 						variables[key] = value
-					} else if mergeSkipDuplicates && !isRemoteFile {
-						// If we're skipping duplicates and this is a local file, it takes precedence
-						fmt.Printf("Keeping local value for duplicate variable: %s\n", key)
-					} else if !mergeSkipDuplicates && !mergeOverwrite {
-						fmt.Printf("Warning: Duplicate variable found: %s\n", key)
-						fmt.Printf("  Local value: %s\n", variables[key])
-						fmt.Printf("  Remote value: %s\n", value)
-						fmt.Println("Use --overwrite to prefer remote values or --skip-duplicates to prefer local values")
+						prefixes[key] = prefix
+						sources[key] = source.label()
+					} else if mergeSkipDuplicates {
+						// If we're skipping duplicates, the local value takes precedence
+						logInfo("Keeping local value for duplicate variable: %s", key)
+						if variables[key] != value {
+							resolutions[key] = [2]string{sources[key], source.label()}
+						}
+					} else if !mergeSkipDuplicates && !mergeOverwrite && variables[key] != value {
+						// Record the conflict so the user can resolve it once all files are read
+						conflicts = append(conflicts, tui.Conflict{
+							Key:         key,
+							LocalValue:  variables[key],
+							RemoteValue: value,
+							LocalLabel:  sources[key],
+							RemoteLabel: source.label(),
+						})
 					}
 				} else {
 					variables[key] = value
+					prefixes[key] = prefix
+					sources[key] = source.label()
 					variableOrder = append(variableOrder, key)
 				}
 			}
 		}
 		
-		f.Close()
+		// Comments after the last variable, or in a file without variables
+		if seenVariable {
+			trailingComments = appendNewComments(trailingComments, pending)
+		} else {
+			fileComments = appendNewComments(fileComments, pending)
+		}
+	}
+
+	// Carry over variables removed on one side since the last sync, unless the other
+	// side changed them
+	if base != nil {
+		kept := variableOrder[:0]
+		for _, key := range variableOrder {
+			if !remoteKeys[key] && base.unchanged(key, variables[key]) {
+				logInfo("Removing variable deleted in the remote Gist: %s", key)
+				delete(variables, key)
+				continue
+			}
+			if !localKeys[key] && base.unchanged(key, variables[key]) {
+				logInfo("Removing variable deleted locally: %s", key)
+				delete(variables, key)
+				continue
+			}
+			if base.has(key) && (!remoteKeys[key] || !localKeys[key]) {
+				logWarn("Keeping %s: it was deleted on one side but changed on the other", key)
+			}
+			kept = append(kept, key)
+		}
+		variableOrder = kept
+	}
+
+	// Resolve conflicting values
+	if len(conflicts) > 0 {
+		logInfo("Found %d conflicting variables", len(conflicts))
+		if !stdinIsTerminal() {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("%d conflicting variables need a decision, but stdin is not a terminal to ask", len(conflicts)),
+				"Use --skip-duplicates or --overwrite to resolve conflicts without asking")
+		}
 		
-		// Check for scanner errors
-		if err := scanner.Err(); err != nil {
-			fmt.Printf("Error reading file %s: %s\n", file, err)
+		var resolved map[string]string
+		var err error
+		if encryption.UseTUI {
+			resolved, err = tui.ResolveConflicts(conflicts, redact, showValues)
+		} else {
+			resolved = resolveConflictsPlain(conflicts)
+		}
+		if err != nil {
+			fmt.Println("Merge canceled.")
 			os.Exit(1)
 		}
+		
+		for key, value := range resolved {
+			variables[key] = value
+		}
+		
+		// Note which side won each conflict
+		for _, conflict := range conflicts {
+			if resolved[conflict.Key] == conflict.RemoteValue {
+				resolutions[conflict.Key] = [2]string{conflict.RemoteLabel, conflict.LocalLabel}
+				sources[conflict.Key] = conflict.RemoteLabel
+			} else {
+				resolutions[conflict.Key] = [2]string{conflict.LocalLabel, conflict.RemoteLabel}
+			}
+		}
 	}
 
 	// Create output file
-	outFile, err := os.Create(mergeOutput)
-	if err != nil {
-		fmt.Printf("Error creating output file: %s\n", err)
-		os.Exit(1)
+	out := resultStdout
+	if !toStdout {
+		outFile, err := os.Create(mergeOutput)
+		if err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not create output file: %s", err))
+		}
+		defer outFile.Close()
+		out = outFile
 	}
-	defer outFile.Close()
 
 	// Write merged content
-	writer := bufio.NewWriter(outFile)
+	writer := bufio.NewWriter(out)
 	
 	// Add a header comment
-	fmt.Fprintf(writer, "# .env file created by envi merge\n")
-	fmt.Fprintf(writer, "# Created on %s\n", time.Now().Format("2006-01-02 15:04:05"))
-	
-	if mergeGistID != "" {
-		fmt.Fprintf(writer, "# Merged local .env with remote Gist: %s\n", mergeGistID)
-	} else {
-		fmt.Fprintf(writer, "# Merged from %d files: %s\n", len(filesToProcess), strings.Join(filesToProcess, ", "))
+	if !mergeNoHeader {
+		fmt.Fprintf(writer, "# .env file created by envi merge\n")
+		fmt.Fprintf(writer, "# Created on %s\n", time.Now().Format("2006-01-02 15:04:05"))
+		
+		if len(mergeGistIDs) == 1 {
+			fmt.Fprintf(writer, "# Merged local .env with remote Gist: %s\n", mergeGistIDs[0])
+		} else if len(mergeGistIDs) > 1 {
+			fmt.Fprintf(writer, "# Merged local .env with remote Gists: %s\n", strings.Join(mergeGistIDs, ", "))
+		} else {
+			fmt.Fprintf(writer, "# Merged from %d files: %s\n", len(filesToProcess), strings.Join(filesToProcess, ", "))
+		}
+		fmt.Fprintln(writer, "")
 	}
-	fmt.Fprintln(writer, "")
 	
-	// Write comments if keeping them
-	if mergeKeepComments && len(comments) > 0 {
-		fmt.Fprintf(writer, "# Merged comments from source files:\n")
-		for _, comment := range comments {
+	// Write the comments from the top of the source files
+	if len(fileComments) > 0 {
+		for _, comment := range fileComments {
 			fmt.Fprintln(writer, comment)
 		}
 		fmt.Fprintln(writer, "")
 	}
 	
+	// Variables from the first source need no annotation
+	primaryLabel := ""
+	if len(mergeSources) > 0 {
+		primaryLabel = mergeSources[0].label()
+	}
+	annotate := func(key string) {
+		for _, comment := range keyComments[key] {
+			fmt.Fprintln(writer, comment)
+		}
+		if !mergeAnnotate {
+			return
+		}
+		if resolution, ok := resolutions[key]; ok {
+			fmt.Fprintf(writer, "# conflict: kept %s over %s\n", annotationLabel(resolution[0]), annotationLabel(resolution[1]))
+		} else if sources[key] != primaryLabel {
+			fmt.Fprintf(writer, "# from %s\n", annotationLabel(sources[key]))
+		}
+	}
+	
 	// Write variables
 	if mergeSort {
 		// Sort variables alphabetically
 		sortedKeys := sortKeys(variables)
 		for _, key := range sortedKeys {
-			fmt.Fprintf(writer, "%s=%s\n", key, variables[key])
+			annotate(key)
+			fmt.Fprintln(writer, withInlineComment(formatEnvLine(prefixes[key], key, variables[key]), valueComments[key][variables[key]]))
 		}
 	} else {
 		// Use original order
 		for _, key := range variableOrder {
-			fmt.Fprintf(writer, "%s=%s\n", key, variables[key])
+			annotate(key)
+			fmt.Fprintln(writer, withInlineComment(formatEnvLine(prefixes[key], key, variables[key]), valueComments[key][variables[key]]))
 		}
 	}
 	
-	writer.Flush()
+	// Write the comments from the end of the source files
+	if len(trailingComments) > 0 {
+		fmt.Fprintln(writer, "")
+		for _, comment := range trailingComments {
+			fmt.Fprintln(writer, comment)
+		}
+	}
 	
-	fmt.Printf("Successfully merged .env files into %s\n", mergeOutput)
+	if err := writer.Flush(); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not write output file: %s", err))
+	}
+	
+	if !toStdout {
+		fmt.Printf("Successfully merged .env files into %s\n", mergeOutput)
+	}
 	fmt.Printf("Merged %d variables\n", len(variables))
+	
+	// Say how many of the merged values each source provided
+	if len(mergeSources) > 1 {
+		contributions := make(map[string]int)
+		for key := range variables {
+			contributions[sources[key]]++
+		}
+		for _, source := range mergeSources {
+			fmt.Printf("  %s: %d\n", source.label(), contributions[source.label()])
+		}
+	}
+	
+	// The output now holds everything from the remote Gist, so it is the new synced state
+	if syncedContent != nil && !toStdout {
+		saveSnapshot(mergeGistIDs[0], syncedContent)
+	}
+	
+	// The backup may hold plaintext secrets, so overwrite it rather than just unlinking it
+	if mergeWipeBackup && backupFile != "" {
+		if err := secureWipeFile(backupFile); err != nil {
+			logWarn("Could not securely delete backup file %s: %s", backupFile, err)
+		} else {
+			logInfo("Securely deleted backup file %s", backupFile)
+		}
+	}
+}
+
+// fetchMergeGist fetches the .env content of a Gist to merge, decrypting it with --unmask.
+// Fully encrypted content can't be merged without --unmask; masked values are merged
+// as they are, with a warning.
+func fetchMergeGist(cmd *cobra.Command, token, gistID string) []byte {
+	logInfo("Fetching Gist with ID: %s", gistID)
+	
+	// Get the .env file of the Gist; it is decrypted below, asking for the key only if needed
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	var remoteContent []byte
+	err := withSpinner("Fetching Gist...", func() error {
+		var err error
+		remoteContent, err = envi.Pull(ctx, envi.PullOptions{Token: token, BaseURL: githubURL, GistID: gistID})
+		return err
+	})
+	switch {
+	case errors.Is(err, envi.ErrFileNotFound):
+		exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Gist %s: no .env file found in this Gist", gistID))
+	case errors.Is(err, envi.ErrFileTruncated):
+		exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Gist %s: the .env file is larger than the %s the GitHub API returns, so it can't be read intact", gistID, formatByteSize(gistAPIFileLimit)))
+	case err != nil:
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not retrieve Gist with ID %s: %s", gistID, apiError(err)))
+	}
+	
+	// Check if content is encrypted and needs decryption
+	isEncrypted := encryption.IsEncrypted(remoteContent)
+	isMasked := encryption.IsMasked(remoteContent)
+	
+	if (isEncrypted || isMasked) && mergeUnmask {
+		logInfo("Detected encrypted content in Gist %s. Attempting to decrypt...", gistID)
+		
+		remoteContent, err = decryptEnvContent(remoteContent)
+		if err != nil {
+			exitOnKeyFileError(err)
+			exitWithError(ErrCodeDecryptFailed, fmt.Sprintf("Could not decrypt Gist %s. Please check your encryption settings and try again.", gistID))
+		}
+		
+		logInfo("Successfully decrypted remote content!")
+	} else if isEncrypted {
+		exitWithError(ErrCodeDecryptFailed, fmt.Sprintf("Gist %s is fully encrypted, so there are no variables to merge", gistID),
+			"Add --unmask to decrypt it")
+	} else if isMasked {
+		logWarn("Gist %s has masked values but --unmask flag not specified.", gistID)
+		logWarn("Merging masked values - this may not be what you want.")
+	}
+	
+	return remoteContent
+}
+
+// loadMergeBase returns the last synced state of a Gist for --three-way
+func loadMergeBase(gistID string, remoteContent []byte) *envSnapshot {
+	if encryption.IsEncrypted(remoteContent) || encryption.IsMasked(remoteContent) {
+		exitWithError(ErrCodeDecryptFailed, "--three-way needs the remote values", "Add --unmask to decrypt them")
+	}
+	base, err := loadSnapshot(gistID)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not read the last synced state: %s", err))
+	}
+	if base == nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("No synced state found for Gist %s", gistID),
+			"Pull it first with 'envi pull --unmask', or merge without --three-way")
+	}
+	logInfo("Comparing with the state of Gist %s at the last sync", gistID)
+	return base
+}
+
+// appendNewComments adds the comment lines that comments doesn't already have, ignoring
+// indentation, so a comment several sources share is written once
+func appendNewComments(comments []string, lines []string) []string {
+	for _, line := range lines {
+		duplicate := false
+		for _, comment := range comments {
+			if strings.TrimSpace(comment) == strings.TrimSpace(line) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			comments = append(comments, line)
+		}
+	}
+	return comments
+}
+
+// withInlineComment appends an inline comment to a line, if there is one
+func withInlineComment(line, comment string) string {
+	if comment == "" {
+		return line
+	}
+	return line + " " + comment
+}
+
+// mergeSource is a set of .env content being merged, read from a local file or a Gist
+type mergeSource struct {
+	name    string
+	content []byte
+	remote  bool
+	gistID  string
+}
+
+// label returns a readable name for the source
+func (s mergeSource) label() string {
+	if s.remote {
+		return fmt.Sprintf("Remote (Gist %s)", s.gistID)
+	}
+	return fmt.Sprintf("Local (%s)", s.name)
+}
+
+// annotationLabel formats a source label for an --annotate comment, e.g. "remote (Gist abc123)"
+func annotationLabel(label string) string {
+	if label == "" {
+		return label
+	}
+	return strings.ToLower(label[:1]) + label[1:]
+}
+
+// resolveConflictsPlain asks on the terminal which value to keep for each conflict
+func resolveConflictsPlain(conflicts []tui.Conflict) map[string]string {
+	resolved := make(map[string]string)
+	applyAll := ""
+	
+	for _, conflict := range conflicts {
+		choice := applyAll
+		if choice == "" {
+			fmt.Printf("\nConflict for variable: %s\n", conflict.Key)
+			fmt.Printf("  [l] %s: %s\n", conflict.LocalLabel, displayValue(conflict.LocalValue))
+			fmt.Printf("  [r] %s: %s\n", conflict.RemoteLabel, displayValue(conflict.RemoteValue))
+			fmt.Print("Keep which value? (l/r, L/R to apply to all remaining) [l]: ")
+			
+			var response string
+			fmt.Scanln(&response)
+			
+			switch response {
+			case "L", "R":
+				applyAll = strings.ToLower(response)
+				choice = applyAll
+			default:
+				choice = strings.ToLower(response)
+			}
+		}
+		
+		if choice == "r" {
+			resolved[conflict.Key] = conflict.RemoteValue
+		} else {
+			resolved[conflict.Key] = conflict.LocalValue
+		}
+	}
+	
+	return resolved
 }
 
 // copyFile copies a file from src to dst
@@ -309,15 +654,6 @@ func sortKeys(m map[string]string) []string {
 	for k := range m {
 		keys = append(keys, k)
 	}
-	
-	// Simple bubble sort for alphabetical ordering
-	for i := 0; i < len(keys)-1; i++ {
-		for j := i + 1; j < len(keys); j++ {
-			if keys[i] > keys[j] {
-				keys[i], keys[j] = keys[j], keys[i]
-			}
-		}
-	}
-	
+	sort.Strings(keys)
 	return keys
 } 
\ No newline at end of file
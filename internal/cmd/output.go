@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/google/go-github/v37/github"
+
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// This file contains the machine-readable output mode enabled by --json
+
+// Error codes reported in JSON mode so scripts can branch on the cause of a failure
+const (
+	ErrCodeNoToken       = "NO_TOKEN"
+	ErrCodeGistNotFound  = "GIST_NOT_FOUND"
+	ErrCodeDecryptFailed = "DECRYPT_FAILED"
+	ErrCodeBadKeyFile    = "BAD_KEY_FILE"
+	ErrCodeNoEnvFile     = "NO_ENV_FILE"
+	ErrCodeAPI           = "API_ERROR"
+	ErrCodeTimeout       = "TIMEOUT"
+	ErrCodeGeneric       = "ERROR"
+)
+
+var (
+	// jsonOutput makes commands print a single JSON result instead of prose
+	jsonOutput bool
+
+	// resultStdout is where machine-readable results go. When results are written to
+	// stdout, human-readable output is sent to stderr instead.
+	resultStdout = os.Stdout
+)
+
+// enableJSONOutput routes human-readable output to stderr so stdout only carries JSON.
+// Interactive prompts are switched to plain terminal input.
+func enableJSONOutput() {
+	if !jsonOutput {
+		return
+	}
+	routeInfoToStderr()
+	encryption.UseTUI = false
+}
+
+// routeInfoToStderr sends everything printed to os.Stdout to stderr, keeping the real
+// stdout in resultStdout for data that scripts consume
+func routeInfoToStderr() {
+	if os.Stdout == os.Stderr {
+		return
+	}
+	resultStdout = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// printJSON writes a value to the JSON output stream
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(resultStdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %s\n", err)
+	}
+}
+
+// printJSONResult writes a successful result in JSON mode. Fields are merged into {"ok": true}.
+func printJSONResult(fields map[string]interface{}) {
+	if !jsonOutput {
+		return
+	}
+	result := map[string]interface{}{"ok": true}
+	for k, v := range fields {
+		result[k] = v
+	}
+	printJSON(result)
+}
+
+// exitWithError prints an error message and any hints to stderr, then exits. In JSON
+// mode {"ok":false,"error":"...","code":"..."} is also printed to stdout.
+func exitWithError(code, message string, hints ...string) {
+	fmt.Fprintf(os.Stderr, "Error: %s\n", message)
+	for _, hint := range hints {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+
+	if jsonOutput {
+		printJSON(map[string]interface{}{
+			"ok":    false,
+			"error": message,
+			"code":  code,
+		})
+	}
+	os.Exit(1)
+}
+
+// gistErrorCode returns the error code for a failed Gist API call
+func gistErrorCode(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrCodeTimeout
+	}
+	if errResp, ok := err.(*github.ErrorResponse); ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+		return ErrCodeGistNotFound
+	}
+	return ErrCodeAPI
+}
+
+// nonNil returns an empty slice instead of nil so it encodes as [] rather than null
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -1,26 +1,36 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/google/go-github/v37/github"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2"
 
 	"github.com/dexterity-inc/envi/internal/config"
 	"github.com/dexterity-inc/envi/internal/encryption"
-	"github.com/dexterity-inc/envi/internal/tui"
+	"github.com/dexterity-inc/envi/internal/logging"
+	"github.com/dexterity-inc/envi/pkg/envi"
 )
 
 // Pull command flags
 var (
-	pullGistID      string
-	pullOutput      string
-	pullUnmask      bool
-	pullForce       bool
+	pullGistID          string
+	pullOutput          string
+	pullUnmask          bool
+	pullForce           bool
+	pullExportStyle     bool
+	pullSearchUp        bool
+	pullAll             bool
+	pullStdout          bool
+	pullFormat          string
+	pullIncludeComments bool
+	pullOnly            []string
+	pullNoSaveID        bool
+	pullSort            string
 )
 
 // pullCmd is the pull command
@@ -34,15 +44,27 @@ var pullCmd = &cobra.Command{
 // InitPullCommand sets up the pull command and its subcommands
 func InitPullCommand() {
 	// Initialize the command flags
-	pullCmd.Flags().StringVarP(&pullGistID, "id", "i", "", "GitHub Gist ID to pull from")
-	pullCmd.Flags().StringVarP(&pullOutput, "output", "o", ".env", "Output file path")
+	pullCmd.Flags().StringVarP(&pullGistID, "id", "i", "", "GitHub Gist ID, URL or @BOOKMARK to pull from")
+	pullCmd.Flags().StringVarP(&pullOutput, "output", "o", ".env", "Output file path (- for stdout, like --stdout)")
+	pullCmd.Flags().StringVar(&pullOutput, "file", ".env", "Path to the local .env file (alias for --output)")
+	pullCmd.Flags().BoolVarP(&pullAll, "all", "a", false, "Pull every env file in the Gist (.env, .env.* or *.env), writing each to its original name")
+	pullCmd.Flags().BoolVar(&pullSearchUp, "search-up", false, "Search parent directories for the nearest existing .env file to update")
 	pullCmd.Flags().BoolVarP(&pullUnmask, "unmask", "u", false, "Decrypt/unmask values when pulling")
 	pullCmd.Flags().BoolVarP(&pullForce, "force", "f", false, "Overwrite existing file without confirmation")
+	pullCmd.Flags().BoolVar(&pullStdout, "stdout", false, "Write the content to stdout instead of a file; messages go to stderr")
+	pullCmd.Flags().StringVar(&pullFormat, "format", "dotenv", "Output format: dotenv (as stored), env (values quoted where needed), or for --stdout shell (quoted export statements for eval) or value (values only, one per line)")
+	pullCmd.Flags().BoolVar(&pullIncludeComments, "include-comments", false, "With --format shell, keep comment lines and inline comments as shell comments")
+	pullCmd.Flags().StringSliceVar(&pullOnly, "only", []string{}, "Only write these variables; glob patterns such as DB_* are allowed (comma-separated)")
+	pullCmd.Flags().BoolVar(&pullNoSaveID, "no-save-id", false, "Don't save this Gist as the default")
+	pullCmd.Flags().StringVar(&pullSort, "sort", sortNone, "Order of the variables: none (as in the Gist), alpha, or prefix (alphabetical, grouped by prefix such as DB_)")
+	pullCmd.Flags().BoolVar(&pullExportStyle, "export-style", false, "Prefix each variable with 'export ' so the file can be sourced")
 	
 	// Add encryption flags for decryption
 	pullCmd.Flags().BoolVar(&encryption.UseKeyFile, "use-key-file", false, "Use key file instead of password")
-	pullCmd.Flags().StringVarP(&encryption.EncryptionKeyFile, "key-file", "k", ".envi.key", "Path to encryption key file")
+	pullCmd.Flags().StringVarP(&encryption.EncryptionKeyFile, "key-file", "k", ".envi.key", "Path to encryption key file; a bare file name is looked up in the data directory")
 	pullCmd.Flags().StringVarP(&encryption.EncryptionPassword, "password", "p", "", "Encryption password (not recommended)")
+	pullCmd.Flags().BoolVar(&encryption.PasswordFromStdin, "password-stdin", false, "Read the encryption password from the first line of stdin")
+	pullCmd.Flags().BoolVar(&encryption.KeyFromStdin, "key-stdin", false, "Read the base64-encoded encryption key from the first line of stdin, instead of a password or key file")
 
 	// Add the pull command to the root command
 	rootCmd.AddCommand(pullCmd)
@@ -50,109 +72,212 @@ func InitPullCommand() {
 
 // runPullCommand handles the pull command execution
 func runPullCommand(cmd *cobra.Command, args []string) {
+	if encryption.PasswordFromStdin && cmd.Flags().Changed("password") {
+		exitWithError(ErrCodeGeneric, "--password and --password-stdin can't be used together")
+	}
+	checkStdinSecretFlags()
+	if cmd.Flags().Changed("file") && cmd.Flags().Changed("output") {
+		exitWithError(ErrCodeGeneric, "--file and --output can't be used together", "--file is an alias for --output")
+	}
+	
+	// Keep stdout clean for the env content, e.g. for eval "$(envi pull --stdout --format shell)"
+	if pullOutput == "-" {
+		pullStdout = true
+	}
+	switch pullFormat {
+	case "dotenv", "env":
+	case "shell", "value":
+		if !pullStdout {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("--format %s can only be used with --stdout", pullFormat))
+		}
+	default:
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Unknown format %q (use dotenv, env, shell or value)", pullFormat))
+	}
+	if pullIncludeComments && pullFormat != "shell" {
+		exitWithError(ErrCodeGeneric, "--include-comments can only be used with --format shell; the dotenv and env formats always keep comments")
+	}
+	if pullStdout {
+		routeInfoToStderr()
+	}
+	
+	if pullSort != sortNone && pullSort != sortAlpha && pullSort != sortPrefix {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Unknown sort order %q (use none, alpha or prefix)", pullSort))
+	}
+	
+	// Check the --only patterns before fetching anything
+	if len(pullOnly) > 0 && pullAll {
+		exitWithError(ErrCodeGeneric, "--only can't be used with --all")
+	}
+	// --all writes each file to its own name, so there is no single output to choose
+	if pullAll && (cmd.Flags().Changed("output") || cmd.Flags().Changed("file") || pullStdout) {
+		exitWithError(ErrCodeGeneric, "--all can't be used with --output, --file or --stdout",
+			"--all writes each env file in the Gist to its own name in the current directory")
+	}
+	for _, pattern := range pullOnly {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid --only pattern %q", pattern))
+		}
+	}
+	
 	// Get GitHub token
 	token, err := config.GetGitHubToken()
 	if err != nil {
-		fmt.Println("Error:", err)
-		os.Exit(1)
+		exitWithError(ErrCodeNoToken, err.Error())
+	}
+	
+	// Resolve the output path
+	if !pullStdout {
+		pullOutput = resolveEnvPath(pullOutput, pullSearchUp)
 	}
 	
 	// Load config
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Warning: Could not load config: %s\n", err)
+		logWarn("Could not load config: %s", err)
 	} else {
 		// Apply config defaults
 		if !cmd.Flags().Changed("unmask") && cfg != nil && cfg.UnmaskByDefault {
 			pullUnmask = true
-			fmt.Println("Using default setting: Automatically unmasking values")
+			logInfo("Using default setting: Automatically unmasking values")
 		}
 		
 		if !cmd.Flags().Changed("use-key-file") && cfg.UseKeyFileByDefault {
 			encryption.UseKeyFile = true
-			fmt.Println("Using default setting: Using key file for decryption")
+			logInfo("Using default setting: Using key file for decryption")
 		}
 		
 		if !cmd.Flags().Changed("key-file") && cfg.DefaultKeyFile != "" {
 			encryption.EncryptionKeyFile = cfg.DefaultKeyFile
-			fmt.Printf("Using default key file: %s\n", encryption.EncryptionKeyFile)
+			logInfo("Using default key file: %s", encryption.EncryptionKeyFile)
 		}
 	}
 	
-	// Get Gist ID (from flag or config)
+	// Get Gist ID (from flag, bookmark or config)
+	pullGistID = resolveGistRef(pullGistID)
 	if pullGistID == "" && cfg != nil && cfg.LastGistID != "" {
-		if encryption.UseTUI {
+		if !promptAnswered() && (jsonOutput || encryption.ReadsStdin() || !stdinIsTerminal()) {
+			// Scripts can't answer prompts, so use the saved Gist
+			pullGistID = cfg.LastGistID
+		} else {
 			// Ask user if they want to use the last Gist ID
-			useLastID, err := tui.Confirm(
+			useLastID, err := confirmPrompt(
 				"Use saved Gist?",
 				fmt.Sprintf("Would you like to pull from your last used Gist (%s)?", cfg.LastGistID),
 			)
 			if err != nil {
-				fmt.Printf("Error getting confirmation: %s\n", err)
-				os.Exit(1)
+				exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not get confirmation: %s", err))
 			}
 			
 			if useLastID {
 				pullGistID = cfg.LastGistID
-				fmt.Printf("Using saved Gist ID: %s\n", pullGistID)
-			}
-		} else {
-			// Ask in plain terminal mode
-			fmt.Printf("Found saved Gist ID: %s\n", cfg.LastGistID)
-			fmt.Println("Would you like to pull from this Gist? (y/N)")
-			
-			var response string
-			fmt.Scanln(&response)
-			
-			if strings.ToLower(response) == "y" {
-				pullGistID = cfg.LastGistID
-				fmt.Printf("Using saved Gist ID: %s\n", pullGistID)
+				logInfo("Using saved Gist ID: %s", pullGistID)
 			}
 		}
 	}
 	
 	// Check if Gist ID is provided
 	if pullGistID == "" {
-		fmt.Println("Error: No Gist ID specified and no saved Gist ID found")
-		fmt.Println("Use 'envi pull --id GIST_ID' or first push an .env file with 'envi push'")
-		os.Exit(1)
+		exitWithError(ErrCodeGeneric, "No Gist ID specified and no saved Gist ID found",
+			"Use 'envi pull --id GIST_ID' or first push an .env file with 'envi push'")
 	}
 	
-	// Create GitHub client
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(cmd.Context(), ts)
-	client := github.NewClient(tc)
-	
-	// Get Gist
-	gist, _, err := client.Gists.Get(cmd.Context(), pullGistID)
+	// Get the files of the Gist. They are decrypted below, after asking for the key
+	// only if needed.
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	var files []envi.File
+	err = withSpinner("Fetching Gist...", func() error {
+		var err error
+		files, err = envi.PullFiles(ctx, envi.PullOptions{Token: token, BaseURL: githubURL, GistID: pullGistID})
+		return err
+	})
 	if err != nil {
-		fmt.Printf("Error retrieving Gist with ID %s: %s\n", pullGistID, err)
-		os.Exit(1)
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not retrieve Gist with ID %s: %s", pullGistID, apiError(err)))
 	}
 	
-	// Find .env file in Gist
-	var envFile *github.GistFile
-	for filename, file := range gist.Files {
-		if string(filename) == ".env" {
-			envFile = &file
-			break
+	var pulledFiles []string
+	if pullAll {
+		// Pull every env file in the Gist to its original name
+		for _, file := range files {
+			if !isEnvFileName(file.Name) {
+				logInfo("Skipping %s: not an env file", file.Name)
+				continue
+			}
+			
+			if file.Truncated {
+				logWarn("Skipping %s: it is larger than the %s the GitHub API returns", file.Name, formatByteSize(gistAPIFileLimit))
+				continue
+			}
+			logInfo("Pulling %s (%s)...", file.Name, encryptionState(file.Content))
+			if writePulledFile(file.Content, filepath.Base(file.Name)) {
+				pulledFiles = append(pulledFiles, filepath.Base(file.Name))
+			}
+		}
+		fmt.Printf("Pulled %d files from Gist %s\n", len(pulledFiles), pullGistID)
+	} else {
+		// Find .env file in Gist
+		envContent, err := pulledEnvContent(files)
+		if err != nil {
+			exitWithError(ErrCodeNoEnvFile, err.Error())
+		}
+		
+		if !writePulledFile(envContent, pullOutput) {
+			fmt.Println("Operation canceled.")
+			printJSONResult(map[string]interface{}{"gist_id": pullGistID, "files": []string{}, "canceled": true})
+			os.Exit(0)
+		}
+		pulledFiles = append(pulledFiles, pullOutput)
+		
+		// Don't let other env files in the Gist go unnoticed
+		var others []string
+		for _, file := range files {
+			if isEnvFileName(file.Name) && file.Name != ".env" {
+				others = append(others, fmt.Sprintf("%s (%s)", file.Name, encryptionState(file.Content)))
+			}
+		}
+		if len(others) > 0 {
+			logInfo("Note: This Gist also contains %s. Use --all to pull every file.", strings.Join(others, ", "))
 		}
 	}
 	
-	if envFile == nil {
-		fmt.Println("Error: No .env file found in this Gist")
-		os.Exit(1)
+	// Save Gist ID in config if it's not already saved
+	if cfg != nil && cfg.LastGistID != pullGistID && !noSaveGistID(cmd, cfg, pullNoSaveID) {
+		cfg.LastGistID = pullGistID
+		if err := config.SaveConfig(cfg); err != nil {
+			logWarn("Could not save Gist ID to config: %s", err)
+		} else {
+			logInfo("Saved Gist ID for future use")
+		}
 	}
 	
-	// Get content
-	envContent := []byte(*envFile.Content)
-	
+	printJSONResult(map[string]interface{}{"gist_id": pullGistID, "files": pulledFiles})
+}
+
+// pulledEnvContent returns the content of the .env file among the files of a Gist
+func pulledEnvContent(files []envi.File) ([]byte, error) {
+	for _, file := range files {
+		if file.Name != ".env" {
+			continue
+		}
+		if file.Truncated {
+			return nil, fmt.Errorf("the .env file in this Gist is %s, and the GitHub API only returns the first %s of a file, so it can't be read intact",
+				formatByteSize(int64(file.Size)), formatByteSize(gistAPIFileLimit))
+		}
+		logging.Debug("Detected encryption mode", "gist_id", pullGistID, "file", ".env", "mode", contentMode(file.Content))
+		return file.Content, nil
+	}
+	return nil, errors.New("no .env file found in this Gist")
+}
+
+// writePulledFile decrypts content if requested and writes it to outputPath,
+// asking before overwriting. It returns false if the user declined to overwrite.
+func writePulledFile(envContent []byte, outputPath string) bool {
 	// Check if content is encrypted and needs decryption
 	isEncrypted := encryption.IsEncrypted(envContent)
 	isMasked := encryption.IsMasked(envContent)
 	
 	if (isEncrypted || isMasked) && pullUnmask {
-		fmt.Println("Detected encrypted content. Attempting to decrypt...")
+		logInfo("Detected encrypted content. Attempting to decrypt...")
 		
 		var decryptedContent []byte
 		var err error
@@ -164,59 +289,205 @@ func runPullCommand(cmd *cobra.Command, args []string) {
 		}
 		
 		if err != nil {
-			fmt.Println("Error decrypting content. Please check the encryption key or password and try again.")
-			os.Exit(1)
+			exitOnKeyFileError(err)
+			exitWithError(ErrCodeDecryptFailed, "Could not decrypt content. Please check the encryption key or password and try again.")
 		}
 		
 		envContent = decryptedContent
-		fmt.Println("Successfully decrypted content!")
+		logInfo("Successfully decrypted content!")
 	} else if (isEncrypted || isMasked) && !pullUnmask {
-		fmt.Println("Note: Content is encrypted/masked but --unmask flag was not specified.")
-		fmt.Println("The file will be saved in its encrypted form.")
-		fmt.Println("To decrypt, run 'envi pull --id " + pullGistID + " --unmask'")
+		logInfo("Note: Content is encrypted/masked but --unmask flag was not specified.")
+		logInfo("The file will be saved in its encrypted form.")
+		logInfo("To decrypt, run 'envi pull --id %s --unmask'", pullGistID)
+	}
+	
+	// The snapshot records the content as the Gist holds it, before the changes below
+	synced := envContent
+	
+	// Catch a Gist whose .env holds something else, such as JSON or YAML, before it is
+	// written. Content left encrypted can't be checked.
+	if !encryption.IsEncrypted(envContent) {
+		if problem := envFormatProblem(envContent); problem != "" {
+			confirmNonEnvContent(outputPath, problem)
+		}
+	}
+	
+	// Keep only the requested variables. Masked lines keep their names, so they can be
+	// filtered without decrypting them.
+	if len(pullOnly) > 0 {
+		if encryption.IsEncrypted(envContent) {
+			exitWithError(ErrCodeDecryptFailed, "The content is fully encrypted, so --only needs --unmask to find the variables")
+		}
+		var matched int
+		envContent, matched = filterEnvContent(envContent, pullOnly)
+		if matched == 0 {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("No variables match %s", strings.Join(pullOnly, ", ")))
+		}
+		logInfo("Keeping %d variable(s) matching %s", matched, strings.Join(pullOnly, ", "))
+	}
+	
+	// Reorder the variables; fully encrypted content has no lines to reorder
+	if pullSort != sortNone {
+		if encryption.IsEncrypted(envContent) {
+			logWarn("The content is fully encrypted, so it can't be sorted without --unmask")
+		} else {
+			envContent = sortEnvContent(envContent, pullSort)
+		}
+	}
+	
+	// Quote values that need it; fully encrypted content has no values to quote
+	if pullFormat == "env" {
+		if encryption.IsEncrypted(envContent) {
+			logWarn("The content is fully encrypted, so its values can't be quoted without --unmask")
+		} else {
+			envContent = formatEnvContent(envContent)
+		}
+	}
+	
+	// Re-emit variables with the export prefix if requested
+	if pullExportStyle {
+		envContent = applyExportStyle(envContent)
+	}
+	
+	// Print instead of writing a file
+	if pullStdout {
+		switch pullFormat {
+		case "shell":
+			envContent = formatShellExports(envContent, pullIncludeComments)
+		case "value":
+			envContent = formatValues(envContent)
+		}
+		resultStdout.Write(envContent)
+		if len(envContent) > 0 && envContent[len(envContent)-1] != '\n' {
+			resultStdout.Write([]byte("\n"))
+		}
+		return true
 	}
 	
 	// Check if output file already exists
-	if _, err := os.Stat(pullOutput); err == nil && !pullForce {
+	if _, err := os.Stat(outputPath); err == nil && !pullForce {
 		var overwrite bool
 		
-		if encryption.UseTUI {
-			overwrite, err = tui.Confirm(
-				"Overwrite file?",
-				fmt.Sprintf("The file %s already exists. Overwrite?", pullOutput),
-			)
-			if err != nil {
-				fmt.Printf("Error getting confirmation: %s\n", err)
-				os.Exit(1)
-			}
-		} else {
-			fmt.Printf("The file %s already exists. Overwrite? (y/N)", pullOutput)
-			var response string
-			fmt.Scanln(&response)
-			overwrite = strings.ToLower(response) == "y"
+		// stdin held the password or isn't a terminal, so there is nobody to ask
+		if !promptAnswered() && (encryption.ReadsStdin() || !stdinIsTerminal()) {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("The file %s already exists", outputPath), "Use --force to overwrite it")
+		}
+		
+		overwrite, err = confirmPrompt(
+			"Overwrite file?",
+			fmt.Sprintf("The file %s already exists. Overwrite?", outputPath),
+		)
+		if err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not get confirmation: %s", err))
 		}
 		
 		if !overwrite {
-			fmt.Println("Operation canceled.")
-			os.Exit(0)
+			return false
 		}
 	}
 	
 	// Write content to file
-	if err := ioutil.WriteFile(pullOutput, envContent, 0600); err != nil {
-		fmt.Printf("Error writing to %s: %s\n", pullOutput, err)
+	if err := ioutil.WriteFile(outputPath, envContent, 0600); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not write to %s: %s", outputPath, err))
+	}
+	
+	fmt.Printf("Successfully pulled .env file to %s\n", outputPath)
+	
+	// Remember what was pulled, for 'envi merge --three-way'. A partial pull isn't a sync.
+	if !pullAll && len(pullOnly) == 0 {
+		saveSnapshot(pullGistID, synced)
+	}
+	return true
+}
+
+// confirmNonEnvContent warns that pulled content doesn't look like .env data and asks
+// before it is written. Printing to stdout or --force only warn; when nobody can be
+// asked, the pull stops.
+func confirmNonEnvContent(outputPath string, problem string) {
+	logWarn("The pulled content doesn't look like a .env file: %s. Check that --id points to the right Gist.", problem)
+	if pullStdout || pullForce {
+		return
+	}
+	if !promptAnswered() && (jsonOutput || encryption.ReadsStdin() || !stdinIsTerminal()) {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Not writing %s, since the content doesn't look like a .env file", outputPath),
+			"Use --force to write it anyway")
+	}
+	
+	write, err := confirmPrompt("Write anyway?", fmt.Sprintf("Write it to %s anyway?", outputPath))
+	if err != nil || !write {
+		fmt.Println("Pull canceled.")
 		os.Exit(1)
 	}
+}
+
+// formatShellExports turns .env content into `export KEY='value'` lines that are safe
+// to eval in a POSIX shell. Dotenv quoting is undone first, see envi.Unquote. With
+// includeComments, comment lines, inline comments and the blank lines between groups
+// of variables are kept where they were.
+func formatShellExports(content []byte, includeComments bool) []byte {
+	entries, _ := parseEnvEntries(content)
+	exports := make(map[int]string, len(entries))
+	for _, entry := range entries {
+		value := envi.Unquote(entry.Value)
+		export := fmt.Sprintf("export %s='%s'", entry.Key, strings.ReplaceAll(value, "'", `'\''`))
+		if includeComments {
+			export = withInlineComment(export, entry.Comment)
+		}
+		exports[entry.Line] = export
+	}
 	
-	fmt.Printf("Successfully pulled .env file to %s\n", pullOutput)
+	var out []string
+	lines, _ := encryption.SplitLines(content)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if export, ok := exports[i+1]; ok {
+			out = append(out, export)
+		} else if includeComments && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+			out = append(out, trimmed)
+		}
+	}
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// formatValues returns only the values of the variables in content, unquoted, one per
+// line, e.g. for DATABASE_URL=$(envi pull --only DATABASE_URL --stdout --format value)
+func formatValues(content []byte) []byte {
+	entries, _ := parseEnvEntries(content)
 	
-	// Save Gist ID in config if it's not already saved
-	if cfg != nil && cfg.LastGistID != pullGistID {
-		cfg.LastGistID = pullGistID
-		if err := config.SaveConfig(cfg); err != nil {
-			fmt.Printf("Warning: Could not save Gist ID to config: %s\n", err)
-		} else {
-			fmt.Println("Saved Gist ID for future use")
+	var b strings.Builder
+	for _, entry := range entries {
+		b.WriteString(envi.Unquote(entry.Value) + "\n")
+	}
+	return []byte(b.String())
+}
+
+// filterEnvContent keeps the variable lines whose key matches one of the glob patterns,
+// dropping everything else including comments. It returns the number of lines kept.
+func filterEnvContent(content []byte, patterns []string) ([]byte, int) {
+	lines, newline := encryption.SplitLines(content)
+	var kept []string
+	for _, line := range lines {
+		stripped, _ := envi.SplitPrefix(line)
+		eq := strings.Index(stripped, "=")
+		if strings.HasPrefix(strings.TrimSpace(line), "#") || eq < 0 {
+			continue
 		}
+		key := strings.TrimSpace(stripped[:eq])
+		for _, pattern := range patterns {
+			if matched, _ := filepath.Match(pattern, key); matched {
+				kept = append(kept, line)
+				break
+			}
+		}
+	}
+	if len(kept) == 0 {
+		return nil, 0
 	}
-} 
\ No newline at end of file
+	return append(encryption.JoinLines(kept, newline), newline...), len(kept)
+}
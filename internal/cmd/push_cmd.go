@@ -1,16 +1,25 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v37/github"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2"
 
 	"github.com/dexterity-inc/envi/internal/config"
 	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/internal/logging"
 	"github.com/dexterity-inc/envi/internal/tui"
+	"github.com/dexterity-inc/envi/pkg/envi"
 )
 
 // Push command flags
@@ -20,24 +29,79 @@ var (
 	pushPublic        bool
 	pushEnvFile       string
 	pushAutoGenerate  bool
+	pushSearchUp      bool
+	pushFiles         []string
+	pushInteractive   bool
+	pushStrictSecrets bool
+	pushAllowPlaintext bool
+	pushDescriptionTemplate string
+	pushProject       string
+	pushForceNew      bool
+	pushNoReadme      bool
+	pushReadmeFile    string
+	pushVerify        bool
+	pushKeepDescription bool
+	pushMaxSize       string
+	pushNoSaveID      bool
+	pushAllowSecrets  []string
+	pushWatch         bool
+	pushDebounce      time.Duration
+	pushPrune         bool
+	pushKeepRemote    bool
 )
 
 // pushCmd is the push command
 var pushCmd = &cobra.Command{
 	Use:   "push",
 	Short: "Push .env file to GitHub Gist",
-	Long:  `Push your .env file to a new or existing GitHub Gist with optional encryption.`,
+	Long: `Push your .env file to a new or existing GitHub Gist with optional encryption.
+
+The Gist description can be generated with --description-template (or the
+description_template config setting). These placeholders are filled in at push time:
+
+  {project}  Project name (--project, or the name of the .env file's directory)
+  {date}     Current date (YYYY-MM-DD)
+  {user}     Local user name
+  {host}     Host name
+
+When updating a Gist, the pushed file replaces the Gist's copy, and variables
+that only the Gist has are listed as they are removed. Use --prune to be asked
+before removing them, or --keep-remote to keep them instead.
+
+With --watch, push keeps running after the first push and pushes the .env file
+again whenever it changes, until interrupted with Ctrl-C.`,
 	Run:   runPushCommand,
 }
 
 // InitPushCommand sets up the push command and its subcommands
 func InitPushCommand() {
 	// Initialize the command flags
-	pushCmd.Flags().StringVarP(&pushGistID, "id", "i", "", "GitHub Gist ID to update (leave blank for new Gist)")
+	pushCmd.Flags().StringVarP(&pushGistID, "id", "i", "", "GitHub Gist ID or @BOOKMARK to update (leave blank for new Gist)")
 	pushCmd.Flags().StringVarP(&pushDescription, "description", "d", "Environment variables created with envi", "Description for the Gist")
 	pushCmd.Flags().BoolVarP(&pushPublic, "public", "p", false, "Make the Gist public (default private)")
-	pushCmd.Flags().StringVarP(&pushEnvFile, "file", "f", ".env", "Path to the .env file")
+	pushCmd.Flags().StringVarP(&pushEnvFile, "file", "f", ".env", "Path to the .env file (- to read it from stdin)")
 	pushCmd.Flags().BoolVarP(&pushAutoGenerate, "auto", "a", false, "Auto-generate a sample .env file if none exists")
+	pushCmd.Flags().StringSliceVar(&pushFiles, "files", []string{}, "Push several env files or directories of env files to one Gist, each under its own name (comma-separated)")
+	pushCmd.Flags().BoolVar(&pushSearchUp, "search-up", false, "Search parent directories for the nearest .env file")
+	pushCmd.Flags().BoolVar(&pushStrictSecrets, "strict-secrets", false, "Refuse to push likely secrets without encryption")
+	pushCmd.Flags().BoolVar(&pushAllowPlaintext, "allow-plaintext", false, "Push likely secrets without encryption, without asking")
+	pushCmd.Flags().BoolVar(&pushWatch, "watch", false, "Keep running and push the .env file again whenever it changes")
+	pushCmd.Flags().DurationVar(&pushDebounce, "debounce", time.Second, "With --watch, wait until the file has been unchanged this long before pushing")
+	pushCmd.Flags().StringSliceVar(&pushAllowSecrets, "allow-secret", []string{}, "Variables not to warn about when their values look like credentials (comma-separated)")
+	pushCmd.Flags().StringVar(&pushDescriptionTemplate, "description-template", "", "Build the description from a template, e.g. \"Environment variables for {project} ({date})\"")
+	pushCmd.Flags().BoolVar(&pushForceNew, "force-new", false, "Always create a new Gist, ignoring the saved Gist, and save it as the default")
+	pushCmd.Flags().BoolVar(&pushNoReadme, "no-readme", false, "Don't add a README with decryption instructions to the Gist")
+	pushCmd.Flags().StringVar(&pushReadmeFile, "readme-file", "", "Use this file as the Gist's README.md instead of the generated one")
+	pushCmd.Flags().BoolVar(&pushKeepDescription, "keep-description", false, "Never change the description of an existing Gist")
+	pushCmd.Flags().BoolVar(&pushNoSaveID, "no-save-id", false, "Don't save a newly created Gist as the default")
+	pushCmd.Flags().StringVar(&pushMaxSize, "max-size", "1MB", "Refuse to push files larger than this after encryption, e.g. 512KB or 2MB (0 for no limit)")
+	pushCmd.Flags().BoolVar(&pushVerify, "verify", false, "Fetch the Gist again after pushing and check it holds the pushed content")
+	pushCmd.Flags().StringVar(&pushProject, "project", "", "Project name for the {project} placeholder (defaults to the directory name)")
+	pushCmd.Flags().BoolVar(&encryption.PasswordFromStdin, "password-stdin", false, "Read the encryption password from the first line of stdin")
+	pushCmd.Flags().BoolVar(&encryption.KeyFromStdin, "key-stdin", false, "Read the base64-encoded encryption key from the first line of stdin, instead of a password or key file")
+	pushCmd.Flags().BoolVar(&pushPrune, "prune", false, "List the variables that only the Gist has and ask before removing them")
+	pushCmd.Flags().BoolVar(&pushKeepRemote, "keep-remote", false, "Keep variables that only the Gist has, adding them to the pushed file")
+	pushCmd.Flags().BoolVar(&pushInteractive, "interactive", false, "Review, edit and choose which variables to push in a terminal UI")
 	
 	// Add the push command to the root command
 	rootCmd.AddCommand(pushCmd)
@@ -45,176 +109,657 @@ func InitPushCommand() {
 
 // runPushCommand handles the push command execution
 func runPushCommand(cmd *cobra.Command, args []string) {
+	if pushWatch {
+		checkWatchFlags()
+	}
+	gistID := pushEnvFiles(cmd)
+	if pushWatch {
+		watchAndPush(cmd, gistID)
+	}
+}
+
+// pushEnvFiles pushes the env files once and returns the ID of the Gist they are in
+func pushEnvFiles(cmd *cobra.Command) string {
+	if pushNoReadme && pushReadmeFile != "" {
+		exitWithError(ErrCodeGeneric, "--no-readme and --readme-file can't be used together")
+	}
+	if pushKeepDescription && (cmd.Flags().Changed("description") || cmd.Flags().Changed("description-template")) {
+		exitWithError(ErrCodeGeneric, "--keep-description can't be combined with --description or --description-template")
+	}
+	if pushPrune && pushKeepRemote {
+		exitWithError(ErrCodeGeneric, "--prune and --keep-remote can't be used together")
+	}
+	if pushForceNew && cmd.Flags().Changed("id") {
+		exitWithError(ErrCodeGeneric, "--force-new and --id can't be used together",
+			"Use --id to update an existing Gist, or --force-new to create a new one")
+	}
+	checkStdinSecretFlags()
+	if encryption.ReadsStdin() && pushFromStdin() {
+		exitWithError(ErrCodeGeneric, encryption.StdinFlag()+" and --file - can't be used together, since both read stdin",
+			"Pass the password with ENVI_PASSWORD or ENVI_PASSWORD_FILE, or the key with ENVI_KEY, instead")
+	}
+	if encryption.ReadsStdin() && pushInteractive {
+		exitWithError(ErrCodeGeneric, encryption.StdinFlag()+" and --interactive can't be used together, since the editor needs the terminal")
+	}
+	maxSize, err := parseByteSize(pushMaxSize)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid --max-size: %s", err))
+	}
+	
 	// Get GitHub token
 	token, err := config.GetGitHubToken()
 	if err != nil {
-		fmt.Println("Error:", err)
-		os.Exit(1)
+		exitWithError(ErrCodeNoToken, err.Error())
 	}
 	
 	// Load config
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Warning: Could not load config: %s\n", err)
+		logWarn("Could not load config: %s", err)
 	} else {
 		// Apply encryption defaults if not explicitly set
 		applyEncryptionDefaults(cmd, cfg)
 	}
 	
-	// Check if .env file exists
-	if _, err := os.Stat(pushEnvFile); os.IsNotExist(err) {
-		if pushAutoGenerate {
-			// Create a sample .env file
-			fmt.Printf("No .env file found. Creating a sample at %s\n", pushEnvFile)
-			sampleContent := "# Sample .env file created by envi\n" +
-				"# Replace these with your actual environment variables\n\n" +
-				"DB_HOST=localhost\n" +
-				"DB_PORT=5432\n" +
-				"DB_USER=username\n" +
-				"DB_PASSWORD=password\n" +
-				"API_KEY=your_api_key_here\n"
+	// Collect the files to push, keyed by their Gist filename
+	envFiles := make(map[string][]byte)
+	if len(pushFiles) > 0 {
+		// Each file is uploaded under its own name. Directories are expanded to the
+		// env files they contain, minus those excluded by their .enviignore.
+		var paths []string
+		for _, path := range pushFiles {
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				paths = append(paths, path)
+				continue
+			}
+			dirFiles, skipped, err := envFilesInDir(path)
+			if err != nil {
+				exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Could not read directory %s: %s", path, err))
+			}
+			for _, name := range skipped {
+				logInfo("Skipping %s (excluded by %s)", filepath.Join(path, name), enviIgnoreFile)
+			}
+			if len(dirFiles) == 0 {
+				exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("No env files to push in %s", path))
+			}
+			paths = append(paths, dirFiles...)
+		}
+		// Gist filenames can't hold directories, so two files with the same name would
+		// overwrite each other
+		pathsByName := make(map[string]string)
+		for _, path := range paths {
+			name := filepath.Base(path)
+			if other, ok := pathsByName[name]; ok {
+				if filepath.Clean(other) == filepath.Clean(path) {
+					continue // The same file given twice
+				}
+				exitWithError(ErrCodeGeneric, fmt.Sprintf("%s and %s would both be pushed as %s", other, path, name),
+					"Push files with the same name to separate Gists")
+			}
+			pathsByName[name] = path
 			
-			if err := os.WriteFile(pushEnvFile, []byte(sampleContent), 0600); err != nil {
-				fmt.Printf("Error creating sample .env file: %s\n", err)
-				os.Exit(1)
+			content, err := os.ReadFile(path)
+			if err != nil {
+				exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Could not read %s: %s", path, err))
 			}
-		} else {
-			fmt.Printf("Error: .env file not found at %s\n", pushEnvFile)
-			fmt.Println("Create the file first or use --auto to generate a sample")
-			os.Exit(1)
+			envFiles[name] = content
+			warnIfNotGitIgnored(path)
+		}
+	} else if pushFromStdin() {
+		// Content piped from another program; there is no file to check or generate
+		envContent, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Could not read .env content from stdin: %s", err))
 		}
+		envFiles[".env"] = envContent
+	} else {
+		// Resolve the .env path
+		pushEnvFile = resolveEnvPath(pushEnvFile, pushSearchUp)
+	
+		// Check if .env file exists
+		if _, err := os.Stat(pushEnvFile); os.IsNotExist(err) {
+			if pushAutoGenerate {
+				// Create a sample .env file
+				logInfo("No .env file found. Creating a sample at %s", pushEnvFile)
+				if err := os.WriteFile(pushEnvFile, []byte(sampleEnvContent), 0600); err != nil {
+					exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not create sample .env file: %s", err))
+				}
+			} else {
+				exitWithError(ErrCodeNoEnvFile, fmt.Sprintf(".env file not found at %s", pushEnvFile),
+					"Create the file first or use --auto to generate a sample")
+			}
+		}
+	
+		// Read .env file
+		envContent, err := os.ReadFile(pushEnvFile)
+		if err != nil {
+			exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Could not read .env file: %s", err))
+		}
+		warnIfNotGitIgnored(pushEnvFile)
+	
+		envFiles[".env"] = envContent
 	}
 	
-	// Read .env file
-	envContent, err := os.ReadFile(pushEnvFile)
-	if err != nil {
-		fmt.Printf("Error reading .env file: %s\n", err)
-		os.Exit(1)
+	// Generate the description from a template unless one was given explicitly
+	if !cmd.Flags().Changed("description") {
+		template := pushDescriptionTemplate
+		if template == "" && cfg != nil {
+			template = cfg.DescriptionTemplate
+		}
+		if template != "" {
+			pushDescription = renderDescription(template, pushProject)
+		}
 	}
 	
 	// Handle encryption options
 	if encryption.UseEncryption && encryption.UseMaskedEncryption {
-		fmt.Println("Warning: Both --encrypt and --mask flags specified. Using --mask (masked encryption).")
+		logWarn("Both --encrypt and --mask flags specified. Using --mask (masked encryption).")
 		encryption.UseEncryption = false
 	}
 	
-	if encryption.UseEncryption {
-		fmt.Println("Encrypting .env file...")
-		encryptedContent, err := encryption.EncryptContent(envContent)
-		if err != nil {
-			fmt.Printf("Error encrypting .env file: %s\n", err)
-			os.Exit(1)
+	// Let the user review each file and pick which values to mask
+	maskKeys := make(map[string]map[string]bool)
+	if pushInteractive {
+		if pushFromStdin() {
+			exitWithError(ErrCodeGeneric, "--interactive can't be used when reading from stdin")
 		}
-		envContent = encryptedContent
-		fmt.Println("Encryption successful.")
-	} else if encryption.UseMaskedEncryption {
-		fmt.Println("Masking values in .env file...")
-		maskedContent, err := encryption.MaskEnvContent(envContent)
-		if err != nil {
-			fmt.Println("Error masking .env file. Please check the input and try again.")
-			os.Exit(1)
+		if !encryption.UseTUI {
+			exitWithError(ErrCodeGeneric, "--interactive requires the terminal UI (remove --tui=false)")
+		}
+		for _, name := range sortedFileNames(envFiles) {
+			envFiles[name], maskKeys[name] = editEnvBeforePush(name, envFiles[name])
 		}
-		envContent = maskedContent
-		fmt.Println("Value masking successful. Variable names remain visible.")
 	}
 	
-	// Create GitHub client
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(cmd.Context(), ts)
-	client := github.NewClient(tc)
-	
-	// Get Gist ID (from flag or config)
-	if pushGistID == "" && cfg != nil && cfg.LastGistID != "" {
-		useLastID, err := tui.Confirm("Use saved Gist?", fmt.Sprintf("Would you like to update your last used Gist (%s)?", cfg.LastGistID))
+	// Get Gist ID (from flag, bookmark or config)
+	// When nobody can answer whether to update the saved Gist, push stops instead of
+	// creating a new Gist, which would happen unnoticed on every run of a script
+	pushGistID = resolveGistRef(pushGistID)
+	if pushGistID == "" && cfg != nil && cfg.LastGistID != "" && !pushForceNew {
+		if reason := savedGistPromptBlocked(); reason != "" && !promptAnswered() {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("There is a saved Gist (%s), but push can't ask whether to update it: %s", cfg.LastGistID, reason),
+				"Use --yes to update it, --id to choose a Gist, or --force-new to create a new one")
+		}
+		useLastID, err := confirmPrompt("Use saved Gist?", fmt.Sprintf("Would you like to update your last used Gist (%s)?", cfg.LastGistID))
 		if err != nil {
-			fmt.Printf("Error getting confirmation: %s\n", err)
-			os.Exit(1)
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not get confirmation: %s", err))
 		}
 		
 		if useLastID {
 			pushGistID = cfg.LastGistID
-			fmt.Printf("Using saved Gist ID: %s\n", pushGistID)
+			logInfo("Using saved Gist ID: %s", pushGistID)
 		}
 	}
 	
-	// Create or update Gist
-	if pushGistID == "" {
-		// Create new Gist
-		newGist := &github.Gist{
-			Description: github.String(pushDescription),
-			Public:      github.Bool(pushPublic),
-			Files: map[github.GistFilename]github.GistFile{
-				github.GistFilename(".env"): {
-					Content: github.String(string(envContent)),
-				},
-			},
+	// Variables only the Gist has are removed, unless --keep-remote keeps them
+	if pushGistID != "" {
+		reconcileRemoteVars(cmd, token, pushGistID, envFiles, maskKeys)
+	}
+	
+	// Keep the plain content to check whether the Gist is already up to date
+	plainFiles := make(map[string][]byte, len(envFiles))
+	for name, envContent := range envFiles {
+		plainFiles[name] = envContent
+	}
+	
+	// Apply the chosen encryption mode to each file
+	for name, envContent := range envFiles {
+		envFiles[name] = encryptForPush(name, envContent, maskKeys[name])
+	}
+	fullEncryption := encryption.UseEncryption
+	maskedEncryption := !fullEncryption && anyMasked(envFiles)
+	
+	// Make sure secrets aren't uploaded in clear text by accident
+	checkPlaintextSecrets(envFiles, cfg)
+	warnSecretValues(envFiles)
+	
+	// Catch files that GitHub would reject or only return in part
+	checkPushSizes(envFiles, maxSize)
+	
+	// Add a README: a custom one if given, otherwise instructions if encrypted.
+	// Pull detects encryption from the content itself, so the README is optional.
+	files := make(map[string][]byte, len(envFiles)+1)
+	for name, content := range envFiles {
+		files[name] = content
+	}
+	if !cmd.Flags().Changed("no-readme") && cfg != nil && pushReadmeFile == "" {
+		pushNoReadme = cfg.NoReadme
+	}
+	if pushReadmeFile != "" {
+		readme, err := os.ReadFile(pushReadmeFile)
+		if err != nil {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not read README file: %s", err))
 		}
-		
-		// Add README with instructions if encrypted
-		if encryption.UseEncryption || encryption.UseMaskedEncryption {
-			readmeContent := createReadmeContent(encryption.UseEncryption, encryption.UseMaskedEncryption)
-			newGist.Files[github.GistFilename("README.md")] = github.GistFile{
-				Content: github.String(readmeContent),
-			}
+		files["README.md"] = readme
+	} else if (fullEncryption || maskedEncryption) && !pushNoReadme {
+		files["README.md"] = []byte(createReadmeContent(fullEncryption, maskedEncryption))
+	}
+	
+	// Only change an existing Gist's description when one was given on the command line,
+	// even if it equals the default. An empty description leaves the remote one as it is.
+	description := pushDescription
+	created := pushGistID == ""
+	descriptionGiven := cmd.Flags().Changed("description") || cmd.Flags().Changed("description-template")
+	if !created && (pushKeepDescription || !descriptionGiven) {
+		description = ""
+	}
+	
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	
+	// Skip the upload when nothing changed, so repeated pushes don't create empty revisions
+	if !created && description == "" && gistUpToDate(ctx, token, pushGistID, plainFiles, envFiles) {
+		fmt.Printf("Gist %s is already up to date\n", pushGistID)
+		if plain, ok := plainFiles[".env"]; ok {
+			saveSnapshot(pushGistID, plain)
 		}
-		
-		// Create the Gist
-		gist, _, err := client.Gists.Create(cmd.Context(), newGist)
-		if err != nil {
-			fmt.Printf("Error creating Gist: %s\n", err)
-			os.Exit(1)
+		printJSONResult(map[string]interface{}{
+			"gist_id":   pushGistID,
+			"url":       gistWebURL(pushGistID),
+			"files":     sortedFileNames(envFiles),
+			"created":   false,
+			"unchanged": true,
+		})
+		return pushGistID
+	}
+	
+	// Create or update the Gist. Content was already protected above, so it is pushed as-is.
+	spinnerMessage := "Updating Gist..."
+	if created {
+		spinnerMessage = "Creating Gist..."
+	}
+	var gistID string
+	err = withSpinner(spinnerMessage, func() error {
+		var err error
+		gistID, err = envi.Push(ctx, envi.PushOptions{
+			Token:       token,
+			BaseURL:     githubURL,
+			GistID:      pushGistID,
+			Description: description,
+			Public:      pushPublic,
+			Files:       files,
+		})
+		return err
+	})
+	if err != nil {
+		if created {
+			exitWithError(gistErrorCode(err), fmt.Sprintf("Could not create Gist: %s", apiError(err)))
 		}
-		
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not update Gist %s: %s", pushGistID, apiError(err)))
+	}
+	
+	if created {
 		// Save Gist ID in config
-		if cfg != nil {
-			cfg.LastGistID = *gist.ID
+		saved := false
+		if cfg != nil && !noSaveGistID(cmd, cfg, pushNoSaveID) {
+			cfg.LastGistID = gistID
 			if err := config.SaveConfig(cfg); err != nil {
-				fmt.Printf("Warning: Could not save Gist ID to config: %s\n", err)
+				logWarn("Could not save Gist ID to config: %s", err)
+			} else {
+				saved = true
 			}
 		}
 		
-		fmt.Println("Successfully pushed .env to GitHub Gist!")
-		fmt.Printf("Gist URL: https://gist.github.com/%s\n", *gist.ID)
-		fmt.Printf("Gist ID: %s (saved for future use)\n", *gist.ID)
+		fmt.Printf("Successfully pushed %d file(s) to GitHub Gist!\n", len(envFiles))
+		fmt.Printf("Gist URL: %s\n", gistWebURL(gistID))
+		if saved {
+			fmt.Printf("Gist ID: %s (saved for future use)\n", gistID)
+		} else {
+			fmt.Printf("Gist ID: %s\n", gistID)
+		}
 	} else {
-		// Update existing Gist
-		// First, get the current Gist to preserve other files
-		gist, _, err := client.Gists.Get(cmd.Context(), pushGistID)
-		if err != nil {
-			fmt.Printf("Error retrieving Gist with ID %s: %s\n", pushGistID, err)
-			os.Exit(1)
+		fmt.Printf("Successfully updated %d file(s) in GitHub Gist!\n", len(envFiles))
+		fmt.Printf("Gist URL: %s\n", gistWebURL(gistID))
+	}
+	
+	// Remember what was pushed, so 'envi status' can tell what changed since
+	if plain, ok := plainFiles[".env"]; ok {
+		saveSnapshot(gistID, plain)
+	}
+	
+	// Read the Gist back to catch partial writes or encoding problems. Protected files
+	// are compared after decryption, like the up-to-date check.
+	if pushVerify {
+		verifyCtx, verifyCancel := apiContext(cmd)
+		defer verifyCancel()
+		if !gistUpToDate(verifyCtx, token, gistID, plainFiles, envFiles) {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("Verification failed: Gist %s does not hold the pushed content", gistID),
+				"Compare it with your local file using 'envi diff', or push again")
 		}
-		
-		// Update the Gist
-		gist.Files = map[github.GistFilename]github.GistFile{
-			github.GistFilename(".env"): {
-				Content: github.String(string(envContent)),
-			},
+		fmt.Println("Verified: the Gist holds the pushed content")
+	}
+	
+	// Offer to keep the env file and envi's own files out of git; content from
+	// stdin has no file or directory to offer it for
+	if (cfg == nil || !cfg.NoGitignoreOffer) && !pushFromStdin() {
+		offerGitignoreAfterPush(filepath.Dir(pushEnvFile))
+	}
+	
+	printJSONResult(map[string]interface{}{
+		"gist_id":   gistID,
+		"url":       gistWebURL(gistID),
+		"files":     sortedFileNames(envFiles),
+		"created":   created,
+		"unchanged": false,
+		"verified":  pushVerify,
+	})
+	return gistID
+}
+
+// gistUpToDate reports whether a Gist already holds the given files. Every encryption
+// uses a new nonce, so protected content is compared after decryption. Changing how a
+// file is protected, or which values are masked, counts as a change.
+func gistUpToDate(ctx context.Context, token, gistID string, plainFiles, envFiles map[string][]byte) bool {
+	client := newGitHubClient(ctx, token)
+	
+	// Any real problem with the Gist is reported by the update itself
+	gist, err := fetchGist(ctx, client, gistID)
+	if err != nil {
+		return false
+	}
+	
+	for name, plain := range plainFiles {
+		file, ok := gist.Files[github.GistFilename(name)]
+		if !ok || file.Content == nil {
+			return false
 		}
+		remote := []byte(file.GetContent())
 		
-		// Add README with instructions if encrypted
-		if encryption.UseEncryption || encryption.UseMaskedEncryption {
-			readmeContent := createReadmeContent(encryption.UseEncryption, encryption.UseMaskedEncryption)
-			gist.Files[github.GistFilename("README.md")] = github.GistFile{
-				Content: github.String(readmeContent),
-			}
+		if encryptionState(remote) != encryptionState(envFiles[name]) {
+			return false
 		}
-		
-		// Update Gist description if provided
-		if pushDescription != "Environment variables created with envi" && cmd.Flags().Changed("description") {
-			gist.Description = github.String(pushDescription)
+		if !equalKeySets(maskedKeys(remote), maskedKeys(envFiles[name])) {
+			return false
 		}
 		
-		// Update the Gist
-		_, _, err = client.Gists.Edit(cmd.Context(), pushGistID, gist)
+		remotePlain, err := decryptEnvContent(remote)
+		if err != nil || !bytes.Equal(remotePlain, plain) {
+			return false
+		}
+	}
+	
+	return true
+}
+
+// maskedKeys returns the variables whose values are masked in content
+func maskedKeys(content []byte) map[string]bool {
+	keys := make(map[string]bool)
+	entries, _ := parseEnvEntries(content)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Value, encryption.MaskedPrefix) {
+			keys[entry.Key] = true
+		}
+	}
+	return keys
+}
+
+// equalKeySets reports whether two sets contain the same keys
+func equalKeySets(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if !b[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// encryptForPush applies the selected encryption mode to a file's content, exiting if
+// that fails. See protectForPush.
+func encryptForPush(name string, envContent []byte, maskKeys map[string]bool) []byte {
+	protected, err := protectForPush(name, envContent, maskKeys)
+	if err != nil {
+		exitOnKeyFileError(err)
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not push %s: %s", name, err))
+	}
+	return protected
+}
+
+// protectForPush applies the selected encryption mode to a file's content.
+// When maskKeys is non-nil (set in the interactive editor), only those keys are masked.
+func protectForPush(name string, envContent []byte, maskKeys map[string]bool) ([]byte, error) {
+	if maskKeys != nil && !encryption.UseEncryption {
+		if len(maskKeys) == 0 {
+			return envContent, nil
+		}
+		logInfo("Masking %d selected values in %s...", len(maskKeys), name)
+		maskedContent, err := encryption.MaskEnvKeys(envContent, maskKeys)
 		if err != nil {
-			fmt.Printf("Error updating Gist: %s\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("values could not be masked: %w", err)
+		}
+		return maskedContent, nil
+	}
+	
+	if encryption.UseEncryption {
+		logInfo("Encrypting %s...", name)
+		encryptedContent, err := encryption.EncryptContent(envContent)
+		if err != nil {
+			return nil, fmt.Errorf("content could not be encrypted: %w", err)
+		}
+		envContent = encryptedContent
+		logInfo("Encryption successful.")
+	} else if encryption.UseMaskedEncryption {
+		logInfo("Masking values in %s...", name)
+		maskedContent, err := encryption.MaskEnvContent(envContent)
+		if err != nil {
+			return nil, fmt.Errorf("values could not be masked: %w", err)
+		}
+		envContent = maskedContent
+		logInfo("Value masking successful. Variable names remain visible.")
+	}
+	
+	return envContent, nil
+}
+
+// editEnvBeforePush opens the variable editor for a file and returns the edited
+// content along with the keys marked for masking
+func editEnvBeforePush(name string, content []byte) ([]byte, map[string]bool) {
+	entries, _ := parseEnvEntries(content)
+	
+	variables := make([]tui.EditorVariable, len(entries))
+	for i, entry := range entries {
+		variables[i] = tui.EditorVariable{
+			Key:     entry.Key,
+			Value:   entry.Value,
+			Include: true,
+			Mask:    encryption.UseMaskedEncryption,
+			Secret:  isSensitiveKey(entry.Key),
+		}
+	}
+	
+	edited, err := tui.EditVariables(fmt.Sprintf("Review %s before pushing", name), variables, redact)
+	if err != nil {
+		fmt.Println("Push canceled.")
+		os.Exit(1)
+	}
+	
+	// Rewrite the file line by line so comments and layout are kept
+	byLine := make(map[int]int, len(entries))
+	for i, entry := range entries {
+		byLine[entry.Line] = i
+	}
+	
+	maskKeys := make(map[string]bool)
+	lines, newline := encryption.SplitLines(content)
+	var output []string
+	for i, line := range lines {
+		idx, isVar := byLine[i+1]
+		if !isVar {
+			output = append(output, line)
+			continue
 		}
 		
-		fmt.Println("Successfully updated .env in GitHub Gist!")
-		fmt.Printf("Gist URL: https://gist.github.com/%s\n", pushGistID)
+		v := edited[idx]
+		if !v.Include {
+			continue
+		}
+		if v.Key == entries[idx].Key && v.Value == entries[idx].Value {
+			output = append(output, line) // Unedited variables are pushed as they were written
+		} else {
+			output = append(output, withInlineComment(formatEnvLine(entries[idx].Prefix, v.Key, v.Value), entries[idx].Comment))
+		}
+		if v.Mask {
+			maskKeys[v.Key] = true
+		}
 	}
+	
+	return encryption.JoinLines(output, newline), maskKeys
+}
+
+// plaintextSecretsPolicy returns what to do about unencrypted secrets, from the flags
+// or the configured policy
+func plaintextSecretsPolicy(cfg *config.Config) string {
+	policy := config.PlaintextSecretsWarn
+	if cfg != nil && cfg.PlaintextSecrets != "" {
+		policy = cfg.PlaintextSecrets
+	}
+	if pushStrictSecrets {
+		policy = config.PlaintextSecretsBlock
+	}
+	if pushAllowPlaintext {
+		policy = config.PlaintextSecretsAllow
+	}
+	return policy
+}
+
+// findPlaintextSecrets returns the variables with secret-like names that would be
+// uploaded unencrypted, as "KEY (file)"
+func findPlaintextSecrets(envFiles map[string][]byte) []string {
+	var found []string
+	for _, name := range sortedFileNames(envFiles) {
+		content := envFiles[name]
+		if encryption.IsEncrypted(content) {
+			continue
+		}
+		entries, _ := parseEnvEntries(content)
+		for _, entry := range entries {
+			if isSensitiveKey(entry.Key) && entry.Value != "" && !strings.HasPrefix(entry.Value, encryption.MaskedPrefix) {
+				found = append(found, fmt.Sprintf("%s (%s)", entry.Key, name))
+			}
+		}
+	}
+	return found
+}
+
+// checkPlaintextSecrets looks for likely secrets that would be uploaded unencrypted and,
+// depending on flags and the configured policy, asks for confirmation or stops the push
+func checkPlaintextSecrets(envFiles map[string][]byte, cfg *config.Config) {
+	policy := plaintextSecretsPolicy(cfg)
+	if policy == config.PlaintextSecretsAllow {
+		return
+	}
+	
+	found := findPlaintextSecrets(envFiles)
+	if len(found) == 0 {
+		return
+	}
+	
+	logWarn("These variables look like secrets and will be pushed without encryption:")
+	for _, key := range found {
+		fmt.Fprintf(os.Stderr, "  - %s\n", key)
+	}
+	
+	// Without --yes or --no, nobody can answer a prompt in JSON mode, when stdin holds the content or password,
+	// or when it isn't a terminal, so treat it like block
+	if policy == config.PlaintextSecretsBlock || (!promptAnswered() && (jsonOutput || stdinUsed() || !stdinIsTerminal())) {
+		exitWithError(ErrCodeGeneric, "Refusing to push unencrypted secrets",
+			"Use --mask or --encrypt, or pass --allow-plaintext to push anyway")
+	}
+	
+	proceed, err := confirmPrompt("Push unencrypted secrets?", "Push these values in clear text anyway?")
+	if err != nil || !proceed {
+		fmt.Println("Push canceled. Use --mask or --encrypt to protect the values.")
+		os.Exit(1)
+	}
+}
+
+// warnSecretValues warns about unencrypted values that look like credentials, such as AWS
+// keys or private keys, under names checkPlaintextSecrets doesn't recognize as secret
+func warnSecretValues(envFiles map[string][]byte) {
+	allowed := allowedKeys(pushAllowSecrets)
+	var found []secretFinding
+	for _, name := range sortedFileNames(envFiles) {
+		for _, finding := range scanEnvContent(name, envFiles[name], secretDetectors, allowed) {
+			if !isSensitiveKey(finding.Key) {
+				found = append(found, finding)
+			}
+		}
+	}
+	if len(found) == 0 {
+		return
+	}
+	
+	logWarn("These values look like credentials and will be pushed without encryption:")
+	for _, finding := range found {
+		fmt.Fprintf(os.Stderr, "  - %s (%s:%d): %s\n", finding.Key, finding.File, finding.Line, strings.Join(finding.Types, ", "))
+	}
+	logWarn("Use --mask to encrypt them, or --allow-secret KEY if they aren't secret")
+}
+
+// checkPushSizes exits if a file is larger than maxSize (0 for no limit), and warns about
+// files that are allowed but too large to be pulled intact
+func checkPushSizes(envFiles map[string][]byte, maxSize int64) {
+	for _, name := range sortedFileNames(envFiles) {
+		size := int64(len(envFiles[name]))
+		if maxSize > 0 && size > maxSize {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("%s is %s after encryption, over the %s limit", name, formatByteSize(size), formatByteSize(maxSize)),
+				fmt.Sprintf("The GitHub API only returns the first %s of a Gist file, so larger files can't be pulled intact", formatByteSize(gistAPIFileLimit)),
+				"Move large values such as certificates out of the file, or raise the limit with --max-size")
+		}
+		if size > gistAPIFileLimit {
+			logWarn("%s is %s; the GitHub API only returns the first %s of a Gist file, so 'envi pull' won't be able to read it",
+				name, formatByteSize(size), formatByteSize(gistAPIFileLimit))
+		}
+	}
+}
+
+// pushFromStdin reports whether the .env content is read from stdin (--file -)
+func pushFromStdin() bool {
+	return len(pushFiles) == 0 && pushEnvFile == "-"
+}
+
+// stdinUsed reports whether stdin carries the .env content or the password, so it can't
+// be used to answer prompts
+func stdinUsed() bool {
+	return pushFromStdin() || encryption.ReadsStdin()
+}
+
+// savedGistPromptBlocked returns why push can't ask whether to update the saved Gist,
+// or "" if it can
+func savedGistPromptBlocked() string {
+	switch {
+	case jsonOutput:
+		return "--json is set"
+	case pushFromStdin():
+		return "stdin holds the .env content"
+	case encryption.ReadsStdin():
+		return "stdin holds the password or key"
+	case !stdinIsTerminal():
+		return "stdin is not a terminal"
+	}
+	return ""
+}
+
+// anyMasked reports whether any of the files contains masked values
+func anyMasked(envFiles map[string][]byte) bool {
+	for _, content := range envFiles {
+		if encryption.IsMasked(content) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedFileNames returns the names of the files being pushed in alphabetical order
+func sortedFileNames(envFiles map[string][]byte) []string {
+	names := make([]string, 0, len(envFiles))
+	for name := range envFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // createReadmeContent creates a helpful README for the Gist
@@ -255,6 +800,35 @@ func createReadmeContent(fullEncryption, maskedEncryption bool) string {
 	return readme
 }
 
+// renderDescription fills in the {project}, {date}, {user} and {host} placeholders
+// of a description template
+func renderDescription(template, project string) string {
+	if project == "" {
+		// Name the project after the directory holding the .env file
+		dir := "."
+		if len(pushFiles) == 0 {
+			dir = filepath.Dir(pushEnvFile)
+		}
+		if absDir, err := filepath.Abs(dir); err == nil {
+			project = filepath.Base(absDir)
+		}
+	}
+	
+	username := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	
+	host, _ := os.Hostname()
+	
+	return strings.NewReplacer(
+		"{project}", project,
+		"{date}", time.Now().Format("2006-01-02"),
+		"{user}", username,
+		"{host}", host,
+	).Replace(template)
+}
+
 // applyEncryptionDefaults applies default encryption settings from config
 func applyEncryptionDefaults(cmd *cobra.Command, cfg *config.Config) {
 	// Apply default encryption settings if not explicitly set by flags
@@ -262,21 +836,28 @@ func applyEncryptionDefaults(cmd *cobra.Command, cfg *config.Config) {
 		if cfg.UseMaskedEncryption {
 			encryption.UseMaskedEncryption = true
 			encryption.UseEncryption = false
-			fmt.Println("Using default setting: Masked encryption enabled")
+			logInfo("Using default setting: Masked encryption enabled")
 		} else {
 			encryption.UseEncryption = true
 			encryption.UseMaskedEncryption = false
-			fmt.Println("Using default setting: Full encryption enabled")
+			logInfo("Using default setting: Full encryption enabled")
 		}
 	}
 	
 	if !cmd.Flags().Changed("use-key-file") && cfg.UseKeyFileByDefault {
 		encryption.UseKeyFile = true
-		fmt.Println("Using default setting: Using key file for encryption")
+		logInfo("Using default setting: Using key file for encryption")
 	}
 	
 	if !cmd.Flags().Changed("key-file") && cfg.DefaultKeyFile != "" {
 		encryption.EncryptionKeyFile = cfg.DefaultKeyFile
-		fmt.Printf("Using default key file: %s\n", encryption.EncryptionKeyFile)
+		logInfo("Using default key file: %s", encryption.EncryptionKeyFile)
 	}
+	
+	if !cmd.Flags().Changed("cipher") && cfg.Cipher != "" {
+		encryption.CipherName = cfg.Cipher
+	}
+	
+	logging.Debug("Encryption settings", "encrypt", encryption.UseEncryption, "mask", encryption.UseMaskedEncryption,
+		"key_file", encryption.UseKeyFile, "cipher", encryption.CipherName, "deterministic", encryption.DeterministicMasking)
 } 
\ No newline at end of file
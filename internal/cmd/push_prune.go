@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// reconcileRemoteVars handles the variables that the Gist's copy of each file has and the
+// pushed content doesn't. By default the pushed file replaces the Gist's copy and they are
+// listed in a warning; with --prune push asks before removing them, and with --keep-remote
+// they are added to the content. Only --keep-remote decrypts the Gist's copy, so replacing
+// an encrypted file never needs its old key. envFiles holds the content before encryption
+// and is updated in place.
+func reconcileRemoteVars(cmd *cobra.Command, token, gistID string, envFiles map[string][]byte, maskKeys map[string]map[string]bool) {
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+
+	// Any real problem with the Gist is reported by the update itself
+	gist, err := fetchGist(ctx, newGitHubClient(ctx, token), gistID)
+	if err != nil {
+		return
+	}
+
+	var prunedFiles, encryptedFiles []string
+	pruned := make(map[string][]string)
+	for _, name := range sortedFileNames(envFiles) {
+		remote, ok := gistFileContent(gist, name)
+		if !ok {
+			continue
+		}
+
+		if pushKeepRemote {
+			content, keys, err := appendRemoteOnlyVars(remote, envFiles[name], reprotectsValues(maskKeys[name]))
+			if err != nil {
+				exitOnKeyFileError(err)
+				exitWithError(ErrCodeDecryptFailed, fmt.Sprintf("Could not keep the variables that only %s in Gist %s has: %s", name, gistID, err),
+					"Push without --keep-remote to replace the Gist's copy instead")
+			}
+			if len(keys) > 0 {
+				logInfo("Keeping %d variable(s) that only the Gist's %s has: %s", len(keys), name, strings.Join(keys, ", "))
+				envFiles[name] = content
+			}
+			continue
+		}
+
+		// The variables of an encrypted file can't be listed without its key
+		if encryption.IsEncrypted(remote) {
+			if pushPrune {
+				encryptedFiles = append(encryptedFiles, name)
+			}
+			continue
+		}
+		_, keys, _ := remoteOnlyVars(remote, envFiles[name], false)
+		if len(keys) == 0 {
+			continue
+		}
+		if pushPrune {
+			prunedFiles = append(prunedFiles, name)
+			pruned[name] = keys
+			continue
+		}
+		logWarn("Removing %d variable(s) that only the Gist's %s has: %s (use --keep-remote to keep them)", len(keys), name, strings.Join(keys, ", "))
+	}
+
+	if len(prunedFiles) > 0 || len(encryptedFiles) > 0 {
+		confirmPrune(gistID, prunedFiles, pruned, encryptedFiles)
+	}
+}
+
+// confirmPrune lists the variables --prune is about to remove from each file, and the
+// encrypted files whose variables can't be listed, and asks before going on
+func confirmPrune(gistID string, files []string, pruned map[string][]string, encryptedFiles []string) {
+	count := 0
+	for _, name := range files {
+		logInfo("Only the Gist's %s has %s, which --prune removes", name, strings.Join(pruned[name], ", "))
+		count += len(pruned[name])
+	}
+	for _, name := range encryptedFiles {
+		logInfo("The Gist's %s is encrypted, so the variables --prune removes from it can't be listed", name)
+	}
+
+	if !promptAnswered() && (jsonOutput || stdinUsed() || !stdinIsTerminal()) {
+		exitWithError(ErrCodeGeneric, "Removing variables from the Gist needs confirmation", "Use --yes to remove them without asking")
+	}
+	question := fmt.Sprintf("Remove these %d variable(s) from Gist %s?", count, gistID)
+	if len(encryptedFiles) > 0 {
+		question = fmt.Sprintf("Remove the variables that only Gist %s has?", gistID)
+	}
+	remove, err := confirmPrompt("Remove variables?", question)
+	if err != nil || !remove {
+		fmt.Println("Push canceled.")
+		os.Exit(1)
+	}
+}
+
+// gistFileContent returns the content of a file in a Gist, if it has one
+func gistFileContent(gist *github.Gist, name string) ([]byte, bool) {
+	file, ok := gist.Files[github.GistFilename(name)]
+	if !ok || file.Content == nil {
+		return nil, false
+	}
+	return []byte(file.GetContent()), true
+}
+
+// reprotectsValues reports whether a push protects every value of a file, so values
+// kept from the Gist can be added to it in plain text
+func reprotectsValues(maskKeys map[string]bool) bool {
+	return encryption.UseEncryption || (encryption.UseMaskedEncryption && maskKeys == nil)
+}
+
+// appendRemoteOnlyVars adds the variables that remote has and content doesn't to the end
+// of content and returns their names. Masked values are unmasked only when the push
+// protects them again; otherwise they are kept masked. Values of an encrypted remote file
+// can only be kept if the push protects them again.
+func appendRemoteOnlyVars(remote, content []byte, reprotect bool) ([]byte, []string, error) {
+	if encryption.IsEncrypted(remote) && !reprotect {
+		return nil, nil, fmt.Errorf("it is encrypted and this push is not")
+	}
+	lines, keys, err := remoteOnlyVars(remote, content, reprotect)
+	if err != nil || len(lines) == 0 {
+		return content, nil, err
+	}
+
+	merged := append([]byte{}, content...)
+	if len(merged) > 0 && merged[len(merged)-1] != '\n' {
+		merged = append(merged, '\n')
+	}
+	for _, line := range lines {
+		merged = append(merged, line+"\n"...)
+	}
+	return merged, keys, nil
+}
+
+// remoteOnlyVars returns the lines and names of the variables that remote has and local
+// doesn't, in the order of remote. Encrypted remote content is decrypted to read them,
+// and masked content too if unmask is set.
+func remoteOnlyVars(remote, local []byte, unmask bool) ([]string, []string, error) {
+	if encryption.IsEncrypted(remote) || (unmask && encryption.IsMasked(remote)) {
+		decrypted, err := decryptEnvContent(remote)
+		if err != nil {
+			return nil, nil, err
+		}
+		remote = decrypted
+	}
+
+	localVars, _ := parseEnvContent(local)
+	entries, _ := parseEnvEntries(remote)
+	remoteLines, _ := encryption.SplitLines(remote)
+
+	var lines, keys []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if _, ok := localVars[entry.Key]; ok || seen[entry.Key] {
+			continue
+		}
+		seen[entry.Key] = true
+		lines = append(lines, remoteLines[entry.Line-1])
+		keys = append(keys, entry.Key)
+	}
+	return lines, keys, nil
+}
+
+// reconcileWatchedVars is reconcileRemoteVars for push --watch, which fetches the Gist
+// itself and reports problems instead of exiting. Without --keep-remote, the variables the
+// pushed content drops are only listed.
+func reconcileWatchedVars(ctx context.Context, token, gistID string, content []byte) ([]byte, error) {
+	gist, err := fetchGist(ctx, newGitHubClient(ctx, token), gistID)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	remote, ok := gistFileContent(gist, ".env")
+	if !ok {
+		return content, nil
+	}
+
+	if !pushKeepRemote {
+		if !encryption.IsEncrypted(remote) {
+			if _, keys, _ := remoteOnlyVars(remote, content, false); len(keys) > 0 {
+				logWarn("Removing %d variable(s) that only the Gist has: %s", len(keys), strings.Join(keys, ", "))
+			}
+		}
+		return content, nil
+	}
+
+	merged, keys, err := appendRemoteOnlyVars(remote, content, reprotectsValues(nil))
+	if err != nil {
+		return nil, fmt.Errorf("could not keep the variables only the Gist has: %s", err)
+	}
+	if len(keys) > 0 {
+		logInfo("Keeping %d variable(s) that only the Gist has: %s", len(keys), strings.Join(keys, ", "))
+	}
+	return merged, nil
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/pkg/envi"
+)
+
+// checkWatchFlags exits if --watch is combined with flags it can't work with
+func checkWatchFlags() {
+	switch {
+	case len(pushFiles) > 0:
+		exitWithError(ErrCodeGeneric, "--watch can't be used with --files; it watches a single .env file")
+	case pushFromStdin():
+		exitWithError(ErrCodeGeneric, "--watch can't be used with --file -, since there is no file to watch")
+	case pushInteractive:
+		exitWithError(ErrCodeGeneric, "--watch can't be used with --interactive")
+	case jsonOutput:
+		exitWithError(ErrCodeGeneric, "--watch can't be used with --json, since it keeps printing results")
+	case pushPrune:
+		exitWithError(ErrCodeGeneric, "--watch can't be used with --prune, since removing variables needs confirmation")
+	case pushDebounce < 0:
+		exitWithError(ErrCodeGeneric, "--debounce can't be negative")
+	}
+}
+
+// watchAndPush pushes the .env file to the Gist again whenever it changes, until
+// interrupted. It waits for --debounce to pass without further changes, so a burst of
+// saves is pushed once, and skips saves that didn't change the content.
+func watchAndPush(cmd *cobra.Command, gistID string) {
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cmd.SetContext(ctx)
+
+	token, err := config.GetGitHubToken()
+	if err != nil {
+		exitWithError(ErrCodeNoToken, err.Error())
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = nil
+	}
+
+	pushed, err := os.ReadFile(pushEnvFile)
+	if err != nil {
+		exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Could not read .env file: %s", err))
+	}
+
+	// Unencrypted secrets already accepted by the first push may be pushed again, but new
+	// ones need the confirmation only a normal push can ask for
+	acceptedSecrets := make(map[string]bool)
+	if !encryption.UseEncryption && !encryption.UseMaskedEncryption {
+		for _, secret := range findPlaintextSecrets(map[string][]byte{".env": pushed}) {
+			acceptedSecrets[secret] = true
+		}
+	}
+
+	// Watch the directory rather than the file, so saves that replace the file, as many
+	// editors do, are seen too
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not watch %s: %s", pushEnvFile, err))
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(pushEnvFile)); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not watch %s: %s", pushEnvFile, err))
+	}
+	watched := filepath.Clean(pushEnvFile)
+
+	fmt.Printf("\nWatching %s for changes (press Ctrl-C to stop)\n", pushEnvFile)
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopped watching")
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// Each change restarts the wait, so a burst of saves is pushed once
+			if filepath.Clean(event.Name) == watched && event.Has(fsnotify.Write|fsnotify.Create) {
+				debounce = time.After(pushDebounce)
+			}
+			continue
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "[%s] Could not watch %s: %s\n", time.Now().Format("15:04:05"), pushEnvFile, err)
+			continue
+		case <-debounce:
+			debounce = nil
+		}
+
+		content, err := os.ReadFile(pushEnvFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[%s] Could not read %s: %s\n", time.Now().Format("15:04:05"), pushEnvFile, err)
+			continue
+		}
+		if bytes.Equal(content, pushed) {
+			continue
+		}
+		if err := pushWatchedFile(cmd, token, gistID, content, cfg, acceptedSecrets); err != nil {
+			fmt.Fprintf(os.Stderr, "[%s] Push failed: %s\n", time.Now().Format("15:04:05"), err)
+			continue
+		}
+		pushed = content
+	}
+}
+
+// pushWatchedFile pushes changed .env content to the Gist with the encryption chosen for
+// the first push, within --timeout, and prints the result. Variables only the Gist has
+// are handled as on the first push.
+func pushWatchedFile(cmd *cobra.Command, token, gistID string, content []byte, cfg *config.Config, acceptedSecrets map[string]bool) error {
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+
+	// Values kept from the Gist are already there in the same form, so only the local
+	// content is checked for new plaintext secrets
+	localFiles := map[string][]byte{".env": content}
+	content, err := reconcileWatchedVars(ctx, token, gistID, content)
+	if err != nil {
+		return err
+	}
+
+	protected, err := protectForPush(".env", content, nil)
+	if err != nil {
+		return err
+	}
+	envFiles := map[string][]byte{".env": protected}
+	if plaintextSecretsPolicy(cfg) != config.PlaintextSecretsAllow && !encryption.UseEncryption && !encryption.UseMaskedEncryption {
+		for _, secret := range findPlaintextSecrets(localFiles) {
+			if !acceptedSecrets[secret] {
+				return fmt.Errorf("%s looks like a secret and would be pushed without encryption; push without --watch to confirm it", secret)
+			}
+		}
+	}
+	warnSecretValues(envFiles)
+
+	plainFiles := map[string][]byte{".env": content}
+	if gistUpToDate(ctx, token, gistID, plainFiles, envFiles) {
+		fmt.Printf("[%s] Gist %s is already up to date\n", time.Now().Format("15:04:05"), gistID)
+		saveSnapshot(gistID, content)
+		return nil
+	}
+
+	_, err = envi.Push(ctx, envi.PushOptions{
+		Token:   token,
+		BaseURL: githubURL,
+		GistID:  gistID,
+		Files:   envFiles,
+	})
+	if err != nil {
+		return apiError(err)
+	}
+	saveSnapshot(gistID, content)
+	fmt.Printf("[%s] Pushed %s to Gist %s\n", time.Now().Format("15:04:05"), pushEnvFile, gistID)
+	return nil
+}
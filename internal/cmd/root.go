@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"net/http"
+	"time"
+
 	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
 
+	"github.com/dexterity-inc/envi/internal/config"
 	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/internal/logging"
 	"github.com/dexterity-inc/envi/internal/version"
 )
 
@@ -16,6 +23,36 @@ var rootCmd = &cobra.Command{
 	
 	// This will run before the main command execution
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Keep stdout for JSON results when --json is set
+		enableJSONOutput()
+		
+		if assumeYes && assumeNo {
+			exitWithError(ErrCodeGeneric, "--yes and --no can't be used together")
+		}
+		
+		// Point every config, key file and snapshot lookup at --config-dir
+		if err := config.SetBaseDir(configDir); err != nil {
+			exitWithError(ErrCodeGeneric, err.Error())
+		}
+		
+		// Bare key file names such as .envi.key refer to the data directory
+		if dataDir, err := config.DataDir(); err == nil {
+			encryption.KeyFileDir = dataDir
+		}
+		
+		// With --verbose, GitHub clients built from the command's context log their requests
+		if logging.Verbose {
+			logging.Debug("Starting", "command", cmd.CommandPath(), "version", version.Version)
+			httpClient := &http.Client{Transport: logging.Transport(http.DefaultTransport)}
+			cmd.SetContext(context.WithValue(cmd.Context(), oauth2.HTTPClient, httpClient))
+		}
+		
+		// Use the GitHub Enterprise Server from --github-url or the config, if any
+		resolveGitHubURL(cmd)
+		
+		// Look for a newer release while the command runs, if turned on
+		startUpdateCheck(cmd)
+		
 		// Check if the version flag was used
 		if cmd.Flag("version") != nil && cmd.Flag("version").Changed {
 			displayVersion()
@@ -24,16 +61,37 @@ var rootCmd = &cobra.Command{
 		}
 	},
 	
+	// Forget the encryption key once the command is done, then report the update check
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		encryption.ClearKeyCache()
+		
+		// Mention a newer release found by the background check
+		finishUpdateCheck()
+	},
+	
 	Run: func(cmd *cobra.Command, args []string) {
 		// Show help by default when no subcommand is provided
 		cmd.Help()
 	},
 }
 
+// configDir overrides the directory envi keeps its files in
+var configDir string
+
 // Execute runs the root command and handles errors
 func Execute() error {
 	// Set up global flags
 	rootCmd.PersistentFlags().BoolVar(&encryption.UseTUI, "tui", true, "Use interactive terminal UI")
+	rootCmd.PersistentFlags().BoolVar(&showValues, "show-values", false, "Show variable values in output instead of redacting them")
+	rootCmd.PersistentFlags().DurationVar(&requestTimeout, "timeout", 30*time.Second, "Maximum time to wait for GitHub (0 for no limit)")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Print results and errors as JSON for scripts")
+	rootCmd.PersistentFlags().BoolVarP(&quietOutput, "quiet", "q", false, "Don't print progress messages (warnings and errors are still shown)")
+	rootCmd.PersistentFlags().StringVar(&githubURL, "github-url", "", "GitHub Enterprise Server address, e.g. https://github.example.com (default public GitHub)")
+	rootCmd.PersistentFlags().BoolVarP(&logging.Verbose, "verbose", "V", false, "Log decisions and GitHub requests to stderr for debugging (never logs secret values)")
+	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", "", "Directory for the config file, key files and snapshots (overrides ENVI_CONFIG and XDG directories)")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to every confirmation without asking, for scripts")
+	rootCmd.PersistentFlags().BoolVar(&assumeNo, "no", false, "Answer no to every confirmation without asking, for scripts")
+	rootCmd.PersistentFlags().BoolVar(&inlineComments, "inline-comments", false, "Treat ' #' after an unquoted value as the start of a comment")
 	
 	// Initialize commands
 	InitConfigCommand()
@@ -42,8 +100,24 @@ func Execute() error {
 	InitPullCommand()
 	InitListCommand()
 	InitValidateCommand()
+	InitLintCommand()
+	InitScanCommand()
 	InitMergeCommand()
+	InitStatusCommand()
+	InitDiffCommand()
+	InitExampleCommand()
+	InitVisibilityCommand()
+	InitCopyCommand()
+	InitWhoamiCommand()
+	InitDoctorCommand()
+	InitBookmarkCommand()
+	InitCommentCommand()
+	InitTemplateCommand()
+	InitCheckGitignoreCommand()
+	InitInitCommand()
+	InitBackupCommand()
 	InitVersionCommand()
+	InitUpdateCheckCommand()
 	InitCompletionCommand()
 	
 	// Initialize command flags
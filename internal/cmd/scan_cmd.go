@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// Scan command flags
+var (
+	scanEnvFile  string
+	scanSearchUp bool
+	scanAllow    []string
+)
+
+// secretDetector recognizes one kind of secret from a variable's name or value
+type secretDetector struct {
+	Name  string
+	Match func(key, value string) bool
+}
+
+// secretDetectors are the checks scan and push run on every variable, in the order their
+// names are reported. To recognize a new kind of secret, add a detector here.
+var secretDetectors = []secretDetector{
+	{"AWS access key ID", matchValue(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"Google API key", matchValue(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{"GitHub token", matchValue(`\b(ghp|gho|ghu|ghs|ghr)_[0-9A-Za-z]{36}\b|\bgithub_pat_[0-9A-Za-z_]{22,}`)},
+	{"Slack token", matchValue(`\bxox[abprs]-[0-9A-Za-z-]{10,}`)},
+	{"Stripe secret key", matchValue(`\b[rs]k_live_[0-9A-Za-z]{16,}`)},
+	{"private key", matchValue(`-----BEGIN [A-Z ]*PRIVATE KEY`)},
+	{"high-entropy string", func(key, value string) bool { return isHighEntropy(value) }},
+	{"secret-like name", func(key, value string) bool { return isSensitiveKey(key) }},
+}
+
+// matchValue returns a detector that matches values against a regular expression
+func matchValue(pattern string) func(key, value string) bool {
+	re := regexp.MustCompile(pattern)
+	return func(key, value string) bool {
+		return re.MatchString(value)
+	}
+}
+
+// Values made only of these characters are checked for entropy, which leaves out text,
+// paths with spaces and most URLs
+var (
+	entropyCandidateRegex = regexp.MustCompile(`^[A-Za-z0-9+/=_.-]{20,}$`)
+	hexValueRegex         = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+)
+
+// isHighEntropy reports whether a value looks like a random token. Hex strings can have
+// at most 4 bits of entropy per character, so they get a lower threshold.
+func isHighEntropy(value string) bool {
+	if !entropyCandidateRegex.MatchString(value) || !strings.ContainsAny(value, "0123456789") {
+		return false
+	}
+	threshold := 4.0
+	if hexValueRegex.MatchString(value) {
+		threshold = 3.0
+	}
+	return shannonEntropy(value) >= threshold
+}
+
+// shannonEntropy returns the entropy of a string in bits per character
+func shannonEntropy(value string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range value {
+		counts[r]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// secretFinding is a variable that one or more detectors flagged
+type secretFinding struct {
+	File  string   `json:"file"`
+	Line  int      `json:"line"`
+	Key   string   `json:"key"`
+	Types []string `json:"types"`
+}
+
+// scanCmd is the scan command
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Look for secrets in a .env file",
+	Long: `Look for variables in a .env file that hold secrets: values that look like
+live credentials, such as AWS access key IDs (AKIA...), Google API keys
+(AIza...), GitHub, Slack and Stripe tokens, private key headers and other
+high-entropy strings, and variables with secret-like names.
+
+Each finding is reported with its line number and the kinds of secret detected;
+the values themselves are never printed. Masked values are skipped, and a fully
+encrypted file has nothing to scan. Use --allow for known false positives.
+Exits with a non-zero status when anything is found.
+
+push runs the same value checks and warns before uploading unencrypted values
+that look like credentials.`,
+	Args: cobra.NoArgs,
+	Run:  runScanCommand,
+}
+
+// InitScanCommand sets up the scan command
+func InitScanCommand() {
+	// Initialize the command flags
+	scanCmd.Flags().StringVarP(&scanEnvFile, "file", "f", ".env", "Path to the .env file to scan")
+	scanCmd.Flags().BoolVar(&scanSearchUp, "search-up", false, "Search parent directories for the nearest .env file")
+	scanCmd.Flags().StringSliceVar(&scanAllow, "allow", []string{}, "Variables to leave out of the report, for known false positives (comma-separated)")
+
+	// Add the scan command to the root command
+	rootCmd.AddCommand(scanCmd)
+}
+
+// runScanCommand handles the scan command execution
+func runScanCommand(cmd *cobra.Command, args []string) {
+	scanEnvFile = resolveEnvPath(scanEnvFile, scanSearchUp)
+
+	content, err := os.ReadFile(scanEnvFile)
+	if err != nil {
+		exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Could not read %s: %s", scanEnvFile, err))
+	}
+	if encryption.IsEncrypted(content) {
+		logInfo("%s is fully encrypted, so there is nothing to scan", scanEnvFile)
+	}
+
+	findings := scanEnvContent(scanEnvFile, content, secretDetectors, allowedKeys(scanAllow))
+
+	if jsonOutput {
+		printJSONResult(map[string]interface{}{"file": scanEnvFile, "findings": findings})
+	} else if len(findings) == 0 {
+		fmt.Printf("✓ No secrets found in %s\n", scanEnvFile)
+	} else {
+		for _, finding := range findings {
+			fmt.Printf("%s:%d: %s: %s\n", finding.File, finding.Line, finding.Key, strings.Join(finding.Types, ", "))
+		}
+		fmt.Printf("\n%d variables look like secrets. Push them with masking or encryption, or use --allow KEY for false positives.\n", len(findings))
+	}
+
+	if len(findings) > 0 {
+		os.Exit(1)
+	}
+}
+
+// allowedKeys turns an --allow list into a set
+func allowedKeys(keys []string) map[string]bool {
+	allowed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		allowed[strings.TrimSpace(key)] = true
+	}
+	return allowed
+}
+
+// scanEnvContent runs the detectors on every variable of .env content, in file order.
+// Empty and masked values, allowed keys and fully encrypted content are skipped.
+func scanEnvContent(name string, content []byte, detectors []secretDetector, allowed map[string]bool) []secretFinding {
+	var findings []secretFinding
+	if encryption.IsEncrypted(content) {
+		return findings
+	}
+
+	entries, _ := parseEnvEntries(content)
+	for _, entry := range entries {
+		value := strings.TrimSpace(entry.Value)
+		if isQuotedValue(value) {
+			value = value[1 : len(value)-1]
+		}
+		if value == "" || allowed[entry.Key] || strings.HasPrefix(value, encryption.MaskedPrefix) {
+			continue
+		}
+
+		var types []string
+		for _, detector := range detectors {
+			if detector.Match(entry.Key, value) {
+				types = append(types, detector.Name)
+			}
+		}
+		if len(types) > 0 {
+			findings = append(findings, secretFinding{File: name, Line: entry.Line, Key: entry.Key, Types: types})
+		}
+	}
+	return findings
+}
@@ -9,7 +9,6 @@ import (
 
 	"github.com/google/go-github/v37/github"
 	"github.com/spf13/cobra"
-	"golang.org/x/oauth2"
 
 	"github.com/dexterity-inc/envi/internal/config"
 	"github.com/dexterity-inc/envi/internal/encryption"
@@ -51,14 +50,13 @@ func runShareCommand(cmd *cobra.Command, args []string) {
 	// Get GitHub token
 	token, err := config.GetGitHubToken()
 	if err != nil {
-		fmt.Println("Error:", err)
-		os.Exit(1)
+		exitWithError(ErrCodeNoToken, err.Error())
 	}
 	
 	// Load config and apply defaults
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Warning: Could not load config: %s\n", err)
+		logWarn("Could not load config: %s", err)
 	} else {
 		applyEncryptionDefaults(cmd, cfg)
 	}
@@ -69,32 +67,29 @@ func runShareCommand(cmd *cobra.Command, args []string) {
 	// Prepare environment content if needed
 	envContent, err := prepareEnvContent()
 	if err != nil {
-		fmt.Println("Error: An issue occurred while preparing the environment content. Please check the input and try again.")
-		os.Exit(1)
+		exitWithError(ErrCodeGeneric, "An issue occurred while preparing the environment content. Please check the input and try again.")
 	}
 	
 	// Create GitHub client
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(cmd.Context(), ts)
-	client := github.NewClient(tc)
+	client := newGitHubClient(cmd.Context(), token)
 	
 	// Get user info
-	user, _, err := client.Users.Get(context.Background(), "")
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	user, _, err := client.Users.Get(ctx, "")
 	if err != nil {
-		fmt.Printf("Error getting GitHub user: %s\n", err)
-		os.Exit(1)
+		exitWithError(ErrCodeAPI, fmt.Sprintf("Could not get GitHub user: %s", apiError(err)))
 	}
 	
 	// Get Gist details
-	gist, _, err := client.Gists.Get(context.Background(), gistID)
+	gist, err := fetchGist(ctx, client, gistID)
 	if err != nil {
-		fmt.Printf("Error retrieving Gist with ID %s: %s\n", gistID, err)
-		os.Exit(1)
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not retrieve Gist with ID %s: %s", gistID, apiError(err)))
 	}
 	
 	// Handle sharing with users if specified
 	if len(shareWithUsers) > 0 {
-		shareWithGitHubUsers(client, user, gist, envContent)
+		shareWithGitHubUsers(ctx, client, user, gist, envContent)
 	}
 	
 	// Generate shareable URL if requested
@@ -111,11 +106,11 @@ func runShareCommand(cmd *cobra.Command, args []string) {
 
 // getGistID gets the Gist ID from flag or config
 func getGistID(cfg *config.Config) string {
+	shareGistID = resolveGistRef(shareGistID)
 	if shareGistID == "" {
 		if cfg.LastGistID == "" {
-			fmt.Println("Error: No Gist ID specified and no saved Gist ID found")
-			fmt.Println("Use 'envi share --id GIST_ID' or first push an .env file with 'envi push'")
-			os.Exit(1)
+			exitWithError(ErrCodeGeneric, "No Gist ID specified and no saved Gist ID found",
+				"Use 'envi share --id GIST_ID' or first push an .env file with 'envi push'")
 		}
 		shareGistID = cfg.LastGistID
 		fmt.Printf("Using saved Gist ID: %s\n", shareGistID)
@@ -143,7 +138,7 @@ func prepareEnvContent() ([]byte, error) {
 	
 	// Handle encryption options
 	if encryption.UseEncryption && encryption.UseMaskedEncryption {
-		fmt.Println("Warning: Both --encrypt and --mask flags specified. Using --mask (masked encryption).")
+		logWarn("Both --encrypt and --mask flags specified. Using --mask (masked encryption).")
 		encryption.UseEncryption = false
 	}
 	
@@ -169,11 +164,9 @@ func prepareEnvContent() ([]byte, error) {
 }
 
 // shareWithGitHubUsers shares env with specified GitHub users
-func shareWithGitHubUsers(client *github.Client, user *github.User, gist *github.Gist, envContent []byte) {
+func shareWithGitHubUsers(ctx context.Context, client *github.Client, user *github.User, gist *github.Gist, envContent []byte) {
 	fmt.Printf("Sharing .env with users: %s\n", strings.Join(shareWithUsers, ", "))
 	
-	ctx := context.Background()
-	
 	// Process each user
 	for _, username := range shareWithUsers {
 		// Create description with proper attribution
@@ -202,13 +195,13 @@ func shareWithGitHubUsers(client *github.Client, user *github.User, gist *github
 		}
 		
 		// Create the shared Gist
-		createdGist, _, err := client.Gists.Create(ctx, newGist)
+		createdGist, err := createGist(ctx, client, newGist)
 		if err != nil {
-			fmt.Printf("Error creating shared Gist for %s: %s\n", username, err)
+			fmt.Fprintf(os.Stderr, "Error creating shared Gist for %s: %s\n", username, apiError(err))
 			continue
 		}
 		
-		fmt.Printf("Successfully shared with %s: https://gist.github.com/%s\n", username, *createdGist.ID)
+		fmt.Printf("Successfully shared with %s: %s\n", username, gistWebURL(*createdGist.ID))
 	}
 }
 
@@ -223,7 +216,7 @@ func generateAndShowURL(client *github.Client, user *github.User, gist *github.G
 	// Create a message to show
 	sharingMessage := fmt.Sprintf("Shareable URL will expire on %s\n", expiryStr)
 	sharingMessage += "Anyone with this URL can access your .env file.\n"
-	sharingMessage += fmt.Sprintf("https://gist.github.com/%s\n", *gist.ID)
+	sharingMessage += gistWebURL(*gist.ID) + "\n"
 	
 	// Display message using TUI if enabled
 	if encryption.UseTUI {
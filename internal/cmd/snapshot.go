@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// This file contains the snapshots of what was last synced with each Gist. A snapshot
+// records a salted hash of every value instead of the value itself, which is enough to
+// tell whether a value changed since the last sync without storing any secrets.
+
+// snapshotSaltPrefix starts the comment line holding a snapshot's salt
+const snapshotSaltPrefix = "# salt: "
+
+// envSnapshot is the state of a Gist's .env at the last pull, push or merge
+type envSnapshot struct {
+	salt   []byte
+	hashes map[string]string // salted value hash by variable name
+	taken  time.Time
+}
+
+// snapshotDir returns the directory holding the snapshots
+func snapshotDir() (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "snapshots"), nil
+}
+
+// snapshotPath returns where the snapshot of a Gist is stored
+func snapshotPath(gistID string) (string, error) {
+	dir, err := snapshotDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(gistID)+".env"), nil
+}
+
+// hash returns the salted hash of a variable's value
+func (s *envSnapshot) hash(key, value string) string {
+	h := sha256.New()
+	h.Write(s.salt)
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// has reports whether the variable existed at the last sync
+func (s *envSnapshot) has(key string) bool {
+	_, ok := s.hashes[key]
+	return ok
+}
+
+// unchanged reports whether the variable existed at the last sync with this value
+func (s *envSnapshot) unchanged(key, value string) bool {
+	hash, ok := s.hashes[key]
+	return ok && hash == s.hash(key, value)
+}
+
+// changes counts the variables added, removed or changed since the last sync
+func (s *envSnapshot) changes(variables map[string]string) int {
+	count := 0
+	for key, value := range variables {
+		if !s.unchanged(key, value) {
+			count++
+		}
+	}
+	for key := range s.hashes {
+		if _, ok := variables[key]; !ok {
+			count++
+		}
+	}
+	return count
+}
+
+// saveSnapshot records plain .env content as the last synced state of a Gist. Encrypted
+// or masked content can't be compared later, so no snapshot is taken of it. Failures
+// only produce a warning, since the snapshot is a convenience.
+func saveSnapshot(gistID string, content []byte) {
+	if gistID == "" || encryption.IsEncrypted(content) || encryption.IsMasked(content) {
+		return
+	}
+
+	snapshot := &envSnapshot{salt: make([]byte, 16), hashes: make(map[string]string)}
+	if _, err := rand.Read(snapshot.salt); err != nil {
+		logWarn("Could not save sync snapshot: %s", err)
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# envi snapshot of Gist %s, taken %s\n", gistID, time.Now().Format(time.RFC3339))
+	b.WriteString("# Values are salted SHA-256 hashes; the snapshot holds no secrets.\n")
+	b.WriteString(snapshotSaltPrefix + hex.EncodeToString(snapshot.salt) + "\n")
+	entries, _ := parseEnvEntries(content)
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "%s=%s\n", entry.Key, snapshot.hash(entry.Key, entry.Value))
+	}
+
+	path, err := snapshotPath(gistID)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	if err == nil {
+		err = os.WriteFile(path, []byte(b.String()), 0600)
+	}
+	if err != nil {
+		logWarn("Could not save sync snapshot: %s", err)
+	}
+}
+
+// loadSnapshot reads the snapshot of a Gist, returning nil if there is none
+func loadSnapshot(gistID string) (*envSnapshot, error) {
+	path, err := snapshotPath(gistID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, comments := parseEnvEntries(content)
+	snapshot := &envSnapshot{hashes: make(map[string]string), taken: info.ModTime()}
+	for _, comment := range comments {
+		if strings.HasPrefix(comment, snapshotSaltPrefix) {
+			snapshot.salt, err = hex.DecodeString(strings.TrimPrefix(comment, snapshotSaltPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+			}
+		}
+	}
+	if snapshot.salt == nil {
+		return nil, fmt.Errorf("invalid snapshot %s: salt missing", path)
+	}
+	for _, entry := range entries {
+		snapshot.hashes[entry.Key] = entry.Value
+	}
+	return snapshot, nil
+}
+
+// syncedGistIDs returns the Gists with a snapshot on this machine, most recently synced first
+func syncedGistIDs() []string {
+	dir, err := snapshotDir()
+	if err != nil {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	taken := make(map[string]time.Time)
+	var gistIDs []string
+	for _, entry := range entries {
+		gistID := strings.TrimSuffix(entry.Name(), ".env")
+		info, err := entry.Info()
+		if entry.IsDir() || gistID == entry.Name() || err != nil {
+			continue
+		}
+		taken[gistID] = info.ModTime()
+		gistIDs = append(gistIDs, gistID)
+	}
+	sort.Slice(gistIDs, func(i, j int) bool { return taken[gistIDs[i]].After(taken[gistIDs[j]]) })
+	return gistIDs
+}
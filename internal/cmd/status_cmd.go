@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// Status command flags
+var (
+	statusGistID   string
+	statusEnvFile  string
+	statusSearchUp bool
+)
+
+// statusCmd is the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show local and remote .env status",
+	Long:  `Summarize the active Gist, the local .env file, and whether the two are in sync.`,
+	Run:   runStatusCommand,
+}
+
+// InitStatusCommand sets up the status command
+func InitStatusCommand() {
+	// Initialize the command flags
+	statusCmd.Flags().StringVarP(&statusGistID, "id", "i", "", "GitHub Gist ID to compare against (defaults to saved Gist)")
+	statusCmd.Flags().StringVarP(&statusEnvFile, "file", "f", ".env", "Path to the local .env file")
+	statusCmd.Flags().BoolVar(&statusSearchUp, "search-up", false, "Search parent directories for the nearest .env file")
+
+	// Add the status command to the root command
+	rootCmd.AddCommand(statusCmd)
+}
+
+// runStatusCommand handles the status command execution
+func runStatusCommand(cmd *cobra.Command, args []string) {
+	// Load config (local information only, never fatal)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logWarn("Could not load config: %s", err)
+	}
+
+	// Collected for --json output as the checks run
+	result := map[string]interface{}{}
+	defer printJSONResult(result)
+
+	// Counts remote changes since the last sync once the remote values are known
+	compareWithSnapshot := func(remoteVars map[string]string) {}
+
+	// Resolve the active Gist ID
+	gistID := resolveGistRef(statusGistID)
+	gistStatus := "Not set"
+	if gistID == "" && cfg != nil && cfg.LastGistID != "" {
+		gistID = cfg.LastGistID
+		gistStatus = gistID + " (saved)"
+	} else if gistID != "" {
+		gistStatus = gistID
+	}
+	fmt.Printf("Gist ID:      %s\n", gistStatus)
+	result["gist_id"] = gistID
+
+	// Check the local .env file
+	statusEnvFile = resolveEnvPath(statusEnvFile, statusSearchUp)
+	result["local_file"] = statusEnvFile
+	var localVars map[string]string
+	if _, err := os.Stat(statusEnvFile); os.IsNotExist(err) {
+		fmt.Printf("Local file:   %s not found\n", statusEnvFile)
+	} else {
+		localVars, _, err = parseEnvFile(statusEnvFile)
+		if err != nil {
+			fmt.Printf("Local file:   %s could not be read: %s\n", statusEnvFile, err)
+		} else {
+			fmt.Printf("Local file:   %s (%d variables)\n", statusEnvFile, len(localVars))
+			result["local_variables"] = len(localVars)
+		}
+	}
+
+	// Compare the local file with the last pull or push of the Gist. The remote side
+	// is filled in below when its values can be read.
+	if gistID != "" {
+		snapshot, err := loadSnapshot(gistID)
+		localChanges, remoteChanges := -1, -1
+		switch {
+		case err != nil:
+			logWarn("Could not read the last synced state: %s", err)
+		case snapshot != nil && localVars != nil:
+			localChanges = snapshot.changes(localVars)
+			result["local_changes"] = localChanges
+		}
+		if snapshot != nil {
+			result["last_sync"] = snapshot.taken.Format(time.RFC3339)
+			defer func() {
+				fmt.Printf("Last sync:    %s%s\n", snapshot.taken.Local().Format("2006-01-02 15:04"), syncChanges(localChanges, remoteChanges))
+			}()
+		} else if err == nil {
+			defer fmt.Println("Last sync:    unknown (pull or push to record it)")
+		}
+		compareWithSnapshot = func(remoteVars map[string]string) {
+			if snapshot != nil {
+				remoteChanges = snapshot.changes(remoteVars)
+				result["remote_changes"] = remoteChanges
+			}
+		}
+	}
+
+	// Report the token source
+	token, tokenSource, tokenErr := config.ResolveGitHubToken()
+	if tokenErr != nil {
+		fmt.Printf("Token source: none (%s)\n", tokenErr)
+	} else {
+		fmt.Printf("Token source: %s\n", tokenSource)
+		result["token_source"] = tokenSource
+	}
+
+	// Remote checks require both a Gist ID and a token
+	if gistID == "" || tokenErr != nil {
+		fmt.Println("Remote:       not checked")
+		result["remote"] = "not_checked"
+		return
+	}
+
+	// Create GitHub client
+	client := newGitHubClient(cmd.Context(), token)
+
+	// Get Gist
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	gist, err := fetchGist(ctx, client, gistID)
+	if err != nil {
+		fmt.Println("Remote:       remote unavailable")
+		result["remote"] = "unavailable"
+		return
+	}
+
+	// Find .env file in Gist
+	remoteContent, err := getGistEnvContent(gist)
+	if err != nil {
+		fmt.Println("Remote:       no .env file in Gist")
+		result["remote"] = "no_env_file"
+		return
+	}
+
+	// Report the encryption mode and compare with the local file
+	switch {
+	case encryption.IsEncrypted(remoteContent):
+		fmt.Println("Remote:       .env found (full encryption)")
+		fmt.Println("Sync:         unknown (remote content is fully encrypted)")
+		result["remote"] = "full_encryption"
+		result["sync"] = "unknown"
+		return
+	case encryption.IsMasked(remoteContent):
+		fmt.Println("Remote:       .env found (masked encryption)")
+		result["remote"] = "masked_encryption"
+	default:
+		fmt.Println("Remote:       .env found (no encryption)")
+		result["remote"] = "no_encryption"
+		remoteVars, _ := parseEnvContent(remoteContent)
+		compareWithSnapshot(remoteVars)
+	}
+
+	if localVars == nil {
+		fmt.Println("Sync:         unknown (no local file)")
+		result["sync"] = "unknown"
+		return
+	}
+
+	// Masked values can't be compared without decrypting, so only compare keys
+	remoteVars, _ := parseEnvContent(remoteContent)
+	compareValues := !encryption.IsMasked(remoteContent)
+	diff := compareEnvVars(localVars, remoteVars, compareValues)
+	result["differences"] = diff.Count()
+
+	if diff.Count() == 0 {
+		result["sync"] = "in_sync"
+		if compareValues {
+			fmt.Println("Sync:         in sync")
+		} else {
+			fmt.Println("Sync:         in sync (keys only, values are masked)")
+		}
+		return
+	}
+
+	result["sync"] = "differences"
+	fmt.Printf("Sync:         %d differences (%d only local, %d only remote, %d changed)\n",
+		diff.Count(), len(diff.OnlyLocal), len(diff.OnlyRemote), len(diff.Changed))
+}
+
+// syncChanges describes the changes on each side since the last sync. A count of -1
+// means that side couldn't be compared.
+func syncChanges(localChanges, remoteChanges int) string {
+	var parts []string
+	if localChanges >= 0 {
+		parts = append(parts, fmt.Sprintf("local has %d uncommitted change(s)", localChanges))
+	}
+	if remoteChanges >= 0 {
+		parts = append(parts, fmt.Sprintf("remote has %d new change(s)", remoteChanges))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "; " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/pkg/envi"
+)
+
+// Template command flags
+var (
+	templateInput    string
+	templateOutput   string
+	templateGistID   string
+	templateEnvFile  string
+	templateSearchUp bool
+	templateMissing  string
+)
+
+// Ways to handle template keys with no variable
+const (
+	missingKeyError = "error"
+	missingKeyEmpty = "empty"
+)
+
+// templateCmd renders a file template with env variables
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Render a file template with env variables",
+	Long: `Render a Go text/template file, such as nginx.conf.tmpl, with the variables from
+a local .env file or a Gist. Variables are referenced by name, e.g. {{ .API_KEY }},
+and all text/template actions are available, such as {{ if .DEBUG }}...{{ end }}.
+
+Variables come from the local .env file unless --id is given. Masked or encrypted
+Gist content is decrypted in memory before rendering. Quoted values are rendered
+without their quotes, so NAME="a b" renders as a b.
+
+By default a template that references a missing variable is an error. Use
+--missing empty to render missing variables as empty strings instead.
+
+The output is written with mode 0600, since it usually contains secrets.`,
+	Args: cobra.NoArgs,
+	Run:  runTemplateCommand,
+}
+
+// InitTemplateCommand sets up the template command
+func InitTemplateCommand() {
+	// Initialize the command flags
+	templateCmd.Flags().StringVarP(&templateInput, "input", "t", "", "Path to the template file")
+	templateCmd.Flags().StringVarP(&templateOutput, "output", "o", "-", "Path to write the rendered file (- for stdout)")
+	templateCmd.Flags().StringVarP(&templateGistID, "id", "i", "", "GitHub Gist ID or @BOOKMARK to read variables from instead of a local file")
+	templateCmd.Flags().StringVarP(&templateEnvFile, "file", "f", ".env", "Path to the local .env file")
+	templateCmd.Flags().BoolVar(&templateSearchUp, "search-up", false, "Search parent directories for the nearest .env file")
+	templateCmd.Flags().StringVar(&templateMissing, "missing", missingKeyError, "What to do with missing variables: error or empty")
+	templateCmd.MarkFlagRequired("input")
+
+	// Add the template command to the root command
+	rootCmd.AddCommand(templateCmd)
+}
+
+// runTemplateCommand handles the template command execution
+func runTemplateCommand(cmd *cobra.Command, args []string) {
+	// Keep stdout clean for the rendered file
+	toStdout := templateOutput == "-"
+	if toStdout {
+		routeInfoToStderr()
+	}
+
+	var missingOption string
+	switch templateMissing {
+	case missingKeyError:
+		missingOption = "missingkey=error"
+	case missingKeyEmpty:
+		missingOption = "missingkey=zero"
+	default:
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Unknown --missing value %q (use error or empty)", templateMissing))
+	}
+
+	// Parse the template before fetching anything, so mistakes show up early
+	tmplContent, err := os.ReadFile(templateInput)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not read template %s: %s", templateInput, err))
+	}
+	tmpl, err := template.New(templateInput).Option(missingOption).Parse(string(tmplContent))
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Invalid template: %s", err))
+	}
+
+	var variables map[string]string
+	source := templateEnvFile
+	if templateGistID != "" {
+		variables, source = templateGistVariables(cmd)
+	} else {
+		source = resolveEnvPath(templateEnvFile, templateSearchUp)
+		variables, _, err = parseEnvFile(source)
+		if err != nil {
+			exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("Could not read %s: %s", source, err))
+		}
+	}
+	
+	// Templates get the values themselves, without their dotenv quoting
+	for key, value := range variables {
+		variables[key] = envi.Unquote(value)
+	}
+
+	// Render in memory, so a failed render never leaves a partial file
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, variables); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not render template: %s", err),
+			"Add the variable, or use '--missing empty' to render missing variables as empty")
+	}
+
+	if toStdout {
+		resultStdout.Write(rendered.Bytes())
+		return
+	}
+
+	if err := os.WriteFile(templateOutput, rendered.Bytes(), 0600); err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not write %s: %s", templateOutput, err))
+	}
+
+	fmt.Printf("Rendered %s into %s using %d variables from %s\n", templateInput, templateOutput, len(variables), source)
+
+	printJSONResult(map[string]interface{}{
+		"template":  templateInput,
+		"output":    templateOutput,
+		"source":    source,
+		"variables": len(variables),
+	})
+}
+
+// templateGistVariables fetches the variables from the Gist given with --id, decrypting
+// them in memory, and returns them with a description of where they came from
+func templateGistVariables(cmd *cobra.Command) (map[string]string, string) {
+	token, err := config.GetGitHubToken()
+	if err != nil {
+		exitWithError(ErrCodeNoToken, err.Error())
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logWarn("Could not load config: %s", err)
+	} else {
+		applyEncryptionDefaults(cmd, cfg)
+	}
+
+	gistID := resolveGistRef(templateGistID)
+
+	client := newGitHubClient(cmd.Context(), token)
+
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	gist, err := fetchGist(ctx, client, gistID)
+	if err != nil {
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not retrieve Gist with ID %s: %s", gistID, apiError(err)))
+	}
+
+	content, err := getGistEnvContent(gist)
+	if err != nil {
+		exitWithError(ErrCodeNoEnvFile, err.Error())
+	}
+
+	if encryption.IsEncrypted(content) || encryption.IsMasked(content) {
+		content, err = decryptEnvContent(content)
+		if err != nil {
+			exitOnKeyFileError(err)
+			exitWithError(ErrCodeDecryptFailed, "Could not decrypt content. Please check the encryption key or password and try again.")
+		}
+	}
+
+	variables, _ := parseEnvContent(content)
+	return variables, "Gist " + gistID
+}
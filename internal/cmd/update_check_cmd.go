@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+	"golang.org/x/term"
+
+	"github.com/dexterity-inc/envi/internal/config"
+	"github.com/dexterity-inc/envi/internal/logging"
+	"github.com/dexterity-inc/envi/internal/version"
+)
+
+// The repository envi is released from
+const (
+	releaseOwner = "dexterity-inc"
+	releaseRepo  = "envi"
+)
+
+// releasesAPIURL is the API the latest release is looked up with. Releases are always
+// on public GitHub, whatever --github-url says.
+var releasesAPIURL = "https://api.github.com/"
+
+const (
+	// defaultUpdateCheckInterval is how often the background check runs unless
+	// update_check_interval says otherwise
+	defaultUpdateCheckInterval = 24 * time.Hour
+
+	// minUpdateCheckInterval keeps the background check from using up the limit GitHub
+	// puts on requests without a token
+	minUpdateCheckInterval = time.Hour
+
+	// backgroundCheckTimeout is the longest the background check may delay a command,
+	// so being offline or on a slow network never gets in the way
+	backgroundCheckTimeout = 2 * time.Second
+)
+
+// updateCheckCmd is the update-check command
+var updateCheckCmd = &cobra.Command{
+	Use:   "update-check",
+	Short: "Check whether a newer version of envi is available",
+	Long: `Look up the latest envi release on GitHub and show whether it is newer than the
+installed version, with a link to the release. envi never updates itself; install
+the new version the same way as the current one.
+
+Checks can also run in the background while other commands run, at most once a
+day. They are off by default; turn them on with 'envi config set update_check
+true' and change how often they run with update_check_interval, e.g. 168h for
+once a week. The background check gives up quietly when GitHub can't be reached and is
+skipped with --json, --quiet or when stderr isn't a terminal.`,
+	Args: cobra.NoArgs,
+	Run:  runUpdateCheckCommand,
+}
+
+// InitUpdateCheckCommand sets up the update-check command
+func InitUpdateCheckCommand() {
+	// Add the update-check command to the root command
+	rootCmd.AddCommand(updateCheckCmd)
+}
+
+// runUpdateCheckCommand handles the update-check command execution
+func runUpdateCheckCommand(cmd *cobra.Command, args []string) {
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+
+	var release *github.RepositoryRelease
+	err := withSpinner("Checking for updates...", func() error {
+		var err error
+		release, err = latestRelease(ctx)
+		return err
+	})
+	if err != nil {
+		if errResp, ok := err.(*github.ErrorResponse); ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+			exitWithError(ErrCodeAPI, "No envi releases were found on GitHub")
+		}
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not check for updates: %s", apiError(err)),
+			"Check your network connection, or use --timeout to allow more time")
+	}
+	saveUpdateCheckTime(time.Now())
+
+	current := version.GetVersion()
+	latest := release.GetTagName()
+	available := newerVersion(latest, current)
+	printJSONResult(map[string]interface{}{
+		"current":         current,
+		"latest":          latest,
+		"updateAvailable": available,
+		"url":             release.GetHTMLURL(),
+	})
+	if jsonOutput {
+		return
+	}
+
+	switch {
+	case current == "dev":
+		fmt.Printf("This is a development build; the latest release is %s\n", latest)
+		fmt.Printf("Release: %s\n", release.GetHTMLURL())
+	case available:
+		fmt.Printf("A new version of envi is available: %s (you have %s)\n", latest, versionName(current))
+		fmt.Printf("Release: %s\n", release.GetHTMLURL())
+	default:
+		fmt.Printf("envi %s is up to date\n", versionName(current))
+	}
+
+	if cfg, err := config.LoadConfig(); err == nil && !cfg.UpdateCheck {
+		logInfo("Run 'envi config set update_check true' to check for updates in the background")
+	}
+}
+
+// latestRelease looks up the latest envi release. The request is made without a token,
+// which is never sent anywhere but the configured GitHub.
+func latestRelease(ctx context.Context) (*github.RepositoryRelease, error) {
+	// The HTTP client set up for --verbose, if any
+	httpClient, _ := ctx.Value(oauth2.HTTPClient).(*http.Client)
+	client := github.NewClient(httpClient)
+	baseURL, err := url.Parse(releasesAPIURL)
+	if err != nil {
+		return nil, err
+	}
+	client.BaseURL = baseURL
+
+	release, _, err := client.Repositories.GetLatestRelease(ctx, releaseOwner, releaseRepo)
+	return release, err
+}
+
+// versionName returns a version as release tags name it, e.g. v1.4.0
+func versionName(v string) string {
+	return "v" + strings.TrimPrefix(v, "v")
+}
+
+// parseVersion splits a version such as v1.4.0 or 1.5.0-rc.1 into its numbers and
+// pre-release part. Missing minor and patch numbers count as 0.
+func parseVersion(v string) ([3]int, string, bool) {
+	var numbers [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+") // Build metadata doesn't affect the order
+	v, pre, _ := strings.Cut(v, "-")
+
+	parts := strings.Split(v, ".")
+	if len(parts) > len(numbers) {
+		return numbers, "", false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return numbers, "", false
+		}
+		numbers[i] = n
+	}
+	return numbers, pre, true
+}
+
+// newerVersion reports whether latest is a newer version than current. A release is
+// newer than its own pre-releases. Versions that can't be compared, such as
+// a development build, are never reported as out of date.
+func newerVersion(latest, current string) bool {
+	latestNumbers, latestPre, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	currentNumbers, currentPre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+
+	for i := range latestNumbers {
+		if latestNumbers[i] != currentNumbers[i] {
+			return latestNumbers[i] > currentNumbers[i]
+		}
+	}
+	return currentPre != "" && latestPre == ""
+}
+
+// updateCheckStatePath returns the file recording when updates were last checked for
+func updateCheckStatePath() (string, error) {
+	dataDir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "update-check"), nil
+}
+
+// lastUpdateCheck returns when updates were last checked for, or the zero time if never
+func lastUpdateCheck() time.Time {
+	path, err := updateCheckStatePath()
+	if err != nil {
+		return time.Time{}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}
+	}
+	checked, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}
+	}
+	return checked
+}
+
+// saveUpdateCheckTime records when updates were checked for. Failing to record it only
+// means the next check comes sooner, so errors are just logged.
+func saveUpdateCheckTime(checked time.Time) {
+	path, err := updateCheckStatePath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	if err == nil {
+		err = os.WriteFile(path, []byte(checked.UTC().Format(time.RFC3339)+"\n"), 0600)
+	}
+	if err != nil {
+		logging.Debug("Could not record the update check", "error", err)
+	}
+}
+
+// updateCheckInterval returns how often the background check runs
+func updateCheckInterval(cfg *config.Config) time.Duration {
+	if interval, err := time.ParseDuration(cfg.UpdateCheckInterval); err == nil && interval >= minUpdateCheckInterval {
+		return interval
+	}
+	return defaultUpdateCheckInterval
+}
+
+// updateCheckIntervalProblem describes what is wrong with an update_check_interval
+// setting, or returns "" if nothing is
+func updateCheckIntervalProblem(value string) string {
+	if value == "" {
+		return ""
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Sprintf("%q should be a duration such as 24h or 168h", value)
+	}
+	if interval < minUpdateCheckInterval {
+		return fmt.Sprintf("%q is too short; checks can run at most once an hour", value)
+	}
+	return ""
+}
+
+// backgroundUpdateCheck is the check started by startUpdateCheck, if any
+var backgroundUpdateCheck chan *github.RepositoryRelease
+
+// startUpdateCheck starts looking up the latest release while cmd runs, if background
+// checks are turned on and the interval has passed since the last check. Nothing runs
+// for commands whose output is read by scripts or shells.
+func startUpdateCheck(cmd *cobra.Command) {
+	if cmd == updateCheckCmd || cmd.Name() == "completion" || strings.HasPrefix(cmd.Name(), "__") ||
+		jsonOutput || quietOutput || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return
+	}
+	if _, _, ok := parseVersion(version.GetVersion()); !ok {
+		return // Development builds can't be compared with releases
+	}
+
+	// Only an existing config can turn checks on, and reading it has no side effects
+	configPath, err := config.ConfigPath()
+	if err != nil {
+		return
+	}
+	cfg, err := config.ReadConfig(configPath)
+	if err != nil || !cfg.UpdateCheck || time.Since(lastUpdateCheck()) < updateCheckInterval(cfg) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), backgroundCheckTimeout)
+	backgroundUpdateCheck = make(chan *github.RepositoryRelease, 1)
+	go func() {
+		defer cancel()
+		release, err := latestRelease(ctx)
+		if err != nil {
+			logging.Debug("Background update check failed", "error", err)
+			release = nil
+		}
+		backgroundUpdateCheck <- release
+	}()
+}
+
+// finishUpdateCheck waits briefly for the background check started by startUpdateCheck
+// and mentions a newer release on stderr. Whatever the outcome, the next check waits for
+// the interval to pass again, so nobody is told more than once per interval and being
+// offline doesn't slow down every command.
+func finishUpdateCheck() {
+	if backgroundUpdateCheck == nil {
+		return
+	}
+
+	var release *github.RepositoryRelease
+	select {
+	case release = <-backgroundUpdateCheck:
+	case <-time.After(backgroundCheckTimeout):
+		logging.Debug("Background update check timed out")
+	}
+	backgroundUpdateCheck = nil
+	saveUpdateCheckTime(time.Now())
+
+	if release != nil && newerVersion(release.GetTagName(), version.GetVersion()) {
+		fmt.Fprintf(os.Stderr, "\nA new version of envi is available: %s (you have %s)\n", release.GetTagName(), versionName(version.GetVersion()))
+		fmt.Fprintf(os.Stderr, "Release: %s\n", release.GetHTMLURL())
+	}
+}
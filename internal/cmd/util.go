@@ -1,10 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"github.com/dexterity-inc/envi/internal/config"
 	"github.com/dexterity-inc/envi/internal/encryption"
+	"github.com/dexterity-inc/envi/internal/tui"
 )
 
 // This file contains utility functions for the cmd package 
@@ -25,4 +36,204 @@ func createSharingReadmeContent(user *github.User, recipientUsername string) str
 	readmeContent += "Shared using [envi](https://github.com/dexterity-inc/envi), an open-source environment variable manager"
 	
 	return readmeContent
-} 
\ No newline at end of file
+} 
+
+// errNotInteractive is returned by prompts when there is nobody to answer them
+var errNotInteractive = errors.New("interactive confirmation required, but stdin is not a terminal; pass --yes or --no")
+
+// assumeYes and assumeNo answer every confirmation without asking (--yes and --no)
+var (
+	assumeYes bool
+	assumeNo  bool
+)
+
+// promptAnswered reports whether --yes or --no already answers every confirmation, so
+// commands that can't ask, for example in JSON mode, still follow the answer
+func promptAnswered() bool {
+	return assumeYes || assumeNo
+}
+
+// checkStdinSecretFlags exits if both the password and the key are to be read from stdin
+func checkStdinSecretFlags() {
+	if encryption.PasswordFromStdin && encryption.KeyFromStdin {
+		exitWithError(ErrCodeGeneric, "--password-stdin and --key-stdin can't be used together, since both read stdin")
+	}
+}
+
+// stdinIsTerminal reports whether prompts can be answered. When stdin is a pipe or file,
+// as in CI, a prompt would read a wrong answer or wait forever for one.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// confirmPrompt asks a yes/no question, using the TUI when enabled and a plain prompt
+// otherwise. --yes and --no answer it without asking; without them and a terminal it
+// returns errNotInteractive instead of asking.
+func confirmPrompt(title, message string) (bool, error) {
+	if assumeYes {
+		logInfo("%s Yes (--yes)", message)
+		return true, nil
+	}
+	if assumeNo {
+		logInfo("%s No (--no)", message)
+		return false, nil
+	}
+	if !stdinIsTerminal() {
+		return false, errNotInteractive
+	}
+	if encryption.UseTUI {
+		return tui.Confirm(title, message)
+	}
+	
+	fmt.Printf("%s (y/N) ", message)
+	var response string
+	fmt.Scanln(&response)
+	return strings.ToLower(response) == "y", nil
+}
+
+// showValues disables redaction of variable values in command output
+var showValues bool
+
+// redact hides a value, showing only its first and last 2 characters. It counts
+// runes, so a multi-byte character is never split.
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	runes := []rune(value)
+	if len(runes) < 8 {
+		return "****"
+	}
+	return string(runes[:2]) + "****" + string(runes[len(runes)-2:])
+}
+
+// displayValue returns a value for printing, redacted unless --show-values was set
+func displayValue(value string) string {
+	if showValues {
+		return value
+	}
+	return redact(value)
+}
+
+// secureWipeFile overwrites a file with zeros before removing it, so plaintext
+// secrets are not left behind in the freed disk blocks
+func secureWipeFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	
+	if _, err := file.Write(make([]byte, info.Size())); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	
+	return os.Remove(path)
+}
+
+// requestTimeout bounds how long a command waits on the GitHub API
+var requestTimeout time.Duration
+
+// apiContext returns the command's context with the --timeout deadline applied.
+// Call it right before the first GitHub request so prompts don't use up the time.
+func apiContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	if requestTimeout <= 0 {
+		return context.WithCancel(cmd.Context())
+	}
+	return context.WithTimeout(cmd.Context(), requestTimeout)
+}
+
+// apiError replaces a context deadline error with a readable timeout message
+func apiError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("GitHub did not respond within %s (use --timeout to allow more time)", requestTimeout)
+	}
+	if contentTooLarge(err) {
+		return fmt.Errorf("GitHub rejected the content as too large for a Gist; move large values such as certificates out of the file (%w)", err)
+	}
+	return err
+}
+
+// contentTooLarge reports whether GitHub rejected a request because the content was too big
+func contentTooLarge(err error) bool {
+	errResp, ok := err.(*github.ErrorResponse)
+	if !ok || errResp.Response == nil {
+		return false
+	}
+	switch errResp.Response.StatusCode {
+	case http.StatusRequestEntityTooLarge:
+		return true
+	case http.StatusUnprocessableEntity:
+		return strings.Contains(strings.ToLower(errResp.Error()), "too large") ||
+			strings.Contains(strings.ToLower(errResp.Error()), "too_large")
+	}
+	return false
+}
+
+// parseByteSize parses a size such as 512KB, 1MB or 2048 (bytes). Units are binary,
+// so 1KB is 1024 bytes.
+func parseByteSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 512KB, 1MB or a number of bytes)", value)
+	}
+	return n * multiplier, nil
+}
+
+// formatByteSize formats a size in bytes for messages, e.g. 1.5 MB
+func formatByteSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// peekConfig reads the config without creating, moving or checking it, returning nil
+// if there is none or it can't be read. It is for callers that must not change anything
+// or print warnings, such as shell completion.
+func peekConfig() *config.Config {
+	path, err := config.ConfigPath()
+	if err != nil {
+		return nil
+	}
+	cfg, err := config.ReadConfig(path)
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+// noSaveGistID reports whether a Gist used by push or pull should not become the
+// default: --no-save-id decides when given, otherwise the no_save_id config setting
+func noSaveGistID(cmd *cobra.Command, cfg *config.Config, noSaveID bool) bool {
+	if cmd.Flags().Changed("no-save-id") {
+		return noSaveID
+	}
+	return cfg.NoSaveID
+}
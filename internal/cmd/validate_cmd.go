@@ -4,24 +4,57 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+// placeholderRegex matches values that were obviously copied from an example and never filled in
+var placeholderRegex = regexp.MustCompile(`(?i)^(changeme|change_me|change-me|x{3,}|todo|placeholder|your[_-].*[_-]here|<.*>)$`)
+
 // Validate command flags
 var (
 	validateFix         bool
 	validateStrict      bool
 	validateRequired    []string
+	validateEnvFile     string
+	validateSearchUp    bool
+	validateNoPlaceholders bool
+	validateExamples    []string
+	validateRecursive   bool
+	validateMaxDepth    int
+	validateStrictKeys  bool
+	validateKeyPattern  string
 )
 
+// validateKeyRegex is the compiled --key-pattern, set when --strict-keys is used
+var validateKeyRegex *regexp.Regexp
+
+// validateSkipDirs are never searched by validate --recursive
+var validateSkipDirs = map[string]bool{
+	"node_modules": true,
+	".git":         true,
+}
+
 // validateCmd is the validation command
 var validateCmd = &cobra.Command{
-	Use:   "validate",
+	Use:   "validate [DIR]",
 	Short: "Validate .env file against .env.example",
-	Long:  `Compare your project's .env file with .env.example to identify missing variables.`,
+	Long: `Compare your project's .env file with .env.example to identify missing variables.
+
+Use --example more than once to validate against several example files, e.g. a
+shared .env.example plus service-specific examples. The .env file must then contain
+the keys of all of them.
+
+With --recursive, every directory under DIR (default the current directory) that
+contains a .env.example is validated against the .env next to it, and a summary is
+printed per directory. node_modules and .git are skipped. A directory fails if its
+.env is missing, lacks variables from the example, or fails any other check.`,
+	Args:  cobra.MaximumNArgs(1),
 	Run:   runValidateCommand,
 }
 
@@ -31,6 +64,14 @@ func InitValidateCommand() {
 	validateCmd.Flags().BoolVar(&validateFix, "fix", false, "Fix missing variables by adding them to .env file")
 	validateCmd.Flags().BoolVarP(&validateStrict, "strict", "s", false, "Use strict validation (no empty values)")
 	validateCmd.Flags().StringSliceVar(&validateRequired, "required", []string{}, "Required variables (comma-separated)")
+	validateCmd.Flags().StringVarP(&validateEnvFile, "file", "f", ".env", "Path to the .env file to validate")
+	validateCmd.Flags().BoolVar(&validateSearchUp, "search-up", false, "Search parent directories for the nearest .env file")
+	validateCmd.Flags().StringArrayVar(&validateExamples, "example", []string{}, "Example file to validate against; repeat for several (default .env.example next to the .env file)")
+	validateCmd.Flags().BoolVar(&validateNoPlaceholders, "no-placeholders", false, "Fail on values left as .env.example placeholders")
+	validateCmd.Flags().BoolVarP(&validateRecursive, "recursive", "r", false, "Validate every directory under DIR that has a .env.example")
+	validateCmd.Flags().IntVar(&validateMaxDepth, "max-depth", 5, "How many directory levels below DIR --recursive searches")
+	validateCmd.Flags().BoolVar(&validateStrictKeys, "strict-keys", false, "Fail on keys that don't match --key-pattern")
+	validateCmd.Flags().StringVar(&validateKeyPattern, "key-pattern", defaultKeyPattern, "Regular expression keys must match with --strict-keys")
 
 	// Add the validate command to the root command
 	rootCmd.AddCommand(validateCmd)
@@ -38,36 +79,185 @@ func InitValidateCommand() {
 
 // runValidateCommand handles the validate command execution
 func runValidateCommand(cmd *cobra.Command, args []string) {
-	envFile := ".env"
-	exampleFile := ".env.example"
+	if validateStrictKeys {
+		validateKeyRegex = compileKeyPattern(validateKeyPattern)
+	}
 
-	// Check if .env.example file exists
-	if _, err := os.Stat(exampleFile); os.IsNotExist(err) {
-		fmt.Printf("Error: %s file not found\n", exampleFile)
-		fmt.Println("An example environment file is required for validation")
-		os.Exit(1)
+	if validateRecursive {
+		runRecursiveValidate(cmd, args)
+		return
+	}
+	if len(args) > 0 {
+		exitWithError(ErrCodeGeneric, "A directory can only be given with --recursive", "Use --file to validate a single .env file")
+	}
+
+	envFile := resolveEnvPath(validateEnvFile, validateSearchUp)
+	exampleFiles := validateExamples
+	if len(exampleFiles) == 0 {
+		exampleFiles = []string{filepath.Join(filepath.Dir(envFile), ".env.example")}
+	}
+
+	// Check if the example files exist
+	for _, exampleFile := range exampleFiles {
+		if _, err := os.Stat(exampleFile); os.IsNotExist(err) {
+			exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("%s file not found", exampleFile), "An example environment file is required for validation")
+		}
 	}
 
 	// Check if .env file exists
 	if _, err := os.Stat(envFile); os.IsNotExist(err) {
-		fmt.Printf("Error: %s file not found\n", envFile)
-		fmt.Println("Create a .env file first or copy from .env.example")
-		os.Exit(1)
+		exitWithError(ErrCodeNoEnvFile, fmt.Sprintf("%s file not found", envFile), "Create a .env file first or copy from .env.example")
 	}
 
-	// Parse the current .env file
-	currentVars, currentComments, err := parseEnvFile(envFile)
+	result, err := validateAgainstExamples(envFile, exampleFiles)
 	if err != nil {
-		fmt.Printf("Error reading %s: %s\n", envFile, err)
+		exitWithError(ErrCodeGeneric, err.Error())
+	}
+	if !result.checksOK {
 		os.Exit(1)
 	}
+}
+
+// runRecursiveValidate validates every directory with a .env.example below the given
+// directory and prints a summary, exiting non-zero if any failed
+func runRecursiveValidate(cmd *cobra.Command, args []string) {
+	for _, name := range []string{"file", "search-up", "example"} {
+		if cmd.Flags().Changed(name) {
+			exitWithError(ErrCodeGeneric, fmt.Sprintf("--%s can't be used with --recursive", name),
+				"Each directory's .env is validated against the .env.example next to it")
+		}
+	}
 
-	// Parse the reference .env.example file
-	referenceVars, _, err := parseEnvFile(exampleFile)
+	root := "."
+	if len(args) > 0 {
+		root = args[0]
+	}
+	dirs, err := findExampleDirs(root, validateMaxDepth)
 	if err != nil {
-		fmt.Printf("Error reading %s: %s\n", exampleFile, err)
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not search %s: %s", root, err))
+	}
+	if len(dirs) == 0 {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("No .env.example files found under %s", root),
+			"Use --max-depth to search deeper")
+	}
+
+	// Validate each directory, collecting one summary line per directory
+	var summary []string
+	failed := 0
+	for i, dir := range dirs {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("== %s ==\n", dir)
+
+		envFile := filepath.Join(dir, ".env")
+		if _, err := os.Stat(envFile); os.IsNotExist(err) {
+			fmt.Printf("❌ %s file not found\n", envFile)
+			summary = append(summary, fmt.Sprintf("  ✗ %s: no .env file", dir))
+			failed++
+			continue
+		}
+
+		result, err := validateAgainstExamples(envFile, []string{filepath.Join(dir, ".env.example")})
+		switch {
+		case err != nil:
+			fmt.Printf("❌ %s\n", err)
+			summary = append(summary, fmt.Sprintf("  ✗ %s: %s", dir, err))
+			failed++
+		case result.missing > 0:
+			summary = append(summary, fmt.Sprintf("  ✗ %s: %d missing variables", dir, result.missing))
+			failed++
+		case !result.checksOK:
+			summary = append(summary, fmt.Sprintf("  ✗ %s: checks failed", dir))
+			failed++
+		case result.extra > 0:
+			summary = append(summary, fmt.Sprintf("  ✓ %s: %d variables, %d not in .env.example", dir, result.vars, result.extra))
+		default:
+			summary = append(summary, fmt.Sprintf("  ✓ %s: %d variables", dir, result.vars))
+		}
+	}
+
+	fmt.Println("\nSummary:")
+	for _, line := range summary {
+		fmt.Println(line)
+	}
+	fmt.Printf("\n%d of %d directories passed\n", len(dirs)-failed, len(dirs))
+
+	if failed > 0 {
 		os.Exit(1)
 	}
+}
+
+// findExampleDirs returns the directories under root, up to maxDepth levels below it,
+// that contain a .env.example, in walk order
+func findExampleDirs(root string, maxDepth int) ([]string, error) {
+	root = filepath.Clean(root)
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			if entry.Name() == ".env.example" {
+				dirs = append(dirs, filepath.Dir(path))
+			}
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if validateSkipDirs[entry.Name()] {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if strings.Count(rel, string(filepath.Separator))+1 > maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// validateResult summarizes the validation of one .env file
+type validateResult struct {
+	vars     int
+	missing  int
+	extra    int
+	checksOK bool // strict, required, placeholder and duplicate checks passed
+}
+
+// validateAgainstExamples validates an .env file against its example files, printing
+// the results. Missing variables are added if --fix is set.
+func validateAgainstExamples(envFile string, exampleFiles []string) (validateResult, error) {
+	examplesLabel := exampleFilesLabel(exampleFiles)
+
+	// Parse the current .env file
+	currentVars, currentComments, err := parseEnvFile(envFile)
+	if err != nil {
+		return validateResult{}, fmt.Errorf("could not read %s: %w", envFile, err)
+	}
+
+	// Check for keys defined more than once
+	duplicatesOK := checkDuplicateKeys(envFile)
+
+	// Parse the example files. Each key is attributed to the first example that defines it.
+	referenceVars := make(map[string]string)
+	referenceSources := make(map[string]string)
+	for _, exampleFile := range exampleFiles {
+		exampleVars, _, err := parseEnvFile(exampleFile)
+		if err != nil {
+			return validateResult{}, fmt.Errorf("could not read %s: %w", exampleFile, err)
+		}
+		for key, value := range exampleVars {
+			if _, exists := referenceVars[key]; !exists {
+				referenceVars[key] = value
+				referenceSources[key] = exampleFile
+			}
+		}
+	}
 
 	// Find missing variables
 	missingVars := make(map[string]string)
@@ -77,7 +267,7 @@ func runValidateCommand(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	// Check for extra variables in .env that aren't in .env.example
+	// Check for extra variables in .env that aren't in any example
 	extraVars := make([]string, 0)
 	for key := range currentVars {
 		if _, exists := referenceVars[key]; !exists {
@@ -87,27 +277,32 @@ func runValidateCommand(cmd *cobra.Command, args []string) {
 
 	// Report results
 	if len(missingVars) == 0 && len(extraVars) == 0 {
-		fmt.Println("✅ Validation successful: .env contains all variables from .env.example")
+		fmt.Printf("✅ Validation successful: .env contains all variables from %s\n", examplesLabel)
 		fmt.Printf("Found %d environment variables\n", len(currentVars))
-		checkStrictAndRequired(currentVars)
-		return
+		checksOK := checkStrictAndRequired(currentVars, referenceVars) && duplicatesOK
+		return validateResult{vars: len(currentVars), checksOK: checksOK}, nil
 	}
+	result := validateResult{missing: len(missingVars), extra: len(extraVars)}
 
 	// Report missing variables
 	if len(missingVars) > 0 {
 		fmt.Printf("❌ Found %d missing variables in .env:\n", len(missingVars))
-		for key, value := range missingVars {
-			fmt.Printf("  %s=%s\n", key, value)
+		for _, key := range sortKeys(missingVars) {
+			if len(exampleFiles) > 1 {
+				fmt.Printf("  %s=%s (from %s)\n", key, displayValue(missingVars[key]), referenceSources[key])
+			} else {
+				fmt.Printf("  %s=%s\n", key, displayValue(missingVars[key]))
+			}
 		}
 
 		// Fix missing variables if requested
 		if validateFix {
-			err := addMissingVars(envFile, missingVars, currentVars, currentComments)
+			err := addMissingVars(envFile, missingVars, referenceSources, exampleFiles, currentComments)
 			if err != nil {
-				fmt.Printf("Error fixing .env file: %s\n", err)
-				os.Exit(1)
+				return result, fmt.Errorf("could not fix %s: %w", envFile, err)
 			}
 			fmt.Printf("✅ Added %d missing variables to .env\n", len(missingVars))
+			result.missing = 0
 			
 			// Recalculate current vars
 			currentVars, _, _ = parseEnvFile(envFile)
@@ -118,19 +313,59 @@ func runValidateCommand(cmd *cobra.Command, args []string) {
 
 	// Report extra variables
 	if len(extraVars) > 0 {
-		fmt.Printf("⚠️  Found %d extra variables in .env that are not in .env.example:\n", len(extraVars))
+		fmt.Printf("⚠️  Found %d extra variables in .env that are not in %s:\n", len(extraVars), examplesLabel)
 		for _, key := range extraVars {
-			fmt.Printf("  %s=%s\n", key, currentVars[key])
+			fmt.Printf("  %s=%s\n", key, displayValue(currentVars[key]))
 		}
-		fmt.Println("You may want to add these to .env.example if they are needed")
+		fmt.Printf("You may want to add these to %s if they are needed\n", examplesLabel)
 	}
 
 	// Check strict validation and required variables
-	checkStrictAndRequired(currentVars)
+	result.vars = len(currentVars)
+	result.checksOK = checkStrictAndRequired(currentVars, referenceVars) && duplicatesOK
+	return result, nil
 }
 
-// checkStrictAndRequired validates strict mode and required variables
-func checkStrictAndRequired(vars map[string]string) {
+// checkDuplicateKeys reports keys defined more than once in the file, returning
+// false if duplicates should fail validation (strict mode)
+func checkDuplicateKeys(filename string) bool {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return true
+	}
+
+	entries, _ := parseEnvEntries(content)
+	duplicates := findDuplicateKeys(entries)
+	if len(duplicates) == 0 {
+		return true
+	}
+
+	if validateStrict {
+		fmt.Printf("❌ Found %d duplicate variables in %s:\n", len(duplicates), filename)
+	} else {
+		fmt.Printf("⚠️  Found %d duplicate variables in %s:\n", len(duplicates), filename)
+	}
+
+	keys := make([]string, 0, len(duplicates))
+	for key := range duplicates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		lineStrs := make([]string, len(duplicates[key]))
+		for i, line := range duplicates[key] {
+			lineStrs[i] = strconv.Itoa(line)
+		}
+		fmt.Printf("  %s defined on lines %s (last definition wins)\n", key, strings.Join(lineStrs, ", "))
+	}
+
+	return !validateStrict
+}
+
+// checkStrictAndRequired validates strict mode, required variables, placeholders and
+// key names, returning false if any check failed
+func checkStrictAndRequired(vars, referenceVars map[string]string) bool {
 	// Check for strict validation errors (empty values)
 	hasStrictErrors := false
 	if validateStrict {
@@ -164,54 +399,83 @@ func checkStrictAndRequired(vars map[string]string) {
 			fmt.Println("✅ All required variables are present")
 		}
 	}
-}
 
-// parseEnvFile reads an .env file and returns a map of variables and a slice of comments
-func parseEnvFile(filename string) (map[string]string, []string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, nil, err
+	// Check for values left as placeholders
+	hasPlaceholders := false
+	if validateNoPlaceholders {
+		for _, key := range sortKeys(vars) {
+			if isPlaceholderValue(vars[key], referenceVars[key]) {
+				if !hasPlaceholders {
+					fmt.Println("\n❌ Variables still set to placeholder values:")
+					hasPlaceholders = true
+				}
+				fmt.Printf("  %s\n", key)
+			}
+		}
+		if !hasPlaceholders {
+			fmt.Println("✅ No placeholder values found")
+		}
 	}
-	defer file.Close()
-
-	variables := make(map[string]string)
-	comments := []string{}
-	envVarRegex := regexp.MustCompile(`^([A-Za-z0-9_]+)=(.*)$`)
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		trimmedLine := strings.TrimSpace(line)
-
-		// Skip empty lines
-		if trimmedLine == "" {
-			continue
+	// Check key names
+	hasBadKeys := false
+	if validateKeyRegex != nil {
+		for _, key := range sortKeys(vars) {
+			if !validateKeyRegex.MatchString(key) {
+				if !hasBadKeys {
+					fmt.Printf("\n❌ Keys not matching %s:\n", validateKeyRegex)
+					hasBadKeys = true
+				}
+				fmt.Printf("  %s\n", key)
+			}
 		}
-
-		// Handle comments
-		if strings.HasPrefix(trimmedLine, "#") {
-			comments = append(comments, line)
-			continue
+		if hasBadKeys {
+			fmt.Println("Run 'envi lint --strict-keys --fix' to rename them to UPPER_SNAKE_CASE")
+		} else {
+			fmt.Println("✅ All keys match the key pattern")
 		}
+	}
 
-		// Handle environment variables
-		if envVarRegex.MatchString(line) {
-			matches := envVarRegex.FindStringSubmatch(line)
-			varName := matches[1]
-			varValue := matches[2]
-			variables[varName] = varValue
-		}
+	return !hasStrictErrors && !hasMissingRequired && !hasPlaceholders && !hasBadKeys
+}
+
+// isPlaceholderValue reports whether a value is unchanged from its example or looks like a placeholder
+func isPlaceholderValue(value, exampleValue string) bool {
+	value = strings.Trim(strings.TrimSpace(value), `"'`)
+	if value == "" {
+		return false
+	}
+
+	exampleValue = strings.Trim(strings.TrimSpace(exampleValue), `"'`)
+	if exampleValue != "" && value == exampleValue {
+		return true
 	}
 
-	if err := scanner.Err(); err != nil {
+	return placeholderRegex.MatchString(value)
+}
+
+// parseEnvFile reads an .env file and returns a map of variables and a slice of comments
+func parseEnvFile(filename string) (map[string]string, []string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
 		return nil, nil, err
 	}
 
+	variables, comments := parseEnvContent(content)
 	return variables, comments, nil
 }
 
-// addMissingVars adds missing variables to the .env file
-func addMissingVars(filename string, missingVars, currentVars map[string]string, comments []string) error {
+// exampleFilesLabel names the example files in messages
+func exampleFilesLabel(exampleFiles []string) string {
+	if len(exampleFiles) == 1 {
+		return filepath.Base(exampleFiles[0])
+	}
+	return "any example file"
+}
+
+// addMissingVars adds missing variables to the .env file, grouped by the example file
+// each one came from
+func addMissingVars(filename string, missingVars, sources map[string]string, exampleFiles []string, comments []string) error {
 	// Create a backup of the original file
 	backupFile := filename + ".bak"
 	err := copyFile(filename, backupFile)
@@ -229,26 +493,33 @@ func addMissingVars(filename string, missingVars, currentVars map[string]string,
 
 	writer := bufio.NewWriter(file)
 
-	// First write all existing content
-	existingFile, err := os.Open(backupFile)
+	// First write all existing content, which may have lines of any length
+	existing, err := os.ReadFile(backupFile)
 	if err != nil {
 		return err
 	}
-	defer existingFile.Close()
-
-	scanner := bufio.NewScanner(existingFile)
-	for scanner.Scan() {
-		fmt.Fprintln(writer, scanner.Text())
+	writer.Write(existing)
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		writer.WriteString("\n")
 	}
 	
-	// Add a separator for new variables
-	if len(missingVars) > 0 {
+	// Group the missing variables by the example file that defined them
+	bySource := make(map[string][]string)
+	for _, key := range sortKeys(missingVars) {
+		bySource[sources[key]] = append(bySource[sources[key]], key)
+	}
+	
+	// Add a separator for each group of new variables
+	for _, source := range exampleFiles {
+		if len(bySource[source]) == 0 {
+			continue
+		}
 		fmt.Fprintln(writer, "")
-		fmt.Fprintln(writer, "# Added missing variables from .env.example")
+		fmt.Fprintf(writer, "# Added missing variables from %s\n", filepath.Base(source))
 		
 		// Add missing variables
-		for key, value := range missingVars {
-			fmt.Fprintf(writer, "%s=%s\n", key, value)
+		for _, key := range bySource[source] {
+			fmt.Fprintf(writer, "%s=%s\n", key, missingVars[key])
 		}
 	}
 
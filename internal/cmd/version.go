@@ -4,9 +4,35 @@ import (
 	"fmt"
 	"runtime"
 
+	"github.com/spf13/cobra"
+
 	"github.com/dexterity-inc/envi/internal/version"
 )
 
+// versionCmd is the version command
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show version information",
+	Long: `Show the envi version, the commit and date it was built from, and the Go
+version and platform. With --json, the same information is printed as a JSON
+object for tools that check the installed version.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if jsonOutput {
+			printJSONResult(map[string]interface{}{
+				"version":   version.GetVersion(),
+				"commit":    version.GetCommit(),
+				"buildDate": version.GetBuildDate(),
+				"goVersion": runtime.Version(),
+				"os":        runtime.GOOS,
+				"arch":      runtime.GOARCH,
+			})
+			return
+		}
+		displayVersion()
+	},
+}
+
 // displayVersion prints the version information
 func displayVersion() {
 	fmt.Printf("Envi CLI v%s\n", version.GetVersion())
@@ -21,10 +47,10 @@ func displayVersion() {
 	fmt.Printf("- OS/Arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
 }
 
-// InitVersionCommand initializes the version flags
+// InitVersionCommand sets up the version command and the --version flag
 func InitVersionCommand() {
-	// We don't need a separate version command as Cobra already provides
-	// --version flag. This function is kept for consistency in command initialization.
+	// Add the version command to the root command
+	rootCmd.AddCommand(versionCmd)
 	
 	// Add a custom -v short flag for version
 	rootCmd.Flags().BoolP("version", "v", false, "Display version information")
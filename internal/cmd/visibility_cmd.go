@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+)
+
+// Visibility command flags
+var (
+	visibilityGistID  string
+	visibilityPrivate bool
+	visibilityPublic  bool
+)
+
+// visibilityCmd is the visibility command
+var visibilityCmd = &cobra.Command{
+	Use:   "visibility",
+	Short: "Change whether a Gist is public or private",
+	Long: `Change the visibility of an existing Gist.
+
+GitHub does not allow changing a Gist's visibility after creation, so envi recreates
+the Gist with the same description and files. The new Gist has a NEW ID, which is
+saved as your default Gist. You will be asked whether to delete the old one.`,
+	Run: runVisibilityCommand,
+}
+
+// InitVisibilityCommand sets up the visibility command
+func InitVisibilityCommand() {
+	// Initialize the command flags
+	visibilityCmd.Flags().StringVarP(&visibilityGistID, "id", "i", "", "GitHub Gist ID to change (defaults to saved Gist)")
+	visibilityCmd.Flags().BoolVar(&visibilityPrivate, "private", false, "Make the Gist private")
+	visibilityCmd.Flags().BoolVar(&visibilityPublic, "public", false, "Make the Gist public")
+
+	// Add the visibility command to the root command
+	rootCmd.AddCommand(visibilityCmd)
+}
+
+// runVisibilityCommand handles the visibility command execution
+func runVisibilityCommand(cmd *cobra.Command, args []string) {
+	if visibilityPrivate == visibilityPublic {
+		exitWithError(ErrCodeGeneric, "Specify exactly one of --private or --public")
+	}
+
+	// Get GitHub token
+	token, err := config.GetGitHubToken()
+	if err != nil {
+		exitWithError(ErrCodeNoToken, err.Error())
+	}
+
+	// Load config
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logWarn("Could not load config: %s", err)
+	}
+
+	// Get Gist ID (from flag, bookmark or config)
+	visibilityGistID = resolveGistRef(visibilityGistID)
+	if visibilityGistID == "" && cfg != nil {
+		visibilityGistID = cfg.LastGistID
+	}
+	if visibilityGistID == "" {
+		exitWithError(ErrCodeGeneric, "No Gist ID specified and no saved Gist ID found", "Use 'envi visibility --id GIST_ID --private'")
+	}
+
+	// Create GitHub client
+	client := newGitHubClient(cmd.Context(), token)
+
+	// Get Gist
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	gist, err := fetchGist(ctx, client, visibilityGistID)
+	if err != nil {
+		exitWithError(gistErrorCode(err), fmt.Sprintf("Could not retrieve Gist with ID %s: %s", visibilityGistID, apiError(err)))
+	}
+
+	// Nothing to do if the Gist already has the requested visibility
+	makePublic := visibilityPublic
+	if gist.GetPublic() == makePublic {
+		if makePublic {
+			fmt.Printf("Gist %s is already public. Nothing to do.\n", visibilityGistID)
+		} else {
+			fmt.Printf("Gist %s is already private. Nothing to do.\n", visibilityGistID)
+		}
+		return
+	}
+
+	visibility, oldVisibility := "private", "public"
+	if makePublic {
+		visibility, oldVisibility = "public", "private"
+	}
+	fmt.Println("GitHub can't change the visibility of an existing Gist.")
+	fmt.Printf("Recreating Gist %s as a %s Gist. This will produce a NEW Gist ID.\n", visibilityGistID, visibility)
+
+	// Copy the description and file contents to a new Gist
+	newGist := &github.Gist{
+		Description: gist.Description,
+		Public:      github.Bool(makePublic),
+		Files:       make(map[github.GistFilename]github.GistFile),
+	}
+	for filename, file := range gist.Files {
+		newGist.Files[filename] = github.GistFile{Content: file.Content}
+	}
+
+	created, err := createGist(ctx, client, newGist)
+	if err != nil {
+		exitWithError(ErrCodeAPI, fmt.Sprintf("Could not create %s Gist: %s", visibility, apiError(err)))
+	}
+
+	fmt.Printf("Created %s Gist with new ID: %s\n", visibility, created.GetID())
+	fmt.Printf("Gist URL: %s\n", gistWebURL(created.GetID()))
+
+	// Save the new Gist ID in config
+	if cfg != nil {
+		cfg.LastGistID = created.GetID()
+		if err := config.SaveConfig(cfg); err != nil {
+			logWarn("Could not save Gist ID to config: %s", err)
+		} else {
+			fmt.Println("Saved new Gist ID for future use")
+		}
+	}
+
+	// Offer to delete the old Gist
+	deleteOld, err := confirmPrompt(
+		"Delete old Gist?",
+		fmt.Sprintf("Delete the old %s Gist (%s)?", oldVisibility, visibilityGistID),
+	)
+	if err != nil {
+		exitWithError(ErrCodeGeneric, fmt.Sprintf("Could not get confirmation: %s", err))
+	}
+
+	if !deleteOld {
+		fmt.Printf("Old Gist %s was kept.\n", visibilityGistID)
+		return
+	}
+
+	// Fresh deadline, since the confirmation prompt may have taken a while
+	deleteCtx, deleteCancel := apiContext(cmd)
+	defer deleteCancel()
+	if _, err := client.Gists.Delete(deleteCtx, visibilityGistID); err != nil {
+		exitWithError(ErrCodeAPI, fmt.Sprintf("Could not delete old Gist %s: %s", visibilityGistID, apiError(err)))
+	}
+	fmt.Printf("Deleted old Gist %s\n", visibilityGistID)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/spf13/cobra"
+
+	"github.com/dexterity-inc/envi/internal/config"
+)
+
+// whoamiCmd is the whoami command
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the GitHub account for the configured token",
+	Long: `Show which GitHub account the configured token belongs to and where the token
+was loaded from. Also checks that the token has the gist scope, so permission
+problems are caught before a push fails.`,
+	Run: runWhoamiCommand,
+}
+
+// InitWhoamiCommand sets up the whoami command
+func InitWhoamiCommand() {
+	// Add the whoami command to the root command
+	rootCmd.AddCommand(whoamiCmd)
+}
+
+// runWhoamiCommand handles the whoami command execution
+func runWhoamiCommand(cmd *cobra.Command, args []string) {
+	// Get GitHub token and where it came from
+	token, tokenSource, err := config.ResolveGitHubToken()
+	if err != nil {
+		exitWithError(ErrCodeNoToken, err.Error())
+	}
+
+	// Create GitHub client
+	client := newGitHubClient(cmd.Context(), token)
+
+	// Get the authenticated user
+	ctx, cancel := apiContext(cmd)
+	defer cancel()
+	user, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		exitWithError(ErrCodeAPI, fmt.Sprintf("Could not retrieve GitHub user: %s", apiError(err)))
+	}
+
+	fmt.Printf("Login:        %s\n", user.GetLogin())
+	if user.GetName() != "" {
+		fmt.Printf("Name:         %s\n", user.GetName())
+	}
+	fmt.Printf("Token source: %s\n", tokenSource)
+
+	scopes, reported := tokenScopes(resp)
+	if !reported {
+		fmt.Println("Scopes:       not reported (fine-grained token)")
+		fmt.Println("Note: Make sure the token has the \"Gists\" account permission set to read and write.")
+		return
+	}
+
+	if len(scopes) == 0 {
+		fmt.Println("Scopes:       none")
+	} else {
+		fmt.Printf("Scopes:       %s\n", strings.Join(scopes, ", "))
+	}
+
+	if !hasScope(scopes, "gist") {
+		logWarn("Token is missing the gist scope. Pushing and pulling will fail with 403 errors.")
+		fmt.Println("Create a token with the gist scope at " + githubWebURL("settings/tokens"))
+	}
+}
+
+// tokenScopes returns the OAuth scopes reported for the token used in a request.
+// Classic tokens report their scopes; fine-grained tokens send no header, in which
+// case the second return value is false.
+func tokenScopes(resp *github.Response) ([]string, bool) {
+	if resp == nil || resp.Header.Values("X-OAuth-Scopes") == nil {
+		return nil, false
+	}
+
+	var scopes []string
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true
+}
+
+// hasScope reports whether scope is in scopes
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -6,10 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
+	"runtime"
 
 	"github.com/zalando/go-keyring"
 	"gopkg.in/yaml.v3"
+
+	"github.com/dexterity-inc/envi/internal/logging"
 )
 
 // Config stores application configuration
@@ -22,8 +24,33 @@ type Config struct {
 	UnmaskByDefault     bool   `yaml:"unmask_by_default"`
 	DefaultKeyFile      string `yaml:"default_key_file,omitempty"`
 	UseKeyFileByDefault bool   `yaml:"use_key_file_by_default"`
+	PlaintextSecrets    string `yaml:"plaintext_secrets,omitempty"` // warn (default), block or allow
+	DescriptionTemplate string `yaml:"description_template,omitempty"` // Default Gist description for push, see 'envi push --help'
+	Bookmarks           map[string]string `yaml:"bookmarks,omitempty"` // Gist IDs by bookmark name, used as --id @NAME
+	NoReadme            bool   `yaml:"no_readme,omitempty"` // Don't add a README to Gists with encrypted content
+	TimeFormat          string `yaml:"time_format,omitempty"` // How list shows dates: relative, absolute (default) or rfc3339
+	Cipher              string `yaml:"cipher,omitempty"` // Cipher for new encrypted content: aes-gcm (default) or chacha20poly1305
+	GitHubURL           string `yaml:"github_url,omitempty"` // GitHub Enterprise Server address; public GitHub when empty
+	NoSaveID            bool   `yaml:"no_save_id,omitempty"` // Don't save the Gist used by push and pull as the default
+	NoGitignoreOffer    bool   `yaml:"no_gitignore_offer,omitempty"` // Don't offer to update .gitignore after the first push from a directory
+	UpdateCheck         bool   `yaml:"update_check,omitempty"` // Check for a newer release in the background, see 'envi update-check --help'
+	UpdateCheckInterval string `yaml:"update_check_interval,omitempty"` // How often the background check runs, e.g. 24h (the default) or 168h
 }
 
+// Policies for pushing likely secrets without encryption
+const (
+	PlaintextSecretsWarn  = "warn"
+	PlaintextSecretsBlock = "block"
+	PlaintextSecretsAllow = "allow"
+)
+
+// Formats for times shown by list
+const (
+	TimeFormatRelative = "relative"
+	TimeFormatAbsolute = "absolute"
+	TimeFormatRFC3339  = "rfc3339"
+)
+
 const (
 	// App constants for keyring
 	applicationName = "envi-cli"
@@ -31,34 +58,113 @@ const (
 	
 	// Default file permissions for config
 	configFilePerms = 0600
+	configDirPerms  = 0700
+	
+	// configPathEnv overrides the config file location
+	configPathEnv = "ENVI_CONFIG"
 )
 
-// ConfigPath returns the path to the config file
-func ConfigPath() (string, error) {
+// baseDir replaces the config and data directories when set with SetBaseDir
+var baseDir string
+
+// SetBaseDir makes dir hold everything envi stores: the config file, key files and
+// sync snapshots. It takes precedence over ENVI_CONFIG and the XDG variables. An empty
+// dir restores the default locations.
+func SetBaseDir(dir string) error {
+	if dir == "" {
+		baseDir = ""
+		return nil
+	}
+	
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid config directory %s: %w", dir, err)
+	}
+	baseDir = absDir
+	logging.Debug("Using config directory", "path", baseDir)
+	return nil
+}
+
+// ConfigDir returns the directory holding the config file:
+//   1. the directory set with SetBaseDir (--config-dir)
+//   2. $XDG_CONFIG_HOME/envi if XDG_CONFIG_HOME is set
+//   3. ~/.config/envi on Linux
+//   4. ~/.envi otherwise
+func ConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir returns the directory for key files and other data, following the same
+// rules as ConfigDir with XDG_DATA_HOME and ~/.local/share/envi
+func DataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// xdgDir resolves an envi directory from an XDG base directory variable, its default
+// under the home directory on Linux, or the legacy ~/.envi directory
+func xdgDir(envVar, linuxDefault string) (string, error) {
+	if baseDir != "" {
+		return baseDir, nil
+	}
+	
+	// The XDG spec says relative paths are invalid and must be ignored
+	if base := os.Getenv(envVar); base != "" && filepath.IsAbs(base) {
+		return filepath.Join(base, "envi"), nil
+	}
+	
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("error getting home directory: %w", err)
 	}
 	
-	configDir := filepath.Join(homeDir, ".envi")
+	if runtime.GOOS == "linux" {
+		return filepath.Join(homeDir, linuxDefault, "envi"), nil
+	}
+	return legacyConfigDir(homeDir), nil
+}
+
+// legacyConfigDir returns the directory used by older versions of envi
+func legacyConfigDir(homeDir string) string {
+	return filepath.Join(homeDir, ".envi")
+}
+
+// ConfigPath returns the path to the config file. --config-dir comes first, then
+// ENVI_CONFIG, then the default location in ConfigDir.
+func ConfigPath() (string, error) {
+	if baseDir != "" {
+		return filepath.Join(baseDir, "config.yaml"), nil
+	}
+	if path := os.Getenv(configPathEnv); path != "" {
+		return filepath.Clean(path), nil
+	}
+	
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	
 	configPath := filepath.Join(configDir, "config.yaml")
 	
 	return configPath, nil
 }
 
-// EnsureConfigDir ensures the config directory exists
+// EnsureConfigDir ensures the directory holding the config file exists
 func EnsureConfigDir() error {
-	homeDir, err := os.UserHomeDir()
+	configPath, err := ConfigPath()
 	if err != nil {
-		return fmt.Errorf("error getting home directory: %w", err)
+		return err
 	}
-	
-	configDir := filepath.Join(homeDir, ".envi")
+	configDir := filepath.Dir(configPath)
 	
 	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(configDir, 0700); err != nil {
+		if err := os.MkdirAll(configDir, configDirPerms); err != nil {
+			if baseDir == "" && os.Getenv(configPathEnv) != "" {
+				return fmt.Errorf("cannot create directory for %s=%s: %w", configPathEnv, configPath, err)
+			}
 			return fmt.Errorf("error creating config directory: %w", err)
 		}
+	} else if err != nil {
+		return fmt.Errorf("error checking config directory: %w", err)
 	}
 	
 	return nil
@@ -71,6 +177,11 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 	
+	// Move a config file left by an older version to the current location
+	if err := migrateLegacyConfig(configPath); err != nil {
+		return nil, err
+	}
+	
 	// Create default config if no file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		// Create default config
@@ -89,6 +200,7 @@ func LoadConfig() (*Config, error) {
 			return nil, err
 		}
 		
+		logging.Debug("Created default config", "path", configPath)
 		return defaultConfig, nil
 	}
 	
@@ -99,15 +211,79 @@ func LoadConfig() (*Config, error) {
 	}
 	
 	// Unmarshal the YAML
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("error parsing config file: %w", err)
+	config, err := parseConfig(configPath, data)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Verify file permissions
 	verifyConfigPermissions(configPath)
 	
-	return &config, nil
+	// Settings envi doesn't know are ignored, which hides typos
+	for _, setting := range UnknownSettings(data) {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", configPath, setting)
+	}
+	
+	logging.Debug("Loaded config", "path", configPath)
+	return config, nil
+}
+
+// ReadConfig parses the config file at path without creating, migrating or checking it,
+// for callers that must not change anything on disk
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(path, data)
+}
+
+// migrateLegacyConfig moves ~/.envi/config.yaml to configPath if the config has
+// moved to an XDG directory and no config exists there yet
+func migrateLegacyConfig(configPath string) error {
+	// An explicitly chosen config file is never filled from the legacy location
+	if baseDir != "" || os.Getenv(configPathEnv) != "" {
+		return nil
+	}
+	
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil // Nothing to migrate from
+	}
+	
+	legacyPath := filepath.Join(legacyConfigDir(homeDir), "config.yaml")
+	if legacyPath == configPath {
+		return nil
+	}
+	if _, err := os.Stat(configPath); err == nil {
+		return nil
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		return nil
+	}
+	
+	if err := EnsureConfigDir(); err != nil {
+		return err
+	}
+	
+	// Rename fails across file systems, so fall back to copying
+	if err := os.Rename(legacyPath, configPath); err != nil {
+		data, err := os.ReadFile(legacyPath)
+		if err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+		if err := os.WriteFile(configPath, data, configFilePerms); err != nil {
+			return fmt.Errorf("error writing config file: %w", err)
+		}
+		os.Remove(legacyPath)
+	}
+	
+	if err := os.Chmod(configPath, configFilePerms); err != nil {
+		return fmt.Errorf("error setting config file permissions: %w", err)
+	}
+	
+	fmt.Fprintf(os.Stderr, "Moved config file from %s to %s\n", legacyPath, configPath)
+	return nil
 }
 
 // SaveConfig saves the configuration to disk
@@ -136,40 +312,86 @@ func SaveConfig(config *Config) error {
 	return nil
 }
 
+// ExportConfig encodes the portable, non-secret part of a config.
+// The token and its storage location are machine-specific and never exported.
+func ExportConfig(config *Config) ([]byte, error) {
+	portable := *config
+	portable.GitHubToken = ""
+	portable.TokenInKeyring = false
+	
+	data, err := yaml.Marshal(&portable)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+	
+	return data, nil
+}
+
+// ImportConfig applies exported settings to an existing config, keeping its token settings
+func ImportConfig(config *Config, data []byte) error {
+	var imported Config
+	if err := yaml.Unmarshal(data, &imported); err != nil {
+		return fmt.Errorf("error parsing config file: %w", err)
+	}
+	
+	imported.GitHubToken = config.GitHubToken
+	imported.TokenInKeyring = config.TokenInKeyring
+	*config = imported
+	
+	return nil
+}
+
+// Token sources reported by ResolveGitHubToken
+const (
+	TokenSourceEnv     = "GITHUB_TOKEN environment variable"
+	TokenSourceKeyring = "system credential manager"
+	TokenSourceFile    = "config file"
+)
+
 // GetGitHubToken fetches the GitHub token, trying environment variable, then keyring, then config file
 func GetGitHubToken() (string, error) {
+	token, _, err := ResolveGitHubToken()
+	return token, err
+}
+
+// ResolveGitHubToken fetches the GitHub token and reports which source it came from
+func ResolveGitHubToken() (string, string, error) {
 	// First try environment variable
 	envToken := os.Getenv("GITHUB_TOKEN")
 	if envToken != "" {
 		if !IsValidGitHubToken(envToken) {
-			return "", errors.New("GitHub token from environment variable has invalid format")
+			return "", "", errors.New("GitHub token from environment variable has invalid format")
 		}
-		return envToken, nil
+		logging.Debug("GitHub token resolved", "source", TokenSourceEnv)
+		return envToken, TokenSourceEnv, nil
 	}
 	
 	// Load config
 	config, err := LoadConfig()
 	if err != nil {
-		return "", fmt.Errorf("error loading config: %w", err)
+		return "", "", fmt.Errorf("error loading config: %w", err)
 	}
 	
 	// Try keyring if configured
 	if config.TokenInKeyring {
 		token, err := GetTokenFromKeyring()
 		if err == nil {
-			return token, nil
+			logging.Debug("GitHub token resolved", "source", TokenSourceKeyring)
+			return token, TokenSourceKeyring, nil
 		}
+		logging.Debug("No token in the system credential manager, trying the config file", "error", err)
 	}
 	
 	// Try token from config file
 	if config.GitHubToken != "" {
 		if !IsValidGitHubToken(config.GitHubToken) {
-			return "", errors.New("GitHub token in config file has invalid format")
+			return "", "", errors.New("GitHub token in config file has invalid format")
 		}
-		return config.GitHubToken, nil
+		logging.Debug("GitHub token resolved", "source", TokenSourceFile)
+		return config.GitHubToken, TokenSourceFile, nil
 	}
 	
-	return "", errors.New("no GitHub token found. Use 'envi config --token YOUR_TOKEN' to set one")
+	return "", "", errors.New("no GitHub token found. Run 'envi init', or use 'envi config --token YOUR_TOKEN' to set one")
 }
 
 // SaveTokenToKeyring saves the GitHub token to the system keyring
@@ -182,38 +404,39 @@ func GetTokenFromKeyring() (string, error) {
 	return keyring.Get(applicationName, tokenUsername)
 }
 
+// KeyringStatus reports whether the system keyring holds a token. An error means the
+// keyring can't be used on this system. It only reads from the keyring.
+func KeyringStatus() (bool, error) {
+	_, err := GetTokenFromKeyring()
+	if errors.Is(err, keyring.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 // DeleteTokenFromKeyring removes the GitHub token from the system keyring
 func DeleteTokenFromKeyring() error {
 	return keyring.Delete(applicationName, tokenUsername)
 }
 
+// GitHub token formats
+var (
+	// Classic PATs (ghp_), OAuth (gho_), user-to-server (ghu_), server-to-server (ghs_)
+	// and refresh (ghr_) tokens are a prefix followed by 36 base62 characters
+	prefixedTokenRegex = regexp.MustCompile(`^gh[pousr]_[A-Za-z0-9]{36}$`)
+	
+	// Fine-grained PATs are github_pat_, 22 base62 characters, an underscore and 59 base62 characters
+	fineGrainedTokenRegex = regexp.MustCompile(`^github_pat_[A-Za-z0-9]{22}_[A-Za-z0-9]{59}$`)
+	
+	// Legacy tokens are 40 lowercase hex characters
+	legacyTokenRegex = regexp.MustCompile(`^[a-f0-9]{40}$`)
+)
+
 // IsValidGitHubToken checks if a token is a valid GitHub PAT format
 func IsValidGitHubToken(token string) bool {
-	// GitHub Personal Access Tokens are at least 40 characters
-	if len(token) < 30 {
-		return false
-	}
-	
-	// Matches the format of GitHub tokens
-	// Classic PATs: ghp_*
-	// Fine-grained PATs: github_pat_*
-	// OAuth tokens: gho_*
-	// User-to-server tokens: ghu_*
-	// Server-to-server tokens: ghs_*
-	validPrefixes := []string{"ghp_", "github_pat_", "gho_", "ghu_", "ghs_"}
-	
-	// Also allow the old format tokens that are just hex
-	hexRegex := regexp.MustCompile(`^[a-f0-9]{40}$`)
-	
-	// Check if it has a valid prefix
-	for _, prefix := range validPrefixes {
-		if strings.HasPrefix(token, prefix) {
-			return true
-		}
-	}
-	
-	// Check if it's a valid old-style token
-	return hexRegex.MatchString(token)
+	return prefixedTokenRegex.MatchString(token) ||
+		fineGrainedTokenRegex.MatchString(token) ||
+		legacyTokenRegex.MatchString(token)
 }
 
 // verifyConfigPermissions checks and warns about insecure file permissions
@@ -225,7 +448,7 @@ func verifyConfigPermissions(configPath string) {
 	
 	// Check if permissions are too open
 	if info.Mode().Perm() != configFilePerms {
-		fmt.Printf("Warning: Config file has insecure permissions: %o\n", info.Mode().Perm())
-		fmt.Printf("Run 'chmod 600 %s' to fix\n", configPath)
+		fmt.Fprintf(os.Stderr, "Warning: Config file has insecure permissions: %o\n", info.Mode().Perm())
+		fmt.Fprintf(os.Stderr, "Run 'chmod 600 %s' to fix\n", configPath)
 	}
 } 
\ No newline at end of file
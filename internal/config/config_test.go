@@ -0,0 +1,48 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsValidGitHubToken(t *testing.T) {
+	base62 := func(n int) string {
+		const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+		return strings.Repeat(chars, n/len(chars)+1)[:n]
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"classic PAT", "ghp_" + base62(36), true},
+		{"OAuth token", "gho_" + base62(36), true},
+		{"user-to-server token", "ghu_" + base62(36), true},
+		{"server-to-server token", "ghs_" + base62(36), true},
+		{"refresh token", "ghr_" + base62(36), true},
+		{"fine-grained PAT", "github_pat_" + base62(22) + "_" + base62(59), true},
+		{"legacy token", strings.Repeat("0123456789abcdef", 3)[:40], true},
+
+		{"empty", "", false},
+		{"classic PAT too short", "ghp_" + base62(35), false},
+		{"classic PAT too long", "ghp_" + base62(37), false},
+		{"unknown prefix", "ghx_" + base62(36), false},
+		{"classic PAT with invalid character", "ghp_" + base62(35) + "-", false},
+		{"classic PAT with surrounding whitespace", " ghp_" + base62(36) + "\n", false},
+		{"fine-grained PAT without separator", "github_pat_" + base62(82), false},
+		{"fine-grained PAT too short", "github_pat_" + base62(22) + "_" + base62(58), false},
+		{"fine-grained PAT with wrong prefix", "github-pat_" + base62(22) + "_" + base62(59), false},
+		{"legacy token with uppercase hex", strings.ToUpper(strings.Repeat("0123456789abcdef", 3)[:40]), false},
+		{"legacy token too short", strings.Repeat("a", 39), false},
+		{"long arbitrary string", strings.Repeat("x", 64), false},
+		{"placeholder", "your_github_token_here", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidGitHubToken(tt.token); got != tt.valid {
+				t.Errorf("IsValidGitHubToken(%q) = %v, want %v", tt.token, got, tt.valid)
+			}
+		})
+	}
+}
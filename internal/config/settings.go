@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// managedSettings can be read by name but are changed by their own commands, which
+// take care of secure storage and validation
+var managedSettings = map[string]string{
+	"github_token":     "use 'envi config --token' or 'envi config rotate-token'",
+	"token_in_keyring": "use 'envi config --token' or 'envi config --clear-token'",
+	"bookmarks":        "use 'envi bookmark add' and 'envi bookmark rm'",
+}
+
+// settingField returns the field of cfg stored under a setting name in the config file
+func settingField(cfg *Config, name string) (reflect.Value, error) {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0] == name {
+			return v.Field(i), nil
+		}
+	}
+	if suggestion := closestName(name, KnownSettings()); suggestion != "" {
+		return reflect.Value{}, fmt.Errorf("unknown setting %q (did you mean %q?)", name, suggestion)
+	}
+	return reflect.Value{}, fmt.Errorf("unknown setting %q", name)
+}
+
+// GetSetting returns the value of a setting as text. The GitHub token is never
+// returned, only whether one is set.
+func GetSetting(cfg *Config, name string) (string, error) {
+	field, err := settingField(cfg, name)
+	if err != nil {
+		return "", err
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool()), nil
+	case reflect.Map:
+		var pairs []string
+		for _, key := range field.MapKeys() {
+			pairs = append(pairs, key.String()+"="+field.MapIndex(key).String())
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	}
+	if name == "github_token" && field.String() != "" {
+		return "********", nil
+	}
+	return field.String(), nil
+}
+
+// SetSetting changes a setting from text, converting it to the setting's type. Yes/no
+// settings take true or false, and also yes, no, on, off, 1 and 0. An empty value
+// clears a text setting.
+func SetSetting(cfg *Config, name, value string) error {
+	field, err := settingField(cfg, name)
+	if err != nil {
+		return err
+	}
+	if hint, ok := managedSettings[name]; ok {
+		return fmt.Errorf("%s can't be changed with config set; %s", name, hint)
+	}
+
+	switch field.Kind() {
+	case reflect.Bool:
+		b, err := parseBoolSetting(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q should be true or false", name, value)
+		}
+		field.SetBool(b)
+	case reflect.String:
+		field.SetString(strings.TrimSpace(value))
+	default:
+		return fmt.Errorf("%s can't be changed with config set", name)
+	}
+	return nil
+}
+
+// parseBoolSetting parses a yes/no setting value
+func parseBoolSetting(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(strings.TrimSpace(value))
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseError is returned when the config file isn't valid YAML or a setting has a
+// value of the wrong type
+type ParseError struct {
+	Path    string
+	Details string // What is wrong, starting with the line number
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("config file %s is not valid: %s. Fix or remove the file; 'envi config --validate' checks it", e.Path, e.Details)
+}
+
+// UnknownSetting is a key in the config file that envi doesn't use, usually a typo
+type UnknownSetting struct {
+	Key        string `json:"key"`
+	Line       int    `json:"line"`
+	Suggestion string `json:"suggestion,omitempty"` // The known setting with the closest name, if any is close
+}
+
+func (s UnknownSetting) String() string {
+	if s.Suggestion != "" {
+		return fmt.Sprintf("unknown setting %q on line %d (did you mean %q?)", s.Key, s.Line, s.Suggestion)
+	}
+	return fmt.Sprintf("unknown setting %q on line %d", s.Key, s.Line)
+}
+
+// parseConfig decodes the config file content read from path
+func parseConfig(path string, data []byte) (*Config, error) {
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, &ParseError{Path: path, Details: describeYAMLError(err)}
+	}
+	return &config, nil
+}
+
+// yamlTypeErrorRegex matches the parser's explanation of a value of the wrong type
+var yamlTypeErrorRegex = regexp.MustCompile("cannot unmarshal !!\\w+ (.*) into (\\S+)$")
+
+// yamlTypeNames describes the Go types of Config fields for people editing the file
+var yamlTypeNames = map[string]string{
+	"bool":              "true or false",
+	"string":            "text",
+	"map[string]string": "a list of name: value pairs",
+}
+
+// describeYAMLError turns a parser error into "line N: what is wrong"
+func describeYAMLError(err error) string {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return strings.TrimPrefix(err.Error(), "yaml: ")
+	}
+
+	details := make([]string, len(typeErr.Errors))
+	for i, detail := range typeErr.Errors {
+		if m := yamlTypeErrorRegex.FindStringSubmatch(detail); m != nil {
+			if want, ok := yamlTypeNames[m[2]]; ok {
+				detail = detail[:len(detail)-len(m[0])] + fmt.Sprintf("%s should be %s", m[1], want)
+			}
+		}
+		details[i] = detail
+	}
+	return strings.Join(details, "; ")
+}
+
+// KnownSettings returns the names of the settings the config file can hold
+func KnownSettings() []string {
+	var names []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]; name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnknownSettings returns the top-level keys in config file content that envi doesn't
+// use, in file order. Content that isn't valid YAML has none.
+func UnknownSettings(data []byte) []UnknownSetting {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+
+	known := KnownSettings()
+	isKnown := make(map[string]bool, len(known))
+	for _, name := range known {
+		isKnown[name] = true
+	}
+
+	var unknown []UnknownSetting
+	mapping := doc.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i]
+		if isKnown[key.Value] {
+			continue
+		}
+		unknown = append(unknown, UnknownSetting{Key: key.Value, Line: key.Line, Suggestion: closestName(key.Value, known)})
+	}
+	return unknown
+}
+
+// closestName returns the name that differs least from name, if it is close enough
+// to be a typo: at most a third of its characters changed
+func closestName(name string, names []string) string {
+	best, bestDistance := "", len(name)/3+1
+	for _, candidate := range names {
+		if d := editDistance(strings.ToLower(name), candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
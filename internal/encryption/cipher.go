@@ -0,0 +1,91 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Cipher names accepted by --cipher and the cipher config setting
+const (
+	CipherAESGCM           = "aes-gcm"
+	CipherChaCha20Poly1305 = "chacha20poly1305"
+
+	// DefaultCipher is used when no cipher is chosen. Content encrypted with it keeps
+	// the v2 format, so older versions of envi can still read it.
+	DefaultCipher = CipherAESGCM
+)
+
+// cipherSuite is an AEAD cipher that content can be encrypted with
+type cipherSuite interface {
+	// Name is the cipher name recorded in encrypted content
+	Name() string
+	// NewAEAD returns the cipher for a 256-bit key
+	NewAEAD(key []byte) (cipher.AEAD, error)
+}
+
+// aesGCM is AES-256 in GCM mode
+type aesGCM struct{}
+
+func (aesGCM) Name() string { return CipherAESGCM }
+
+func (aesGCM) NewAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher block: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// chaCha20Poly1305 is ChaCha20-Poly1305, which is faster than AES on CPUs without
+// AES instructions
+type chaCha20Poly1305 struct{}
+
+func (chaCha20Poly1305) Name() string { return CipherChaCha20Poly1305 }
+
+func (chaCha20Poly1305) NewAEAD(key []byte) (cipher.AEAD, error) {
+	return chacha20poly1305.New(key)
+}
+
+// cipherSuites holds the supported ciphers by name
+var cipherSuites = map[string]cipherSuite{
+	CipherAESGCM:           aesGCM{},
+	CipherChaCha20Poly1305: chaCha20Poly1305{},
+}
+
+// CipherNames returns the supported cipher names in sorted order
+func CipherNames() []string {
+	names := make([]string, 0, len(cipherSuites))
+	for name := range cipherSuites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidCipher reports whether name is a supported cipher
+func ValidCipher(name string) bool {
+	_, ok := cipherSuites[name]
+	return ok
+}
+
+// lookupCipher returns the cipher with the given name
+func lookupCipher(name string) (cipherSuite, error) {
+	suite, ok := cipherSuites[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported cipher %q (supported: %s)", name, strings.Join(CipherNames(), ", "))
+	}
+	return suite, nil
+}
+
+// selectedCipher returns the cipher chosen with --cipher, or the default
+func selectedCipher() (cipherSuite, error) {
+	if CipherName == "" {
+		return cipherSuites[DefaultCipher], nil
+	}
+	return lookupCipher(CipherName)
+}
@@ -2,20 +2,24 @@ package encryption
 
 import (
 	"bytes"
-	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 
+	"github.com/dexterity-inc/envi/internal/logging"
 	"github.com/dexterity-inc/envi/internal/tui"
 )
 
@@ -26,7 +30,16 @@ var (
 	UseKeyFile         bool
 	EncryptionKeyFile  string
 	EncryptionPassword string
+	PasswordFromStdin  bool
+	KeyFromStdin       bool
+	CipherName         string = DefaultCipher
+	DeterministicMasking bool
+	LegacyKeyFile      bool
 	UseTUI             bool = true
+	
+	// KeyFileDir is where bare key file names such as ".envi.key" are looked up;
+	// the command layer sets it to envi's data directory
+	KeyFileDir string
 )
 
 // Encryption constants
@@ -34,15 +47,35 @@ const (
 	EncryptionPrefix    = "ENVI_ENCRYPTED:"
 	MaskedPrefix        = "ENVI_MASKED:"
 	EncryptionKeyLength = 32 // 256-bit key
+	MinPasswordLength   = 8
+	
+	// Environment variables that can supply the password non-interactively
+	PasswordEnvVar     = "ENVI_PASSWORD"
+	PasswordFileEnvVar = "ENVI_PASSWORD_FILE"
+	
+	// KeyEnvVar supplies the key itself, base64-encoded, for CI secrets that shouldn't
+	// be written to a key file
+	KeyEnvVar = "ENVI_KEY"
+	
+	// EncryptionHeaderV2 marks fully encrypted content whose payload starts with a
+	// SHA-256 checksum of the plaintext. V1 content (no version tag) has no checksum.
+	EncryptionHeaderV2 = EncryptionPrefix + "v2:"
+	
+	// EncryptionHeaderV3 is followed by the cipher name and a colon, and otherwise has
+	// the V2 payload. It is only written for ciphers other than AES-GCM.
+	EncryptionHeaderV3 = EncryptionPrefix + "v3:"
 )
 
 // InitEncryptionFlags initializes encryption-related flags for commands
 func InitEncryptionFlags(cmd *cobra.Command) {
 	// These flags are added to the root command for all subcommands
-	cmd.PersistentFlags().BoolVar(&UseEncryption, "encrypt", false, "Encrypt data using AES-256")
+	cmd.PersistentFlags().BoolVar(&UseEncryption, "encrypt", false, "Encrypt the whole file (AES-256-GCM unless --cipher is set)")
 	cmd.PersistentFlags().BoolVarP(&UseMaskedEncryption, "mask", "m", false, "Mask values (keep keys visible)")
 	cmd.PersistentFlags().BoolVar(&UseKeyFile, "use-key-file", false, "Use key file instead of password")
-	cmd.PersistentFlags().StringVarP(&EncryptionKeyFile, "key-file", "k", ".envi.key", "Path to encryption key file")
+	cmd.PersistentFlags().StringVarP(&EncryptionKeyFile, "key-file", "k", ".envi.key", "Path to encryption key file; a bare file name is looked up in the data directory")
+	cmd.PersistentFlags().BoolVar(&LegacyKeyFile, "legacy-key-file", false, "Read a key file that isn't a valid key by hashing its content, as older versions did")
+	cmd.PersistentFlags().BoolVar(&DeterministicMasking, "deterministic", false, "Mask unchanged values to the same ciphertext on every push (reveals which values are equal)")
+	cmd.PersistentFlags().StringVar(&CipherName, "cipher", DefaultCipher, "Cipher for encrypting and masking: "+strings.Join(CipherNames(), " or "))
 }
 
 // IsEncrypted checks if content is encrypted with full encryption
@@ -55,75 +88,138 @@ func IsMasked(content []byte) bool {
 	return bytes.Contains(content, []byte(MaskedPrefix))
 }
 
-// EncryptContent encrypts the given content using AES-256-GCM
+// EncryptContent encrypts the given content using the selected cipher with the key from
+// the configured password or key file
 func EncryptContent(content []byte) ([]byte, error) {
+	logging.Debug("Encrypting content", "cipher", CipherName)
+	
 	// Get the encryption key
-	key, err := getEncryptionKey()
+	key, err := getEncryptionKey(true)
 	if err != nil {
-		return nil, errors.New("failed to retrieve encryption key")
+		return nil, fmt.Errorf("failed to retrieve encryption key: %w", err)
 	}
+	defer zeroize(key)
+
+	return EncryptWithKey(content, key)
+}
 
-	// Create a new AES cipher block
-	block, err := aes.NewCipher(key)
+// EncryptWithKey encrypts the given content using the selected cipher (AES-256-GCM by default).
+// The sealed payload is a SHA-256 checksum of the plaintext followed by the plaintext,
+// and the version header is authenticated as additional data.
+func EncryptWithKey(content, key []byte) ([]byte, error) {
+	suite, err := selectedCipher()
 	if err != nil {
-		return nil, errors.New("failed to create AES cipher block")
+		return nil, err
 	}
 
-	// Create a new GCM
-	gcm, err := cipher.NewGCM(block)
+	aead, err := suite.NewAEAD(key)
 	if err != nil {
-		return nil, errors.New("failed to create GCM")
+		return nil, fmt.Errorf("failed to create %s cipher: %w", suite.Name(), err)
 	}
 
 	// Create a nonce
-	nonce := make([]byte, gcm.NonceSize())
+	nonce := make([]byte, aead.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, errors.New("failed to generate nonce")
 	}
 
-	// Encrypt the data
-	ciphertext := gcm.Seal(nonce, nonce, content, nil)
+	// Prepend the plaintext checksum and encrypt the data
+	header := encryptionHeader(suite)
+	checksum := sha256.Sum256(content)
+	payload := append(checksum[:], content...)
+	ciphertext := aead.Seal(nonce, nonce, payload, []byte(header))
 	
-	// Encode as base64 with prefix
-	result := []byte(EncryptionPrefix + base64.StdEncoding.EncodeToString(ciphertext))
+	// Encode as base64 with versioned header
+	result := []byte(header + base64.StdEncoding.EncodeToString(ciphertext))
 	
 	return result, nil
 }
 
-// DecryptContent decrypts the given content using AES-256-GCM
+// encryptionHeader returns the header for content encrypted with a cipher. AES-GCM
+// keeps the V2 header so the content stays readable by older versions.
+func encryptionHeader(suite cipherSuite) string {
+	if suite.Name() == CipherAESGCM {
+		return EncryptionHeaderV2
+	}
+	return EncryptionHeaderV3 + suite.Name() + ":"
+}
+
+// DecryptContent decrypts the given content with the key from the configured password or
+// key file. The cipher is read from the content's header.
 func DecryptContent(content []byte) ([]byte, error) {
+	// Check the format before asking for a password
+	header, suite, _, err := parseEncrypted(content)
+	if err != nil {
+		return nil, err
+	}
+	logging.Debug("Decrypting content", "header", header, "cipher", suite.Name())
+	
+	// Get the encryption key
+	key, err := getEncryptionKey(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve encryption key: %w", err)
+	}
+	defer zeroize(key)
+	
+	return DecryptWithKey(content, key)
+}
+
+// parseEncrypted returns the header, cipher and decoded ciphertext of fully encrypted content
+func parseEncrypted(content []byte) (string, cipherSuite, []byte, error) {
 	// Remove the prefix
 	if !IsEncrypted(content) {
-		return nil, errors.New("content is not encrypted or has invalid format")
+		return "", nil, nil, errors.New("content is not encrypted or has invalid format")
 	}
-	cipherTextB64 := string(content)[len(EncryptionPrefix):]
 	
-	// Decode from base64
-	ciphertext, err := base64.StdEncoding.DecodeString(cipherTextB64)
-	if err != nil {
-		return nil, errors.New("invalid encrypted data format")
+	// Determine the header version; V1 content has no version tag or checksum, and
+	// only V3 names its cipher
+	header := EncryptionPrefix
+	suite := cipherSuites[CipherAESGCM]
+	switch {
+	case bytes.HasPrefix(content, []byte(EncryptionHeaderV2)):
+		header = EncryptionHeaderV2
+	case bytes.HasPrefix(content, []byte(EncryptionHeaderV3)):
+		rest := string(content)[len(EncryptionHeaderV3):]
+		end := strings.Index(rest, ":")
+		if end == -1 {
+			return "", nil, nil, errors.New("invalid encrypted data format: cipher name missing")
+		}
+		var err error
+		if suite, err = lookupCipher(rest[:end]); err != nil {
+			return "", nil, nil, fmt.Errorf("cannot decrypt: %w", err)
+		}
+		header = EncryptionHeaderV3 + rest[:end+1]
 	}
+	cipherTextB64 := strings.TrimSpace(string(content)[len(header):])
 	
-	// Get the encryption key
-	key, err := getEncryptionKey()
+	// A second header means two encrypted blobs were concatenated
+	if strings.Contains(cipherTextB64, EncryptionPrefix) {
+		return "", nil, nil, errors.New("invalid encrypted data: content contains more than one encrypted block")
+	}
+	
+	// Decode from base64
+	ciphertext, err := base64.StdEncoding.DecodeString(cipherTextB64)
 	if err != nil {
-		return nil, errors.New("failed to retrieve encryption key")
+		return "", nil, nil, errors.New("invalid encrypted data format: content may be truncated or edited")
 	}
 	
-	// Create a new AES cipher block
-	block, err := aes.NewCipher(key)
+	return header, suite, ciphertext, nil
+}
+
+// DecryptWithKey decrypts content produced by EncryptWithKey
+func DecryptWithKey(content, key []byte) ([]byte, error) {
+	header, suite, ciphertext, err := parseEncrypted(content)
 	if err != nil {
 		return nil, err
 	}
 	
-	// Create a new GCM
-	gcm, err := cipher.NewGCM(block)
+	aead, err := suite.NewAEAD(key)
 	if err != nil {
 		return nil, err
 	}
 	
 	// Verify ciphertext length
-	nonceSize := gcm.NonceSize()
+	nonceSize := aead.NonceSize()
 	if len(ciphertext) < nonceSize {
 		return nil, errors.New("invalid encrypted data: ciphertext too short")
 	}
@@ -131,24 +227,80 @@ func DecryptContent(content []byte) ([]byte, error) {
 	// Extract nonce and ciphertext
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
 	
+	// V1 content has no additional data or checksum
+	if header == EncryptionPrefix {
+		plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return nil, errors.New("decryption failed: invalid password or corrupted data")
+		}
+		return plaintext, nil
+	}
+	
 	// Decrypt the data
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	payload, err := aead.Open(nil, nonce, ciphertext, []byte(header))
 	if err != nil {
 		return nil, errors.New("decryption failed: invalid password or corrupted data")
 	}
 	
+	// Verify the plaintext checksum
+	if len(payload) < sha256.Size {
+		return nil, errors.New("invalid encrypted data: checksum missing")
+	}
+	checksum, plaintext := payload[:sha256.Size], payload[sha256.Size:]
+	actual := sha256.Sum256(plaintext)
+	if !bytes.Equal(checksum, actual[:]) {
+		return nil, errors.New("checksum mismatch: encrypted content has been modified")
+	}
+	
 	return plaintext, nil
 }
 
 // MaskEnvContent masks the values in a .env file while keeping the keys visible
 func MaskEnvContent(content []byte) ([]byte, error) {
+	return maskEnvLines(content, nil)
+}
+
+// MaskEnvKeys masks only the values of the given keys, leaving other lines unchanged.
+// The result can be unmasked with UnmaskEnvContent like fully masked content.
+func MaskEnvKeys(content []byte, keys map[string]bool) ([]byte, error) {
+	return maskEnvLines(content, keys)
+}
+
+// maskEnvLines masks values with the key from the configured password or key file
+func maskEnvLines(content []byte, keys map[string]bool) ([]byte, error) {
 	// Get the encryption key
-	key, err := getEncryptionKey()
+	key, err := getEncryptionKey(true)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(key)
+	
+	return MaskWithKey(content, key, keys)
+}
+
+// MaskWithKey masks the value of every key=value line, or only of the variables in
+// names if it is non-nil. With DeterministicMasking, nonces are derived from the
+// variable name and value instead of being random.
+func MaskWithKey(content, key []byte, names map[string]bool) ([]byte, error) {
+	shouldMask := func(name string) bool { return names == nil || names[name] }
+	
+	suite, err := selectedCipher()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := suite.NewAEAD(key)
 	if err != nil {
 		return nil, err
 	}
+	prefix := maskedValuePrefix(suite)
+	
+	var nonceKey []byte
+	if DeterministicMasking {
+		nonceKey = deriveNonceKey(key)
+		defer zeroize(nonceKey)
+	}
 	
-	lines := strings.Split(string(content), "\n")
+	lines, newline := SplitLines(content)
 	var maskedLines []string
 	
 	for _, line := range lines {
@@ -169,6 +321,13 @@ func MaskEnvContent(content []byte) ([]byte, error) {
 		// Split into key and value
 		k, v := line[:eqIdx+1], line[eqIdx+1:]
 		
+		// Skip variables that weren't selected; the name is the last word before '=' (after any export/set prefix)
+		fields := strings.Fields(line[:eqIdx])
+		if len(fields) == 0 || !shouldMask(fields[len(fields)-1]) {
+			maskedLines = append(maskedLines, line)
+			continue
+		}
+		
 		// Encrypt the value
 		if v == "" {
 			// Empty value, no need to encrypt
@@ -176,48 +335,78 @@ func MaskEnvContent(content []byte) ([]byte, error) {
 			continue
 		}
 		
-		// Create a new AES cipher block
-		block, err := aes.NewCipher(key)
-		if err != nil {
-			return nil, err
-		}
-		
-		// Create a new GCM
-		gcm, err := cipher.NewGCM(block)
-		if err != nil {
-			return nil, err
-		}
-		
 		// Create a nonce
-		nonce := make([]byte, gcm.NonceSize())
-		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-			return nil, err
+		var nonce []byte
+		if nonceKey != nil {
+			nonce = syntheticNonce(nonceKey, aead.NonceSize(), fields[len(fields)-1], v)
+		} else {
+			nonce = make([]byte, aead.NonceSize())
+			if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+				return nil, err
+			}
 		}
 		
 		// Encrypt the value
-		ciphertext := gcm.Seal(nonce, nonce, []byte(v), nil)
+		ciphertext := aead.Seal(nonce, nonce, []byte(v), nil)
 		
 		// Encode as base64
-		maskedValue := MaskedPrefix + base64.StdEncoding.EncodeToString(ciphertext)
+		maskedValue := prefix + base64.StdEncoding.EncodeToString(ciphertext)
 		
 		// Add to masked lines
 		maskedLines = append(maskedLines, k+maskedValue)
 	}
 	
-	return []byte(strings.Join(maskedLines, "\n")), nil
+	return JoinLines(maskedLines, newline), nil
+}
+
+// deriveNonceKey derives the key for synthetic nonces from the encryption key, so the
+// encryption key itself is never used for anything but encryption
+func deriveNonceKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("envi deterministic masking nonce"))
+	return mac.Sum(nil)
+}
+
+// syntheticNonce derives a nonce from a variable's name and value (SIV-style), so the
+// same value masks to the same ciphertext. A nonce only repeats for the same name and
+// plaintext, which yields identical ciphertext and reveals nothing beyond that equality.
+func syntheticNonce(nonceKey []byte, size int, name, value string) []byte {
+	mac := hmac.New(sha256.New, nonceKey)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)[:size]
+}
+
+// maskedValuePrefix returns the prefix of values masked with a cipher. Values masked
+// with AES-GCM have no cipher name, as before ciphers could be chosen.
+func maskedValuePrefix(suite cipherSuite) string {
+	if suite.Name() == CipherAESGCM {
+		return MaskedPrefix
+	}
+	return MaskedPrefix + suite.Name() + ":"
 }
 
 // UnmaskEnvContent unmasks the values in a masked .env file
 func UnmaskEnvContent(content []byte) ([]byte, error) {
 	// Get the encryption key
-	key, err := getEncryptionKey()
+	key, err := getEncryptionKey(false)
 	if err != nil {
 		return nil, err
 	}
+	defer zeroize(key)
 	
-	lines := strings.Split(string(content), "\n")
+	return UnmaskWithKey(content, key)
+}
+
+// UnmaskWithKey unmasks the values in content masked by MaskWithKey
+func UnmaskWithKey(content, key []byte) ([]byte, error) {
+	lines, newline := SplitLines(content)
 	var unmaskedLines []string
 	
+	// Ciphers created so far, by name; a file may mix values masked with different ciphers
+	aeads := make(map[string]cipher.AEAD)
+	
 	for _, line := range lines {
 		// Skip comments and empty lines
 		if strings.HasPrefix(strings.TrimSpace(line), "#") || strings.TrimSpace(line) == "" {
@@ -243,8 +432,12 @@ func UnmaskEnvContent(content []byte) ([]byte, error) {
 			continue
 		}
 		
-		// Remove prefix
+		// Remove prefix and read the cipher name, if any; base64 never contains a colon
 		encryptedValue := v[len(MaskedPrefix):]
+		cipherName := CipherAESGCM
+		if end := strings.Index(encryptedValue, ":"); end != -1 {
+			cipherName, encryptedValue = encryptedValue[:end], encryptedValue[end+1:]
+		}
 		
 		// Decode from base64
 		ciphertext, err := base64.StdEncoding.DecodeString(encryptedValue)
@@ -252,20 +445,20 @@ func UnmaskEnvContent(content []byte) ([]byte, error) {
 			return nil, errors.New("invalid masked data format")
 		}
 		
-		// Create a new AES cipher block
-		block, err := aes.NewCipher(key)
-		if err != nil {
-			return nil, err
-		}
-		
-		// Create a new GCM
-		gcm, err := cipher.NewGCM(block)
-		if err != nil {
-			return nil, err
+		aead, ok := aeads[cipherName]
+		if !ok {
+			suite, err := lookupCipher(cipherName)
+			if err != nil {
+				return nil, fmt.Errorf("cannot unmask: %w", err)
+			}
+			if aead, err = suite.NewAEAD(key); err != nil {
+				return nil, err
+			}
+			aeads[cipherName] = aead
 		}
 		
 		// Verify ciphertext length
-		nonceSize := gcm.NonceSize()
+		nonceSize := aead.NonceSize()
 		if len(ciphertext) < nonceSize {
 			return nil, errors.New("invalid masked data: ciphertext too short")
 		}
@@ -274,7 +467,7 @@ func UnmaskEnvContent(content []byte) ([]byte, error) {
 		nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
 		
 		// Decrypt the value
-		plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+		plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
 		if err != nil {
 			return nil, errors.New("unmasking failed: invalid password or corrupted data")
 		}
@@ -283,78 +476,571 @@ func UnmaskEnvContent(content []byte) ([]byte, error) {
 		unmaskedLines = append(unmaskedLines, k+string(plaintext))
 	}
 	
-	return []byte(strings.Join(unmaskedLines, "\n")), nil
+	return JoinLines(unmaskedLines, newline), nil
+}
+
+// SplitLines splits .env content into lines with any trailing \r removed, so Windows
+// line endings never end up in values. It also returns the dominant line ending
+// ("\r\n" or "\n") so JoinLines can write the content back in its original style.
+func SplitLines(content []byte) ([]string, string) {
+	text := string(content)
+	
+	newline := "\n"
+	crlf := strings.Count(text, "\r\n")
+	if crlf > strings.Count(text, "\n")-crlf {
+		newline = "\r\n"
+	}
+	
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines, newline
+}
+
+// JoinLines joins lines split by SplitLines using the given line ending
+func JoinLines(lines []string, newline string) []byte {
+	return []byte(strings.Join(lines, newline))
 }
 
-// getEncryptionKey gets the encryption key from password input or key file
-func getEncryptionKey() ([]byte, error) {
+// The key used by a command is derived once and cached until ClearKeyCache is called,
+// so multi-file operations only ask for the password and hash it once.
+var (
+	keyCacheMu sync.Mutex
+	cachedKey  []byte
+	
+	// cachedKeyChecked reports whether the cached key passed the checks for encrypting:
+	// the minimum password length and, for a typed password, the confirmation prompt.
+	// A key derived for decrypting may not have.
+	cachedKeyChecked bool
+)
+
+// ClearKeyCache overwrites the cached key and forgets it. It is called when a command ends.
+func ClearKeyCache() {
+	keyCacheMu.Lock()
+	defer keyCacheMu.Unlock()
+	
+	zeroize(cachedKey)
+	cachedKey = nil
+	cachedKeyChecked = false
+}
+
+// getEncryptionKey returns a copy of the cached key, deriving and caching it first if needed.
+// With confirm, a cached key that hasn't passed the checks for encrypting is derived again
+// with them, which asks for a typed password again.
+func getEncryptionKey(confirm bool) ([]byte, error) {
+	keyCacheMu.Lock()
+	defer keyCacheMu.Unlock()
+	
+	if cachedKey != nil && confirm && !cachedKeyChecked {
+		// stdin was read when the key was derived and can't be read again
+		if PasswordFromStdin {
+			return nil, fmt.Errorf("password from stdin must be at least %d characters", MinPasswordLength)
+		}
+		logging.Debug("Checking the cached key before encrypting")
+		zeroize(cachedKey)
+		cachedKey = nil
+	}
+	if cachedKey == nil {
+		key, checked, err := deriveEncryptionKey(confirm)
+		if err != nil {
+			return nil, err
+		}
+		cachedKey, cachedKeyChecked = key, checked
+	}
+	
+	return append([]byte(nil), cachedKey...), nil
+}
+
+// deriveEncryptionKey gets the encryption key from password input or key file. With confirm,
+// the key is used to encrypt: a password typed interactively must be entered twice, and
+// every password must have at least MinPasswordLength characters. Decrypting accepts any
+// non-empty password, so content encrypted before the minimum existed can still be read. A key
+// given with --key-stdin or ENVI_KEY is used as it is, without a password or key file. It
+// also reports whether the key passed the checks for encrypting, which keys and key files
+// always do.
+func deriveEncryptionKey(confirm bool) ([]byte, bool, error) {
+	if KeyFromStdin {
+		logging.Debug("Encryption key source", "source", "stdin key")
+		line, err := readStdinLine("key", maxStdinKeyLength)
+		if err != nil {
+			return nil, false, err
+		}
+		defer zeroize(line)
+		key, err := ParseRawKey(line, "--key-stdin")
+		return key, err == nil, err
+	}
+	if encoded := os.Getenv(KeyEnvVar); encoded != "" {
+		logging.Debug("Encryption key source", "source", KeyEnvVar)
+		key, err := ParseRawKey([]byte(encoded), KeyEnvVar)
+		return key, err == nil, err
+	}
+	
 	if UseKeyFile {
 		// Use key file
-		return getKeyFromFile()
+		logging.Debug("Encryption key source", "source", "key file", "path", EncryptionKeyFile)
+		key, err := getKeyFromFile()
+		return key, err == nil, err
 	}
 	
 	// Use password
 	if EncryptionPassword != "" {
 		// Password provided in flag (not recommended)
-		return hashPassword(EncryptionPassword), nil
+		logging.Debug("Encryption key source", "source", "--password flag")
+		return KeyFromPassword(EncryptionPassword), true, nil
+	}
+	
+	// Password piped in with --password-stdin
+	if PasswordFromStdin {
+		logging.Debug("Encryption key source", "source", "stdin")
+		password, err := readPasswordFromStdin(confirm)
+		if err != nil {
+			return nil, false, err
+		}
+		defer zeroize(password)
+		return keyFromPasswordBytes(password), len(password) >= MinPasswordLength, nil
 	}
 	
-	// Get password from user
-	var password string
-	var err error
+	// Password provided through the environment (below --password, above interactive input)
+	password, ok, err := getPasswordFromEnv(confirm)
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		defer zeroize(password)
+		return keyFromPasswordBytes(password), len(password) >= MinPasswordLength, nil
+	}
 	
+	// Get password from user, who can only answer on a terminal. Without one, for example
+	// in CI, reading would fail or wait forever on a pipe that is never closed.
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, false, errors.New("an encryption password is needed, but stdin is not a terminal to ask for it; " +
+			"set ENVI_PASSWORD or ENVI_KEY, or use --password-stdin, --key-stdin or --use-key-file")
+	}
+	logging.Debug("Encryption key source", "source", "prompt", "tui", UseTUI)
 	if UseTUI {
-		// Use TUI for password input
-		password, err = tui.GetPassword("Enter encryption password", false)
+		// Use TUI for password input. The form works with strings, so this copy
+		// can't be wiped; the terminal prompt below avoids strings entirely.
+		input, err := tui.GetPassword("Enter encryption password", confirm, minPasswordLength(confirm))
 		if err != nil {
-			return nil, errors.New("failed to retrieve encryption password")
+			return nil, false, fmt.Errorf("failed to retrieve encryption password: %w", err)
 		}
+		password = []byte(input)
 	} else {
 		// Use terminal input
-		fmt.Print("Enter encryption password: ")
-		passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+		password, err = readPasswordFromTerminal(confirm)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	defer zeroize(password)
+	
+	if len(password) == 0 {
+		return nil, false, errors.New("password cannot be empty")
+	}
+	if len(password) < minPasswordLength(confirm) {
+		return nil, false, fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	
+	// A typed password is only checked for encrypting if it was confirmed
+	return keyFromPasswordBytes(password), confirm, nil
+}
+
+// minPasswordLength returns the shortest password accepted when encrypting, or when
+// decrypting with encrypt false
+func minPasswordLength(encrypt bool) int {
+	if encrypt {
+		return MinPasswordLength
+	}
+	return 1
+}
+
+// maxConfirmAttempts is how many times the password confirmation can be mistyped
+const maxConfirmAttempts = 3
+
+// readPasswordFromTerminal reads a password without echoing it. With confirm, the password
+// is asked for twice, and again after a mismatch, up to maxConfirmAttempts times.
+func readPasswordFromTerminal(confirm bool) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		password, err := promptPassword("Enter encryption password: ")
+		if err != nil || !confirm {
+			return password, err
+		}
+		
+		confirmation, err := promptPassword("Confirm encryption password: ")
 		if err != nil {
+			zeroize(password)
 			return nil, err
 		}
-		fmt.Println()
-		password = string(passwordBytes)
+		match := bytes.Equal(password, confirmation)
+		zeroize(confirmation)
+		if match {
+			return password, nil
+		}
+		zeroize(password)
+		
+		if attempt == maxConfirmAttempts {
+			return nil, errors.New("passwords do not match")
+		}
+		fmt.Fprintf(os.Stderr, "Passwords do not match, try again (%d of %d attempts left)\n",
+			maxConfirmAttempts-attempt, maxConfirmAttempts)
 	}
+}
+
+// promptPassword prints a prompt to stderr and reads a line from the terminal without echo
+func promptPassword(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	return password, nil
+}
+
+// The longest password accepted by --password-stdin and key line accepted by --key-stdin
+const (
+	maxStdinPasswordLength = 1024
+	maxStdinKeyLength      = 1024
+)
+
+// readPasswordFromStdin reads the password from the first line of stdin (--password-stdin),
+// without surrounding whitespace. The minimum length only applies when encrypting.
+func readPasswordFromStdin(encrypt bool) ([]byte, error) {
+	line, err := readStdinLine("password", maxStdinPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+	password := bytes.TrimSpace(line)
 	
-	if password == "" {
-		return nil, errors.New("password cannot be empty")
+	if len(password) == 0 {
+		zeroize(line)
+		return nil, errors.New("password from stdin is empty")
+	}
+	if len(password) < minPasswordLength(encrypt) {
+		zeroize(line)
+		return nil, fmt.Errorf("password from stdin must be at least %d characters", MinPasswordLength)
 	}
+	return password, nil
+}
+
+// readStdinLine reads the first line of stdin, holding the named secret, without its
+// line ending
+func readStdinLine(name string, maxLength int) ([]byte, error) {
+	// Read byte by byte so nothing after the first line is consumed. The buffer never
+	// grows, so no copies of the secret are left behind to wipe.
+	line := make([]byte, 0, maxLength)
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				break
+			}
+			if len(line) == cap(line) {
+				zeroize(line)
+				return nil, fmt.Errorf("%s from stdin is longer than %d characters", name, maxLength)
+			}
+			line = append(line, buf[0])
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			zeroize(line)
+			return nil, fmt.Errorf("failed to read %s from stdin: %w", name, err)
+		}
+	}
+	return bytes.TrimSuffix(line, []byte("\r")), nil
+}
+
+// getPasswordFromEnv reads the password from ENVI_PASSWORD or the file named by ENVI_PASSWORD_FILE.
+// The second return value reports whether either variable was set. The minimum length only
+// applies when encrypting.
+func getPasswordFromEnv(encrypt bool) ([]byte, bool, error) {
+	source := PasswordEnvVar
+	password := []byte(os.Getenv(PasswordEnvVar))
+	
+	if len(password) == 0 {
+		passwordFile := os.Getenv(PasswordFileEnvVar)
+		if passwordFile == "" {
+			return nil, false, nil
+		}
+		
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read password file from %s", PasswordFileEnvVar)
+		}
+		source = PasswordFileEnvVar
+		password = bytes.TrimRight(data, "\r\n")
+	}
+	
+	if len(password) == 0 {
+		return nil, false, fmt.Errorf("password from %s is empty", source)
+	}
+	if len(password) < minPasswordLength(encrypt) {
+		zeroize(password)
+		return nil, false, fmt.Errorf("password from %s must be at least %d characters", source, MinPasswordLength)
+	}
+	
+	logging.Debug("Encryption key source", "source", source)
+	return password, true, nil
+}
+
+// RawKeyError reports a key given with --key-stdin or ENVI_KEY that isn't valid
+type RawKeyError struct {
+	Source string // --key-stdin or ENVI_KEY
+	Err    error
+}
+
+func (e *RawKeyError) Error() string {
+	return fmt.Sprintf("key from %s: %s", e.Source, e.Err)
+}
+
+func (e *RawKeyError) Unwrap() error {
+	return e.Err
+}
+
+// ParseRawKey decodes a key given directly rather than in a key file, which must be
+// the base64 encoding of exactly 32 bytes. Surrounding whitespace is ignored.
+func ParseRawKey(encoded []byte, source string) ([]byte, error) {
+	encoded = bytes.TrimSpace(encoded)
+	if len(encoded) == 0 {
+		return nil, &RawKeyError{Source: source, Err: errors.New("it is empty")}
+	}
+	
+	key := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(key, encoded)
+	if err == nil && n == EncryptionKeyLength {
+		return key[:n], nil
+	}
+	zeroize(key)
 	
-	return hashPassword(password), nil
+	if err != nil {
+		return nil, &RawKeyError{Source: source, Err: fmt.Errorf("it isn't valid base64; expected the base64 encoding of %d bytes "+
+			"(generate one with 'openssl rand -base64 %d')", EncryptionKeyLength, EncryptionKeyLength)}
+	}
+	return nil, &RawKeyError{Source: source, Err: fmt.Errorf("it decodes to %d bytes, expected exactly %d; it may be truncated or "+
+		"have characters added (generate one with 'openssl rand -base64 %d')", n, EncryptionKeyLength, EncryptionKeyLength)}
+}
+
+// ReadsStdin reports whether the password or key is read from stdin, which then
+// can't answer questions
+func ReadsStdin() bool {
+	return PasswordFromStdin || KeyFromStdin
+}
+
+// StdinFlag returns the flag that makes the password or key come from stdin
+func StdinFlag() string {
+	if KeyFromStdin {
+		return "--key-stdin"
+	}
+	return "--password-stdin"
+}
+
+// KeyFileError reports a key file that can't be read or doesn't hold a valid key,
+// as opposed to a valid key that doesn't match the encrypted content
+type KeyFileError struct {
+	Path string
+	Err  error
+}
+
+func (e *KeyFileError) Error() string {
+	return fmt.Sprintf("key file %s: %s", e.Path, e.Err)
+}
+
+func (e *KeyFileError) Unwrap() error {
+	return e.Err
 }
 
-// getKeyFromFile reads the encryption key from a file
+// getKeyFromFile reads the encryption key from the selected key file
 func getKeyFromFile() ([]byte, error) {
-	keyData, err := os.ReadFile(EncryptionKeyFile)
+	return ReadKeyFile(ResolveKeyFile(EncryptionKeyFile))
+}
+
+// ReadKeyFile reads the encryption key from a key file. Files ending in .gpg or .asc
+// are decrypted with gpg first, see IsGPGKeyFile.
+func ReadKeyFile(path string) ([]byte, error) {
+	keyData, err := os.ReadFile(path)
 	if err != nil {
-		return nil, errors.New("failed to read encryption key file")
+		if os.IsNotExist(err) {
+			return nil, &KeyFileError{Path: path, Err: errors.New("file not found")}
+		}
+		return nil, &KeyFileError{Path: path, Err: fmt.Errorf("failed to read: %w", err)}
 	}
 	
-	// Clean the key data
-	key := bytes.TrimSpace(keyData)
+	if IsGPGKeyFile(path) {
+		key, err := readGPGKeyFile(path, keyData)
+		zeroize(keyData)
+		if err != nil {
+			return nil, &KeyFileError{Path: path, Err: err}
+		}
+		return key, nil
+	}
 	
-	// Check if the key is base64 encoded
-	decodedKey, err := base64.StdEncoding.DecodeString(string(key))
-	if err == nil && len(decodedKey) == EncryptionKeyLength {
-		// The key is valid base64 and has the correct length after decoding
+	key, err := parseKeyFileData(path, keyData)
+	zeroize(keyData)
+	if err != nil {
+		return nil, &KeyFileError{Path: path, Err: err}
+	}
+	return key, nil
+}
+
+// ResolveKeyFile returns the file a key file setting refers to, so the same key is
+// found from any working directory. A bare file name such as "team.key" is looked up
+// in KeyFileDir, unless only the current directory has it, as older versions expected.
+// Absolute paths, "~/" paths and paths with a directory, such as "./team.key", are
+// used as given.
+func ResolveKeyFile(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, path[2:])
+		}
+	}
+	if path == "" || KeyFileDir == "" || filepath.IsAbs(path) || filepath.Base(path) != path {
+		return path
+	}
+	
+	resolved := filepath.Join(KeyFileDir, path)
+	if _, err := os.Stat(resolved); os.IsNotExist(err) {
+		if _, err := os.Stat(path); err == nil {
+			logging.Debug("Using key file from the current directory", "path", path)
+			return path
+		}
+	}
+	return resolved
+}
+
+// ParseKeyFile decodes key file contents, which must be exactly 32 raw bytes or the
+// base64 encoding of 32 bytes. Raw keys are checked first, since their bytes may be
+// whitespace; surrounding whitespace, such as a trailing newline, is then ignored. Any
+// other content is an error; see ParseLegacyKeyFile for key files of older versions.
+func ParseKeyFile(keyData []byte) ([]byte, error) {
+	if len(keyData) == EncryptionKeyLength {
+		return append([]byte(nil), keyData...), nil
+	}
+	
+	key := bytes.TrimSpace(keyData)
+	if len(key) == 0 {
+		return nil, errors.New("invalid key file: the file is empty")
+	}
+	if decodedKey, ok := decodeBase64Key(key); ok {
 		return decodedKey, nil
 	}
 	
-	// Check if the key has the correct length directly
+	hint := fmt.Sprintf("generate one with 'openssl rand -base64 %d', or read a key file from an older version with --legacy-key-file", EncryptionKeyLength)
+	if _, err := hex.DecodeString(string(key)); err == nil && len(key) == 2*EncryptionKeyLength {
+		return nil, fmt.Errorf("invalid key file: it holds a hex key, but key files are base64 (%s)", hint)
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(string(key)); err == nil {
+		zeroize(decoded)
+		return nil, fmt.Errorf("invalid key file: it is base64 of %d bytes, expected %d (%s)", len(decoded), EncryptionKeyLength, hint)
+	}
+	return nil, fmt.Errorf("invalid key file: it holds %d bytes, but a key is %d raw bytes or the base64 encoding of %d bytes (%s)",
+		len(keyData), EncryptionKeyLength, EncryptionKeyLength, hint)
+}
+
+// ParseLegacyKeyFile is ParseKeyFile for key files of older versions of envi, which used
+// any content that wasn't a key, without surrounding whitespace, as 32 raw bytes if it
+// had that length and hashed it into a key otherwise. IsLegacyKeyFile reports those files.
+func ParseLegacyKeyFile(keyData []byte) ([]byte, error) {
+	if !IsLegacyKeyFile(keyData) {
+		return ParseKeyFile(keyData)
+	}
+	key := bytes.TrimSpace(keyData)
 	if len(key) == EncryptionKeyLength {
-		return key, nil
+		return append([]byte(nil), key...), nil
+	}
+	return keyFromPasswordBytes(key), nil
+}
+
+// IsLegacyKeyFile reports whether key file contents are not empty, but neither 32 raw
+// bytes nor base64 of 32 bytes, so only ParseLegacyKeyFile reads them. Hex keys from
+// 'openssl rand -hex 32' and passphrase files are such files.
+func IsLegacyKeyFile(keyData []byte) bool {
+	key := bytes.TrimSpace(keyData)
+	if len(key) == 0 || len(keyData) == EncryptionKeyLength {
+		return false
+	}
+	decodedKey, ok := decodeBase64Key(key)
+	zeroize(decodedKey)
+	return !ok
+}
+
+// parseKeyFileData decodes the contents of the key file at path, through
+// ParseLegacyKeyFile if --legacy-key-file is set
+func parseKeyFileData(path string, keyData []byte) ([]byte, error) {
+	if !LegacyKeyFile {
+		return ParseKeyFile(keyData)
+	}
+	if IsLegacyKeyFile(keyData) {
+		warnLegacyKeyFile(path)
+	}
+	return ParseLegacyKeyFile(keyData)
+}
+
+// decodeBase64Key decodes the base64 encoding of a 32-byte key
+func decodeBase64Key(encoded []byte) ([]byte, bool) {
+	decodedKey := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(decodedKey, encoded)
+	if err == nil && n == EncryptionKeyLength {
+		return decodedKey[:n], true
+	}
+	zeroize(decodedKey)
+	return nil, false
+}
+
+// warnLegacyKeyFile says that a key file is only read through the deprecated hash fallback
+func warnLegacyKeyFile(path string) {
+	fmt.Fprintf(os.Stderr, "Warning: key file %s is not 32 raw bytes or base64 of 32 bytes, so it is read as older versions did. "+
+		"This format is deprecated; decrypt your Gists with it and push them again with a key from 'openssl rand -base64 %d'\n",
+		path, EncryptionKeyLength)
+}
+
+// GenerateKeyFile writes a new random key to path in the canonical base64 format
+func GenerateKeyFile(path string) error {
+	key, err := newKey()
+	if err != nil {
+		return err
 	}
 	
-	// Hash the key to get a fixed length key
-	return hashPassword(string(key)), nil
+	// Key files may live in a data directory that doesn't exist yet
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	
+	return os.WriteFile(path, []byte(encodeKey(key)), 0600)
+}
+
+// newKey returns a new random encryption key
+func newKey() ([]byte, error) {
+	key := make([]byte, EncryptionKeyLength)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, errors.New("failed to generate key")
+	}
+	return key, nil
+}
+
+// encodeKey returns a key in the canonical key file format: base64 and a newline
+func encodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key) + "\n"
+}
+
+// KeyFromPassword creates a fixed-length encryption key from a password
+func KeyFromPassword(password string) []byte {
+	return keyFromPasswordBytes([]byte(password))
 }
 
-// hashPassword creates a fixed-length encryption key from a password
-func hashPassword(password string) []byte {
-	hash := sha256.Sum256([]byte(password))
+// keyFromPasswordBytes creates the key from a password held in a byte slice, which the
+// caller can wipe afterwards
+func keyFromPasswordBytes(password []byte) []byte {
+	hash := sha256.Sum256(password)
 	return hash[:]
+}
+
+// zeroize overwrites key material with zeros so it doesn't linger in memory
+func zeroize(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
 } 
\ No newline at end of file
@@ -0,0 +1,548 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyFile(t *testing.T) {
+	raw := bytes.Repeat([]byte{0x42}, EncryptionKeyLength)
+	rawWithSpace := append([]byte(" "), bytes.Repeat([]byte{0x43}, EncryptionKeyLength-2)...)
+	rawWithSpace = append(rawWithSpace, '\n')
+	encoded := base64.StdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{"raw", raw, raw},
+		{"raw with whitespace bytes at both ends", rawWithSpace, rawWithSpace},
+		{"base64", []byte(encoded), raw},
+		{"base64 with trailing newline", []byte(encoded + "\n"), raw},
+		{"base64 with CRLF", []byte(encoded + "\r\n"), raw},
+		{"base64 with surrounding spaces", []byte("  " + encoded + "\t\n"), raw},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append([]byte{}, tt.data...)
+			if IsLegacyKeyFile(data) {
+				t.Errorf("IsLegacyKeyFile() = true, want false")
+			}
+			key, err := ParseKeyFile(data)
+			if err != nil {
+				t.Fatalf("ParseKeyFile() error = %v", err)
+			}
+			if !bytes.Equal(key, tt.want) {
+				t.Errorf("ParseKeyFile() = %x, want %x", key, tt.want)
+			}
+			if legacy, err := ParseLegacyKeyFile(data); err != nil || !bytes.Equal(legacy, tt.want) {
+				t.Errorf("ParseLegacyKeyFile() = %x, %v; want the same key", legacy, err)
+			}
+
+			// The key must survive wiping the file contents it was read from
+			zeroize(data)
+			if !bytes.Equal(key, tt.want) {
+				t.Errorf("key changed when the file contents were wiped")
+			}
+		})
+	}
+}
+
+func TestParseKeyFileInvalid(t *testing.T) {
+	raw := bytes.Repeat([]byte{0x42}, EncryptionKeyLength)
+	hexKey := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	hashOf := func(s string) []byte {
+		sum := sha256.Sum256([]byte(s))
+		return sum[:]
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+		legacy  []byte // key older versions read from the file, if any
+	}{
+		{"empty", nil, "empty", nil},
+		{"whitespace", []byte("  \n\t\r\n"), "empty", nil},
+		{"hex from openssl rand -hex 32", []byte(hexKey + "\n"), "hex key", hashOf(hexKey)},
+		{"passphrase", []byte("correct horse battery staple\n"), "holds 29 bytes", hashOf("correct horse battery staple")},
+		{"short", []byte("abc"), "holds 3 bytes", hashOf("abc")},
+		{"base64 of too few bytes", []byte(base64.StdEncoding.EncodeToString(raw[:16]) + "\n"), "base64 of 16 bytes", hashOf(base64.StdEncoding.EncodeToString(raw[:16]))},
+		{"32 characters and a newline", []byte(string(raw) + "\n"), "base64 of 24 bytes", raw},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseKeyFile(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseKeyFile(%q) error = %v, want %q", tt.data, err, tt.wantErr)
+			}
+			if got := IsLegacyKeyFile(tt.data); got != (tt.legacy != nil) {
+				t.Errorf("IsLegacyKeyFile(%q) = %v, want %v", tt.data, got, tt.legacy != nil)
+			}
+
+			key, err := ParseLegacyKeyFile(tt.data)
+			if tt.legacy == nil {
+				if err == nil {
+					t.Errorf("ParseLegacyKeyFile(%q) succeeded, want an error", tt.data)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(key, tt.legacy) {
+				t.Errorf("ParseLegacyKeyFile(%q) = %x, %v; want %x", tt.data, key, err, tt.legacy)
+			}
+		})
+	}
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	raw := bytes.Repeat([]byte{7}, EncryptionKeyLength)
+
+	path := filepath.Join(dir, "envi.key")
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(raw)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ReadKeyFile(path)
+	if err != nil {
+		t.Fatalf("ReadKeyFile() error = %v", err)
+	}
+	if !bytes.Equal(key, raw) {
+		t.Errorf("ReadKeyFile() = %x, want %x", key, raw)
+	}
+
+	var keyErr *KeyFileError
+	if _, err := ReadKeyFile(filepath.Join(dir, "missing.key")); !errors.As(err, &keyErr) {
+		t.Errorf("ReadKeyFile(missing) error = %v, want a KeyFileError", err)
+	}
+
+	empty := filepath.Join(dir, "empty.key")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadKeyFile(empty); !errors.As(err, &keyErr) {
+		t.Errorf("ReadKeyFile(empty) error = %v, want a KeyFileError", err)
+	}
+
+	// A passphrase file is only read with --legacy-key-file
+	legacy := filepath.Join(dir, "legacy.key")
+	if err := os.WriteFile(legacy, []byte("passphrase\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadKeyFile(legacy); !errors.As(err, &keyErr) {
+		t.Errorf("ReadKeyFile(legacy) error = %v, want a KeyFileError", err)
+	}
+	defer func(old bool) { LegacyKeyFile = old }(LegacyKeyFile)
+	LegacyKeyFile = true
+	if key, err := ReadKeyFile(legacy); err != nil || !bytes.Equal(key, KeyFromPassword("passphrase")) {
+		t.Errorf("ReadKeyFile(legacy) with --legacy-key-file = %x, %v; want the hash of the passphrase", key, err)
+	}
+}
+
+func TestGenerateKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "envi.key")
+	if err := GenerateKeyFile(path); err != nil {
+		t.Fatalf("GenerateKeyFile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsLegacyKeyFile(data) {
+		t.Errorf("generated key file %q is in the legacy format", data)
+	}
+	if _, err := ReadKeyFile(path); err != nil {
+		t.Errorf("ReadKeyFile() of a generated key file error = %v", err)
+	}
+}
+
+func TestMaskCRLFRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{9}, EncryptionKeyLength)
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"CRLF", "# Database\r\nDB_HOST=localhost\r\nexport DB_PASSWORD=two words\r\n\r\nEMPTY=\r\n", ""},
+		{"CRLF without final newline", "A=1\r\nB=2", ""},
+		{"LF", "A=1\nB=2\n", ""},
+		{"mixed line endings become the most common one", "A=1\r\nB=2\nC=3\r\n", "A=1\r\nB=2\r\nC=3\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want == "" {
+				want = tt.content
+			}
+
+			masked, err := MaskWithKey([]byte(tt.content), key, nil)
+			if err != nil {
+				t.Fatalf("MaskWithKey() error = %v", err)
+			}
+			if !IsMasked(masked) {
+				t.Fatalf("MaskWithKey() = %q, which isn't masked", masked)
+			}
+			unmasked, err := UnmaskWithKey(masked, key)
+			if err != nil {
+				t.Fatalf("UnmaskWithKey() error = %v", err)
+			}
+			if !bytes.Equal(unmasked, []byte(want)) {
+				t.Errorf("round trip = %q, want %q", unmasked, want)
+			}
+		})
+	}
+}
+
+func TestMaskIgnoresCRLF(t *testing.T) {
+	defer func(old bool) { DeterministicMasking = old }(DeterministicMasking)
+	DeterministicMasking = true
+
+	// A \r must never end up in an encrypted value, so both files mask the same values
+	key := bytes.Repeat([]byte{9}, EncryptionKeyLength)
+	crlf, err := MaskWithKey([]byte("A=1\r\nB=secret\r\n"), key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lf, err := MaskWithKey([]byte("A=1\nB=secret\n"), key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.ReplaceAll(string(crlf), "\r\n", "\n"); got != string(lf) {
+		t.Errorf("masked CRLF content %q differs from masked LF content %q", crlf, lf)
+	}
+}
+
+func TestZeroize(t *testing.T) {
+	for _, b := range [][]byte{nil, {}, []byte("secret password"), bytes.Repeat([]byte{0xff}, EncryptionKeyLength)} {
+		zeroize(b)
+		if !bytes.Equal(b, make([]byte, len(b))) {
+			t.Errorf("zeroize() left %x", b)
+		}
+	}
+}
+
+// withCachedKey caches key as if it had been derived from a password that passed the
+// checks for encrypting if checked is set, and clears the cache when the test ends
+func withCachedKey(t *testing.T, key []byte, checked bool) {
+	t.Helper()
+	keyCacheMu.Lock()
+	cachedKey, cachedKeyChecked = key, checked
+	keyCacheMu.Unlock()
+	t.Cleanup(ClearKeyCache)
+}
+
+func TestClearKeyCache(t *testing.T) {
+	cached := bytes.Repeat([]byte{0x5a}, EncryptionKeyLength)
+	withCachedKey(t, cached, true)
+
+	// Callers get a copy, which they can wipe without affecting the cache
+	key, err := getEncryptionKey(false)
+	if err != nil {
+		t.Fatalf("getEncryptionKey() error = %v", err)
+	}
+	if !bytes.Equal(key, cached) {
+		t.Fatalf("getEncryptionKey() = %x, want the cached key %x", key, cached)
+	}
+	zeroize(key)
+	if bytes.Equal(cached, make([]byte, EncryptionKeyLength)) {
+		t.Fatal("wiping the returned key wiped the cached key too")
+	}
+
+	ClearKeyCache()
+	if !bytes.Equal(cached, make([]byte, EncryptionKeyLength)) {
+		t.Errorf("ClearKeyCache() left the cached key in memory: %x", cached)
+	}
+	if cachedKey != nil {
+		t.Errorf("ClearKeyCache() kept a cached key")
+	}
+}
+
+func TestEncryptContentKeepsCachedKey(t *testing.T) {
+	cached := bytes.Repeat([]byte{0x21}, EncryptionKeyLength)
+	withCachedKey(t, cached, true)
+	want := append([]byte(nil), cached...)
+
+	// Each call wipes its own copy of the key, never the cached one
+	content := []byte("API_KEY=abc123\n")
+	encrypted, err := EncryptContent(content)
+	if err != nil {
+		t.Fatalf("EncryptContent() error = %v", err)
+	}
+	masked, err := MaskEnvContent(content)
+	if err != nil {
+		t.Fatalf("MaskEnvContent() error = %v", err)
+	}
+	if !bytes.Equal(cached, want) {
+		t.Fatalf("encrypting changed the cached key to %x", cached)
+	}
+
+	decrypted, err := DecryptContent(encrypted)
+	if err != nil {
+		t.Fatalf("DecryptContent() error = %v", err)
+	}
+	unmasked, err := UnmaskEnvContent(masked)
+	if err != nil {
+		t.Fatalf("UnmaskEnvContent() error = %v", err)
+	}
+	if !bytes.Equal(decrypted, content) || !bytes.Equal(unmasked, content) {
+		t.Errorf("round trips with the cached key = %q and %q, want %q", decrypted, unmasked, content)
+	}
+}
+
+func TestParseRawKeyOwnsKey(t *testing.T) {
+	// Callers wipe the key file contents once parsed, which must not wipe the key
+	raw := bytes.Repeat([]byte{2}, EncryptionKeyLength)
+	encoded := []byte(base64.StdEncoding.EncodeToString(raw) + "\n")
+	key, err := ParseRawKey(encoded, "ENVI_KEY")
+	if err != nil {
+		t.Fatalf("ParseRawKey() error = %v", err)
+	}
+	zeroize(encoded)
+	if !bytes.Equal(key, raw) {
+		t.Errorf("key changed when the encoded input was wiped: %x", key)
+	}
+}
+
+// withCipher selects a cipher as --cipher would for the rest of the test
+func withCipher(t *testing.T, name string) {
+	t.Helper()
+	old := CipherName
+	CipherName = name
+	t.Cleanup(func() { CipherName = old })
+}
+
+// sealed builds encrypted content by hand: header, then the base64 of the nonce and
+// the payload sealed with additionalData
+func sealed(t *testing.T, suite cipherSuite, key []byte, header string, payload, additionalData []byte) []byte {
+	t.Helper()
+	aead, err := suite.NewAEAD(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := bytes.Repeat([]byte{7}, aead.NonceSize())
+	return []byte(header + base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, payload, additionalData)))
+}
+
+// withChecksum returns the V2/V3 payload for content
+func withChecksum(content []byte) []byte {
+	checksum := sha256.Sum256(content)
+	return append(checksum[:], content...)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{3}, EncryptionKeyLength)
+	content := []byte("DB_PASSWORD=hunter2\nAPI_KEY=sk_live_0123456789\n")
+
+	tests := []struct {
+		cipher string
+		header string
+		other  string
+	}{
+		{CipherAESGCM, EncryptionHeaderV2, CipherChaCha20Poly1305},
+		{CipherChaCha20Poly1305, EncryptionHeaderV3 + "chacha20poly1305:", CipherAESGCM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cipher, func(t *testing.T) {
+			withCipher(t, tt.cipher)
+			encrypted, err := EncryptWithKey(content, key)
+			if err != nil {
+				t.Fatalf("EncryptWithKey() error = %v", err)
+			}
+			if !bytes.HasPrefix(encrypted, []byte(tt.header)) {
+				t.Errorf("EncryptWithKey() = %q, want header %q", encrypted, tt.header)
+			}
+			if !IsEncrypted(encrypted) || bytes.Contains(encrypted, []byte("hunter2")) {
+				t.Errorf("EncryptWithKey() = %q, want encrypted content", encrypted)
+			}
+
+			// The header picks the cipher, whatever --cipher is set to when decrypting
+			CipherName = tt.other
+			decrypted, err := DecryptWithKey(encrypted, key)
+			if err != nil {
+				t.Fatalf("DecryptWithKey() error = %v", err)
+			}
+			if !bytes.Equal(decrypted, content) {
+				t.Errorf("DecryptWithKey() = %q, want %q", decrypted, content)
+			}
+
+			if _, err := DecryptWithKey(encrypted, bytes.Repeat([]byte{4}, EncryptionKeyLength)); err == nil {
+				t.Error("DecryptWithKey() with the wrong key succeeded")
+			}
+		})
+	}
+}
+
+func TestDecryptHeaders(t *testing.T) {
+	key := bytes.Repeat([]byte{5}, EncryptionKeyLength)
+	content := []byte("A=1\nB=2\n")
+	aes, chacha := cipherSuites[CipherAESGCM], cipherSuites[CipherChaCha20Poly1305]
+	v3ChaCha := EncryptionHeaderV3 + "chacha20poly1305:"
+	v3AES := EncryptionHeaderV3 + "aes-gcm:"
+
+	tests := []struct {
+		name      string
+		encrypted []byte
+	}{
+		{"V1 is AES-GCM without a checksum or additional data", sealed(t, aes, key, EncryptionPrefix, content, nil)},
+		{"V2 is AES-GCM with a checksum", sealed(t, aes, key, EncryptionHeaderV2, withChecksum(content), []byte(EncryptionHeaderV2))},
+		{"V3 names ChaCha20-Poly1305", sealed(t, chacha, key, v3ChaCha, withChecksum(content), []byte(v3ChaCha))},
+		{"V3 names AES-GCM", sealed(t, aes, key, v3AES, withChecksum(content), []byte(v3AES))},
+		{"trailing newline", append(sealed(t, aes, key, EncryptionHeaderV2, withChecksum(content), []byte(EncryptionHeaderV2)), '\n')},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decrypted, err := DecryptWithKey(tt.encrypted, key)
+			if err != nil {
+				t.Fatalf("DecryptWithKey(%q) error = %v", tt.encrypted, err)
+			}
+			if !bytes.Equal(decrypted, content) {
+				t.Errorf("DecryptWithKey(%q) = %q, want %q", tt.encrypted, decrypted, content)
+			}
+		})
+	}
+}
+
+func TestDecryptHeadersInvalid(t *testing.T) {
+	key := bytes.Repeat([]byte{5}, EncryptionKeyLength)
+	content := []byte("A=1\n")
+	aes, chacha := cipherSuites[CipherAESGCM], cipherSuites[CipherChaCha20Poly1305]
+	v2 := sealed(t, aes, key, EncryptionHeaderV2, withChecksum(content), []byte(EncryptionHeaderV2))
+	v3 := sealed(t, chacha, key, EncryptionHeaderV3+"chacha20poly1305:", withChecksum(content), []byte(EncryptionHeaderV3+"chacha20poly1305:"))
+	v3Payload := v3[len(EncryptionHeaderV3+"chacha20poly1305:"):]
+
+	tests := []struct {
+		name      string
+		encrypted []byte
+		wantErr   string
+	}{
+		{"not encrypted", content, "not encrypted"},
+		{"unknown cipher", append([]byte(EncryptionHeaderV3+"rot13:"), v3Payload...), `unsupported cipher "rot13"`},
+		{"cipher name missing", []byte(EncryptionHeaderV3 + "abc"), "cipher name missing"},
+		{"V3 content under the V2 header", append([]byte(EncryptionHeaderV2), v3Payload...), "decryption failed"},
+		{"V2 content under a V3 header", append([]byte(EncryptionHeaderV3+"aes-gcm:"), v2[len(EncryptionHeaderV2):]...), "decryption failed"},
+		{"V2 content under the V1 header", append([]byte(EncryptionPrefix), v2[len(EncryptionHeaderV2):]...), "decryption failed"},
+		{"V3 content naming the other cipher", append([]byte(EncryptionHeaderV3+"aes-gcm:"), v3Payload...), "decryption failed"},
+		{"checksum mismatch", sealed(t, aes, key, EncryptionHeaderV2, append(make([]byte, sha256.Size), content...), []byte(EncryptionHeaderV2)), "checksum mismatch"},
+		{"checksum missing", sealed(t, aes, key, EncryptionHeaderV2, []byte("short"), []byte(EncryptionHeaderV2)), "checksum missing"},
+		{"concatenated blocks", append(append([]byte{}, v2...), v2...), "more than one encrypted block"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecryptWithKey(tt.encrypted, key)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("DecryptWithKey(%q) error = %v, want %q", tt.encrypted, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaskCiphers(t *testing.T) {
+	key := bytes.Repeat([]byte{6}, EncryptionKeyLength)
+	content := []byte("# Keys\nDB_PASSWORD=hunter2\nAPI_KEY=abc123\nEMPTY=\n")
+
+	tests := []struct {
+		cipher string
+		prefix string
+	}{
+		{CipherAESGCM, "DB_PASSWORD=" + MaskedPrefix},
+		{CipherChaCha20Poly1305, "DB_PASSWORD=" + MaskedPrefix + "chacha20poly1305:"},
+	}
+	var masked [][]byte
+	for _, tt := range tests {
+		withCipher(t, tt.cipher)
+		m, err := MaskWithKey(content, key, nil)
+		if err != nil {
+			t.Fatalf("MaskWithKey() with %s error = %v", tt.cipher, err)
+		}
+		if !bytes.Contains(m, []byte("\n"+tt.prefix)) {
+			t.Errorf("MaskWithKey() with %s = %q, want a value starting %q", tt.cipher, m, tt.prefix)
+		}
+		if tt.cipher == CipherAESGCM && bytes.Contains(m, []byte(CipherAESGCM)) {
+			t.Errorf("MaskWithKey() with %s = %q, want no cipher name", tt.cipher, m)
+		}
+		masked = append(masked, m)
+	}
+
+	// Unmasking reads the cipher from each value, so a file may mix them
+	withCipher(t, CipherAESGCM)
+	aesLines, _ := SplitLines(masked[0])
+	chachaLines, _ := SplitLines(masked[1])
+	mixed := []byte(strings.Join([]string{aesLines[0], chachaLines[1], aesLines[2], aesLines[3], ""}, "\n"))
+	for _, m := range append(masked, mixed) {
+		unmasked, err := UnmaskWithKey(m, key)
+		if err != nil {
+			t.Fatalf("UnmaskWithKey(%q) error = %v", m, err)
+		}
+		if !bytes.Equal(unmasked, content) {
+			t.Errorf("UnmaskWithKey(%q) = %q, want %q", m, unmasked, content)
+		}
+	}
+
+	unknown := bytes.Replace(masked[1], []byte("chacha20poly1305:"), []byte("rot13:"), 1)
+	if _, err := UnmaskWithKey(unknown, key); err == nil || !strings.Contains(err.Error(), `unsupported cipher "rot13"`) {
+		t.Errorf("UnmaskWithKey() with an unknown cipher error = %v", err)
+	}
+}
+
+func TestSelectedCipher(t *testing.T) {
+	for name, want := range map[string]string{"": DefaultCipher, CipherAESGCM: CipherAESGCM, CipherChaCha20Poly1305: CipherChaCha20Poly1305} {
+		withCipher(t, name)
+		suite, err := selectedCipher()
+		if err != nil || suite.Name() != want {
+			t.Errorf("selectedCipher() with --cipher %q = %v, %v; want %s", name, suite, err, want)
+		}
+	}
+
+	withCipher(t, "rot13")
+	if _, err := EncryptWithKey([]byte("A=1\n"), bytes.Repeat([]byte{1}, EncryptionKeyLength)); err == nil {
+		t.Error("EncryptWithKey() with an unsupported cipher succeeded")
+	}
+}
+
+func TestEncryptChecksCachedDecryptKey(t *testing.T) {
+	t.Setenv(PasswordFileEnvVar, "")
+	content := []byte("A=1\n")
+
+	// A short password is enough to decrypt, but not to encrypt
+	t.Setenv(PasswordEnvVar, "short")
+	if _, err := getEncryptionKey(false); err != nil {
+		t.Fatalf("getEncryptionKey(false) with a short password error = %v", err)
+	}
+	t.Cleanup(ClearKeyCache)
+	if _, err := EncryptContent(content); err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Errorf("EncryptContent() with a cached short password error = %v, want the minimum length", err)
+	}
+	if _, err := MaskEnvContent(content); err == nil || !strings.Contains(err.Error(), "at least") {
+		t.Errorf("MaskEnvContent() with a cached short password error = %v, want the minimum length", err)
+	}
+
+	// A key cached without confirmation is derived again, and the new one is cached
+	ClearKeyCache()
+	withCachedKey(t, KeyFromPassword("unconfirmed"), false)
+	t.Setenv(PasswordEnvVar, "long enough")
+	key, err := getEncryptionKey(true)
+	if err != nil {
+		t.Fatalf("getEncryptionKey(true) error = %v", err)
+	}
+	if !bytes.Equal(key, KeyFromPassword("long enough")) || !cachedKeyChecked {
+		t.Errorf("getEncryptionKey(true) = %x, checked %v; want the key of the password checked again", key, cachedKeyChecked)
+	}
+
+	// stdin can't be read again, so a short password from it is refused
+	defer func(old bool) { PasswordFromStdin = old }(PasswordFromStdin)
+	PasswordFromStdin = true
+	ClearKeyCache()
+	withCachedKey(t, KeyFromPassword("short"), false)
+	if _, err := getEncryptionKey(true); err == nil {
+		t.Error("getEncryptionKey(true) with a short password from stdin succeeded")
+	}
+	if key, err := getEncryptionKey(false); err != nil || !bytes.Equal(key, KeyFromPassword("short")) {
+		t.Errorf("getEncryptionKey(false) = %x, %v; want the cached key", key, err)
+	}
+}
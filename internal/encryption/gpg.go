@@ -0,0 +1,119 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dexterity-inc/envi/internal/logging"
+)
+
+// This file contains the support for key files encrypted with GnuPG. The key itself is
+// stored in the usual key file format; gpg decrypts it with the user's secret key, and
+// the GPG agent asks for the passphrase when it needs one.
+
+// gpgProgram is the GnuPG executable
+var gpgProgram = "gpg"
+
+// IsGPGKeyFile reports whether a key file is meant to be encrypted with GPG, going by
+// its .gpg or .asc extension
+func IsGPGKeyFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".gpg" || ext == ".asc"
+}
+
+// GPGAvailable reports whether gpg can be run
+func GPGAvailable() bool {
+	_, err := exec.LookPath(gpgProgram)
+	return err == nil
+}
+
+// readGPGKeyFile returns the key from a GPG-encrypted key file. A file gpg can't
+// decrypt, or any file when gpg isn't installed, is read as a plain key file if it
+// holds a base64 or raw key, so renaming a key file doesn't break it.
+func readGPGKeyFile(path string, data []byte) ([]byte, error) {
+	if !GPGAvailable() {
+		if key, err := ParseKeyFile(data); err == nil {
+			logging.Debug("gpg not found, reading the key file as a plain key file", "path", path)
+			return key, nil
+		}
+		return nil, errors.New("it is encrypted with GPG, but gpg was not found; install GnuPG or use a plain key file")
+	}
+
+	decrypted, gpgErr := decryptWithGPG(path)
+	if gpgErr != nil {
+		if key, err := ParseKeyFile(data); err == nil {
+			logging.Debug("gpg could not decrypt the key file, reading it as a plain key file", "path", path, "error", gpgErr)
+			return key, nil
+		}
+		return nil, gpgErr
+	}
+
+	key, err := parseKeyFileData(path, decrypted)
+	zeroize(decrypted)
+	if err != nil {
+		return nil, fmt.Errorf("decrypted with gpg, but %w", err)
+	}
+	return key, nil
+}
+
+// decryptWithGPG runs gpg to decrypt a file and returns its output
+func decryptWithGPG(path string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(gpgProgram, "--quiet", "--decrypt", path)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		zeroize(output)
+		return nil, fmt.Errorf("gpg could not decrypt it: %s", gpgErrorMessage(stderr.String(), err))
+	}
+	return output, nil
+}
+
+// GenerateGPGKeyFile writes a new random key to path, encrypted with gpg to recipient's
+// public key. A path ending in .asc gets an ASCII-armored file.
+func GenerateGPGKeyFile(path, recipient string) error {
+	if !GPGAvailable() {
+		return errors.New("gpg was not found; install GnuPG to encrypt key files")
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return err
+	}
+	encoded := []byte(encodeKey(key))
+	defer zeroize(key)
+	defer zeroize(encoded)
+
+	// Key files may live in a data directory that doesn't exist yet
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	args := []string{"--quiet", "--batch", "--yes", "--encrypt", "--recipient", recipient, "--output", path}
+	if strings.ToLower(filepath.Ext(path)) == ".asc" {
+		args = append(args, "--armor")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(gpgProgram, args...)
+	cmd.Stdin = bytes.NewReader(encoded)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gpg could not encrypt the key to %s: %s", recipient, gpgErrorMessage(stderr.String(), err))
+	}
+	return os.Chmod(path, 0600)
+}
+
+// gpgErrorMessage returns the last message gpg printed, without its "gpg: " prefix,
+// or err if it printed nothing
+func gpgErrorMessage(stderr string, err error) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return strings.TrimPrefix(last, "gpg: ")
+	}
+	return err.Error()
+}
@@ -0,0 +1,50 @@
+// Package logging provides the debug log shown with --verbose. It records the decisions
+// envi makes, such as where the token came from or which encryption mode was detected,
+// as structured key=value lines on stderr.
+//
+// Never pass secret values (tokens, passwords, keys or variable values) to Debug.
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Verbose enables debug logging (--verbose)
+var Verbose bool
+
+// logger writes debug lines to stderr, so stdout still only carries results
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+// Debug logs a decision point with key/value attributes when --verbose is set
+func Debug(msg string, args ...interface{}) {
+	if Verbose {
+		logger.Debug(msg, args...)
+	}
+}
+
+// Transport wraps an HTTP transport to log each request's method, endpoint, status and
+// duration. The query string and headers are left out, as they may hold credentials.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return debugTransport{base: base}
+}
+
+// debugTransport is the http.RoundTripper returned by Transport
+type debugTransport struct {
+	base http.RoundTripper
+}
+
+func (t debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	duration := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		Debug("API request failed", "method", req.Method, "host", req.URL.Host, "path", req.URL.Path, "duration", duration, "error", err)
+		return nil, err
+	}
+	Debug("API request", "method", req.Method, "host", req.URL.Host, "path", req.URL.Path, "status", resp.StatusCode, "duration", duration)
+	return resp, nil
+}
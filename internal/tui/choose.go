@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// chooseModel lets the user pick one option from a list
+type chooseModel struct {
+	title       string
+	description string
+	options     []string
+	index       int
+	done        bool
+}
+
+// Choose shows a list of options and returns the index of the one picked, starting
+// with the option at selected highlighted
+func Choose(title, description string, options []string, selected int) (int, error) {
+	m := chooseModel{
+		title:       title,
+		description: description,
+		options:     options,
+		index:       selected,
+	}
+
+	p := tea.NewProgram(m)
+
+	model, err := p.Run()
+	if err != nil {
+		return 0, err
+	}
+
+	finalModel := model.(chooseModel)
+	if !finalModel.done {
+		return 0, fmt.Errorf("canceled")
+	}
+
+	return finalModel.index, nil
+}
+
+// Init initializes the choose model
+func (m chooseModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update moves the selection and confirms it
+func (m chooseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc", "ctrl+c", "q":
+			return m, tea.Quit
+
+		case "up", "k", "shift+tab":
+			m.index = (m.index + len(m.options) - 1) % len(m.options)
+
+		case "down", "j", "tab":
+			m.index = (m.index + 1) % len(m.options)
+
+		case "enter":
+			m.done = true
+			return m, tea.Quit
+		}
+	}
+
+	return m, nil
+}
+
+// View renders the options with the current one highlighted
+func (m chooseModel) View() string {
+	// Render nothing once finished so the list is cleared
+	if m.done {
+		return ""
+	}
+
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render(m.title))
+	b.WriteString("\n")
+	if m.description != "" {
+		b.WriteString(descriptionStyle.Render(m.description))
+		b.WriteString("\n")
+	}
+
+	for i, option := range m.options {
+		if i == m.index {
+			b.WriteString(selectedOptionStyle.Render("> " + option))
+		} else {
+			b.WriteString(optionStyle.Render("  " + option))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("↑/↓ choose • enter confirm • esc cancel"))
+
+	return appStyle.Render(b.String())
+}
@@ -0,0 +1,172 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Conflict describes a variable that has different values in two sources
+type Conflict struct {
+	Key         string
+	LocalValue  string
+	RemoteValue string
+	LocalLabel  string // Name of the source the local value came from
+	RemoteLabel string // Name of the source the remote value came from
+}
+
+// Conflict resolver styles
+var (
+	selectedOptionStyle = lipgloss.NewStyle().
+				Foreground(primaryColor).
+				Bold(true)
+
+	optionStyle = lipgloss.NewStyle().
+			Foreground(subtextColor)
+
+	keyNameStyle = lipgloss.NewStyle().
+			Foreground(warningColor).
+			Bold(true).
+			MarginBottom(1)
+)
+
+// conflictModel manages the conflict resolver state
+type conflictModel struct {
+	conflicts []Conflict
+	index     int
+	useRemote bool
+	reveal    bool
+	redact    func(string) string
+	resolved  map[string]string
+	done      bool
+	width     int
+}
+
+// ResolveConflicts lets the user pick the local or remote value for each conflicting key.
+// Values are shown through redact unless reveal is set; the user can toggle this with 'v'.
+// It returns the chosen value for every key.
+func ResolveConflicts(conflicts []Conflict, redact func(string) string, reveal bool) (map[string]string, error) {
+	m := conflictModel{
+		conflicts: conflicts,
+		reveal:    reveal,
+		redact:    redact,
+		resolved:  make(map[string]string),
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+
+	model, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	finalModel := model.(conflictModel)
+	if !finalModel.done {
+		return nil, fmt.Errorf("canceled")
+	}
+
+	return finalModel.resolved, nil
+}
+
+// Init initializes the conflict model
+func (m conflictModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles key presses and records resolutions
+func (m conflictModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "ctrl+c", "q":
+			return m, tea.Quit
+
+		case "up", "down", "left", "right", "tab", "k", "j":
+			m.useRemote = !m.useRemote
+
+		case "v":
+			m.reveal = !m.reveal
+
+		case "enter":
+			m.resolve(m.index)
+			m.index++
+
+		case "a":
+			// Apply the current choice to this and all remaining conflicts
+			for i := m.index; i < len(m.conflicts); i++ {
+				m.resolve(i)
+			}
+			m.index = len(m.conflicts)
+		}
+
+		if m.index >= len(m.conflicts) {
+			m.done = true
+			return m, tea.Quit
+		}
+	}
+
+	return m, nil
+}
+
+// resolve records the current choice for the conflict at index i
+func (m *conflictModel) resolve(i int) {
+	conflict := m.conflicts[i]
+	if m.useRemote {
+		m.resolved[conflict.Key] = conflict.RemoteValue
+	} else {
+		m.resolved[conflict.Key] = conflict.LocalValue
+	}
+}
+
+// display returns a value for rendering, redacted unless revealed
+func (m conflictModel) display(value string) string {
+	if m.reveal || m.redact == nil {
+		return value
+	}
+	return m.redact(value)
+}
+
+// View renders the current conflict
+func (m conflictModel) View() string {
+	if m.index >= len(m.conflicts) {
+		return ""
+	}
+
+	conflict := m.conflicts[m.index]
+
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render(fmt.Sprintf("Resolve merge conflict (%d/%d)", m.index+1, len(m.conflicts))))
+	b.WriteString("\n")
+	b.WriteString(keyNameStyle.Render(conflict.Key))
+	b.WriteString("\n")
+
+	options := []struct {
+		label    string
+		value    string
+		selected bool
+	}{
+		{conflict.LocalLabel, conflict.LocalValue, !m.useRemote},
+		{conflict.RemoteLabel, conflict.RemoteValue, m.useRemote},
+	}
+
+	for _, option := range options {
+		line := fmt.Sprintf("%s: %s", option.label, m.display(option.value))
+		if option.selected {
+			b.WriteString(selectedOptionStyle.Render("> " + line))
+		} else {
+			b.WriteString(optionStyle.Render("  " + line))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render("↑/↓ choose • enter confirm • a apply to all remaining • v reveal values • esc cancel"))
+
+	return appStyle.Render(b.String())
+}
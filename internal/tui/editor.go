@@ -0,0 +1,208 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// EditorVariable is a variable shown in the variable editor
+type EditorVariable struct {
+	Key     string
+	Value   string
+	Include bool // Whether the variable is kept
+	Mask    bool // Whether the value should be masked when saved
+	Secret  bool // Whether the value is hidden in the display until revealed
+}
+
+// Variable editor styles
+var (
+	cursorRowStyle = lipgloss.NewStyle().
+			Foreground(primaryColor).
+			Bold(true)
+
+	excludedRowStyle = lipgloss.NewStyle().
+				Foreground(subtextColor).
+				Strikethrough(true)
+)
+
+// editorModel manages the variable editor state
+type editorModel struct {
+	title     string
+	variables []EditorVariable
+	cursor    int
+	reveal    bool
+	editing   bool
+	input     textinput.Model
+	redact    func(string) string
+	submitted bool
+}
+
+// EditVariables lets the user choose which variables to keep, edit their values and
+// mark values to mask. Secret values are shown through redact until revealed with 'v'.
+// It returns the edited variables in their original order, including excluded ones.
+func EditVariables(title string, variables []EditorVariable, redact func(string) string) ([]EditorVariable, error) {
+	input := textinput.New()
+	input.Prompt = "= "
+	input.PromptStyle = lipgloss.NewStyle().Foreground(primaryColor)
+	input.TextStyle = lipgloss.NewStyle().Foreground(textColor)
+
+	m := editorModel{
+		title:     title,
+		variables: append([]EditorVariable(nil), variables...),
+		input:     input,
+		redact:    redact,
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+
+	model, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	finalModel := model.(editorModel)
+	if !finalModel.submitted {
+		return nil, fmt.Errorf("canceled")
+	}
+
+	return finalModel.variables, nil
+}
+
+// Init initializes the editor model
+func (m editorModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles key presses for list navigation and value editing
+func (m editorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	// While editing a value, keys go to the text input
+	if m.editing {
+		switch keyMsg.String() {
+		case "enter":
+			m.variables[m.cursor].Value = m.input.Value()
+			m.editing = false
+			m.input.Blur()
+			return m, nil
+		case "esc":
+			m.editing = false
+			m.input.Blur()
+			return m, nil
+		}
+
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	switch keyMsg.String() {
+	case "esc", "ctrl+c", "q":
+		return m, tea.Quit
+
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+
+	case "down", "j":
+		if m.cursor < len(m.variables)-1 {
+			m.cursor++
+		}
+
+	case " ", "x":
+		if len(m.variables) > 0 {
+			m.variables[m.cursor].Include = !m.variables[m.cursor].Include
+		}
+
+	case "m":
+		if len(m.variables) > 0 {
+			m.variables[m.cursor].Mask = !m.variables[m.cursor].Mask
+		}
+
+	case "v":
+		m.reveal = !m.reveal
+
+	case "e", "enter":
+		if len(m.variables) > 0 {
+			m.editing = true
+			m.input.SetValue(m.variables[m.cursor].Value)
+			m.input.CursorEnd()
+			m.input.Focus()
+			return m, textinput.Blink
+		}
+
+	case "s", "ctrl+s":
+		m.submitted = true
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+// display returns a value for rendering, redacted for secrets unless revealed
+func (m editorModel) display(v EditorVariable) string {
+	if !v.Secret || m.reveal || m.redact == nil {
+		return v.Value
+	}
+	return m.redact(v.Value)
+}
+
+// View renders the variable list
+func (m editorModel) View() string {
+	var b strings.Builder
+
+	included := 0
+	for _, v := range m.variables {
+		if v.Include {
+			included++
+		}
+	}
+
+	b.WriteString(titleStyle.Render(m.title))
+	b.WriteString("\n")
+	b.WriteString(descriptionStyle.Render(fmt.Sprintf("%d of %d variables selected", included, len(m.variables))))
+	b.WriteString("\n")
+
+	for i, v := range m.variables {
+		check := "[ ]"
+		if v.Include {
+			check = "[x]"
+		}
+		mask := "  "
+		if v.Mask {
+			mask = "🔒"
+		}
+
+		value := m.display(v)
+		if m.editing && i == m.cursor {
+			value = m.input.View()
+		}
+
+		line := fmt.Sprintf("%s %s %s=%s", check, mask, v.Key, value)
+		switch {
+		case i == m.cursor:
+			b.WriteString(cursorRowStyle.Render("> " + line))
+		case !v.Include:
+			b.WriteString(excludedRowStyle.Render("  " + line))
+		default:
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n")
+	}
+
+	if m.editing {
+		b.WriteString(helpStyle.Render("enter save value • esc cancel edit"))
+	} else {
+		b.WriteString(helpStyle.Render("↑/↓ move • space include • e edit • m mask • v reveal values • s push • esc cancel"))
+	}
+
+	return appStyle.Render(b.String())
+}
@@ -0,0 +1,86 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Spinner styles
+var (
+	spinnerStyle = lipgloss.NewStyle().
+			Foreground(primaryColor)
+
+	spinnerMessageStyle = lipgloss.NewStyle().
+				Foreground(subtextColor)
+)
+
+// spinnerDoneMsg is sent when the spinner's work has finished
+type spinnerDoneMsg struct {
+	err error
+}
+
+// spinnerModel shows a spinner and a message until the work is done
+type spinnerModel struct {
+	spinner spinner.Model
+	message string
+	done    bool
+	err     error
+}
+
+func (m spinnerModel) Init() tea.Cmd {
+	return m.spinner.Tick
+}
+
+func (m spinnerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case spinnerDoneMsg:
+		m.done = true
+		m.err = msg.err
+		return m, tea.Quit
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m spinnerModel) View() string {
+	// Render nothing once finished so the spinner line is cleared
+	if m.done {
+		return ""
+	}
+	return m.spinner.View() + " " + spinnerMessageStyle.Render(m.message)
+}
+
+// WithSpinner runs fn while showing a spinner and message on stderr, and returns fn's error.
+// The spinner is removed once fn returns. If the user interrupts, it returns an error
+// without waiting for fn.
+func WithSpinner(message string, fn func() error) error {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = spinnerStyle
+
+	m := spinnerModel{spinner: s, message: message}
+	p := tea.NewProgram(m, tea.WithOutput(os.Stderr), tea.WithInput(nil))
+
+	go func() {
+		p.Send(spinnerDoneMsg{err: fn()})
+	}()
+
+	model, err := p.Run()
+	if err != nil {
+		return err
+	}
+
+	finalModel := model.(spinnerModel)
+	if !finalModel.done {
+		return fmt.Errorf("canceled")
+	}
+
+	return finalModel.err
+}
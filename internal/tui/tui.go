@@ -114,8 +114,14 @@ type InputField struct {
 	Secret      bool   // Whether to mask input (for passwords)
 	Required    bool   // Whether the field is required
 	Help        string // Help text for the field
+	MinLength   int    // Minimum number of characters, checked on submit (0 for no minimum)
+	MaxLength   int    // Maximum number of characters accepted (0 for no limit)
+	Confirms    string // Label of an earlier field this field must repeat, e.g. a password
 }
 
+// maxConfirmAttempts is how many times a confirmation can be mistyped before the form gives up
+const maxConfirmAttempts = 3
+
 // InputModel manages the input form state
 type InputModel struct {
 	title       string
@@ -125,6 +131,8 @@ type InputModel struct {
 	focusIndex  int
 	submitted   bool
 	err         error
+	failed      error // Set when the form gave up, e.g. after repeated confirmation mismatches
+	mismatches  int
 	help        help.Model
 	keyMap      keyMap
 	width       int
@@ -167,6 +175,11 @@ func New(title, description string, fields []InputField) InputModel {
 		t.Width = 25 
 		t.Prompt = ""
 		
+		// Longer input, including pasted text, is cut off at the limit
+		if field.MaxLength > 0 {
+			t.CharLimit = field.MaxLength
+		}
+		
 		// Configure password masking if needed
 		if field.Secret {
 			t.EchoMode = textinput.EchoPassword
@@ -237,6 +250,9 @@ func (m InputModel) Start() (map[string]string, error) {
 	}
 	
 	finalModel := model.(InputModel)
+	if finalModel.failed != nil {
+		return nil, finalModel.failed
+	}
 	
 	// Check if form was submitted or canceled
 	if !finalModel.submitted {
@@ -276,6 +292,9 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.submitted = true
 				return m, tea.Quit
 			}
+			if m.failed != nil {
+				return m, tea.Quit
+			}
 			
 		case key.Matches(msg, m.keyMap.ShowHelp):
 			m.showHelp = !m.showHelp
@@ -354,11 +373,46 @@ func (m *InputModel) validateFields() bool {
 			m.updateFocus()
 			return false
 		}
+		if field.MinLength > 0 && len([]rune(m.inputs[i].Value())) < field.MinLength {
+			m.err = fmt.Errorf("'%s' must be at least %d characters", field.Label, field.MinLength)
+			// Focus the field that is too short
+			m.focusIndex = i
+			m.updateFocus()
+			return false
+		}
+		if field.Confirms != "" && m.inputs[i].Value() != m.valueOf(field.Confirms) {
+			what := "entries"
+			if field.Secret {
+				what = "passwords"
+			}
+			m.mismatches++
+			if m.mismatches >= maxConfirmAttempts {
+				m.failed = fmt.Errorf("%s do not match", what)
+				return false
+			}
+			m.err = fmt.Errorf("%s do not match, try again (%d of %d attempts left)", what,
+				maxConfirmAttempts-m.mismatches, maxConfirmAttempts)
+			// Clear the confirmation and let the user retype it
+			m.inputs[i].SetValue("")
+			m.focusIndex = i
+			m.updateFocus()
+			return false
+		}
 	}
 	m.err = nil
 	return true
 }
 
+// valueOf returns the current value of the field with the given label
+func (m *InputModel) valueOf(label string) string {
+	for i, field := range m.fields {
+		if field.Label == label {
+			return m.inputs[i].Value()
+		}
+	}
+	return ""
+}
+
 // updateInputs sends the update message to the focused input only
 func (m *InputModel) updateInputs(msg tea.Msg) tea.Cmd {
 	var cmds []tea.Cmd
@@ -390,8 +444,9 @@ func (m *InputModel) renderField(index int) string {
 		style = blurredInputStyle
 	}
 	
-	// Create the input field with appropriate styling
-	renderedInput := style.Render(input.View())
+	// Create the input field with appropriate styling. The box has a fixed width so
+	// it doesn't grow or shrink with the input; longer values scroll inside it.
+	renderedInput := style.Width(input.Width + 3).Render(input.View())
 	
 	// Add label with required indicator if needed
 	label := labelStyle.Render(field.Label)
@@ -451,17 +506,28 @@ func (m InputModel) View() string {
 	return appStyle.Render(b.String())
 }
 
-// GetPassword prompts for a password with optional confirmation
-func GetPassword(title string, confirm bool) (string, error) {
+// maxPasswordLength caps password input so a stray paste can't flood the form
+const maxPasswordLength = 1024
+
+// GetPassword prompts for a password with optional confirmation. The form can't be
+// submitted with a password shorter than minLength (0 for no minimum). A mistyped
+// confirmation is asked for again in the same form, up to maxConfirmAttempts times.
+func GetPassword(title string, confirm bool, minLength int) (string, error) {
 	// Set up the password field
 	var fields []InputField
 	
+	help := ""
+	if minLength > 0 {
+		help = fmt.Sprintf("Minimum %d characters", minLength)
+	}
 	fields = append(fields, InputField{
 		Label:       "Password",
 		Placeholder: "Enter password",
 		Secret:      true,
 		Required:    true,
-		Help:        "Minimum 8 characters",
+		Help:        help,
+		MinLength:   minLength,
+		MaxLength:   maxPasswordLength,
 	})
 	
 	// Add confirmation field if requested
@@ -472,28 +538,20 @@ func GetPassword(title string, confirm bool) (string, error) {
 			Secret:      true,
 			Required:    true,
 			Help:        "Re-enter the same password",
+			MaxLength:   maxPasswordLength,
+			Confirms:    "Password",
 		})
 	}
 	
 	// Create the input model
 	model := New(title, "Password will not be displayed", fields)
 	
-	// Run the form
+	// Run the form. It only submits once the confirmation matches.
 	result, err := model.Start()
 	if err != nil {
 		return "", err
 	}
 	
-	// For confirmation, check that passwords match
-	if confirm {
-		password := result["Password"]
-		confirmation := result["Confirm"]
-		
-		if password != confirmation {
-			return "", fmt.Errorf("passwords do not match")
-		}
-	}
-	
 	return result["Password"], nil
 }
 
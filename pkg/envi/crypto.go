@@ -0,0 +1,87 @@
+package envi
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// Mode selects how content is protected before it is pushed
+type Mode int
+
+const (
+	// ModeNone pushes content as-is
+	ModeNone Mode = iota
+	// ModeMask encrypts values and keeps variable names visible
+	ModeMask
+	// ModeEncrypt encrypts the whole file
+	ModeEncrypt
+)
+
+// KeyFromPassword derives an encryption key from a password, the same way the
+// envi command does
+func KeyFromPassword(password string) []byte {
+	return encryption.KeyFromPassword(password)
+}
+
+// KeyFromFile reads an encryption key file holding exactly 32 raw bytes or the base64
+// encoding of 32 bytes. Any other content is an error.
+func KeyFromFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	return encryption.ParseKeyFile(data)
+}
+
+// KeyFromLegacyFile is KeyFromFile for key files of older versions of envi, whose other
+// content is hashed into a key, the way the envi command reads them with --legacy-key-file
+func KeyFromLegacyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	return encryption.ParseLegacyKeyFile(data)
+}
+
+// Encrypt encrypts the whole content with AES-256-GCM
+func Encrypt(content, key []byte) ([]byte, error) {
+	return encryption.EncryptWithKey(content, key)
+}
+
+// Mask encrypts every value in .env content, keeping variable names and comments visible
+func Mask(content, key []byte) ([]byte, error) {
+	return encryption.MaskWithKey(content, key, nil)
+}
+
+// Decrypt decrypts content produced by Encrypt or Mask. Plain content is returned unchanged.
+func Decrypt(content, key []byte) ([]byte, error) {
+	switch {
+	case encryption.IsEncrypted(content):
+		return encryption.DecryptWithKey(content, key)
+	case encryption.IsMasked(content):
+		return encryption.UnmaskWithKey(content, key)
+	default:
+		return content, nil
+	}
+}
+
+// IsProtected reports whether content is encrypted or contains masked values
+func IsProtected(content []byte) bool {
+	return encryption.IsEncrypted(content) || encryption.IsMasked(content)
+}
+
+// protect applies mode to content
+func protect(content, key []byte, mode Mode) ([]byte, error) {
+	switch mode {
+	case ModeNone:
+		return content, nil
+	case ModeMask:
+		return Mask(content, key)
+	case ModeEncrypt:
+		return Encrypt(content, key)
+	default:
+		return nil, fmt.Errorf("unknown mode %d", mode)
+	}
+}
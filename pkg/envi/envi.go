@@ -0,0 +1,9 @@
+// Package envi provides building blocks of envi for use from other Go programs:
+// storing .env files in GitHub Gists, encrypting or masking their values, parsing
+// them and merging them. The envi command uses the same Gist transfer, parser and
+// encryption formats, so their results are interchangeable, but its commands do more
+// than these functions, such as asking how to resolve merge conflicts.
+//
+// Unlike the envi command, nothing in this package prompts, prints or exits.
+// Keys are passed in explicitly and failures are returned as errors.
+package envi
@@ -0,0 +1,209 @@
+package envi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/google/go-github/v37/github"
+	"golang.org/x/oauth2"
+)
+
+// DefaultFilename is the Gist file that is read and written unless another is given
+const DefaultFilename = ".env"
+
+// ErrFileNotFound is returned by Pull when the Gist has no file with the requested name
+var ErrFileNotFound = errors.New("file not found in Gist")
+
+// ErrFileTruncated is returned by Pull when the file is larger than the GitHub API
+// returns, so its content can't be read intact
+var ErrFileTruncated = errors.New("file is larger than the GitHub API returns")
+
+// PushOptions configures Push
+type PushOptions struct {
+	// Token is a GitHub token with the gist scope. It is ignored if HTTPClient is set.
+	Token string
+	// HTTPClient is an authenticated client to use instead of Token
+	HTTPClient *http.Client
+	// BaseURL is the address of a GitHub Enterprise Server, such as
+	// https://github.example.com. Public GitHub is used when it is empty.
+	BaseURL string
+
+	// GistID is the Gist to update. A new Gist is created when it is empty.
+	GistID string
+	// Description sets the Gist description. When updating, an empty description
+	// leaves the current one unchanged.
+	Description string
+	// Public creates a public Gist instead of a secret one. It has no effect on updates.
+	Public bool
+
+	// Files maps Gist filenames to their content. When updating, files not listed
+	// here are left as they are.
+	Files map[string][]byte
+	// Mode selects how each file is protected before upload
+	Mode Mode
+	// Key is the encryption key, required unless Mode is ModeNone
+	Key []byte
+}
+
+// Push uploads files to a new or existing Gist and returns the Gist ID
+func Push(ctx context.Context, opts PushOptions) (string, error) {
+	if len(opts.Files) == 0 {
+		return "", errors.New("no files to push")
+	}
+	if opts.Mode != ModeNone && len(opts.Key) == 0 {
+		return "", errors.New("an encryption key is required to mask or encrypt files")
+	}
+
+	files := make(map[github.GistFilename]github.GistFile, len(opts.Files))
+	for name, content := range opts.Files {
+		protected, err := protect(content, opts.Key, opts.Mode)
+		if err != nil {
+			return "", fmt.Errorf("protecting %s: %w", name, err)
+		}
+		files[github.GistFilename(name)] = github.GistFile{Content: github.String(string(protected))}
+	}
+
+	client, err := newClient(ctx, opts.Token, opts.HTTPClient, opts.BaseURL)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.GistID == "" {
+		gist, _, err := client.Gists.Create(ctx, &github.Gist{
+			Description: github.String(opts.Description),
+			Public:      github.Bool(opts.Public),
+			Files:       files,
+		})
+		if err != nil {
+			return "", err
+		}
+		return gist.GetID(), nil
+	}
+
+	gist := &github.Gist{Files: files}
+	if opts.Description != "" {
+		gist.Description = github.String(opts.Description)
+	}
+	if _, _, err := client.Gists.Edit(ctx, opts.GistID, gist); err != nil {
+		return "", err
+	}
+	return opts.GistID, nil
+}
+
+// PullOptions configures Pull
+type PullOptions struct {
+	// Token is a GitHub token with the gist scope. It is ignored if HTTPClient is set.
+	Token string
+	// HTTPClient is an authenticated client to use instead of Token
+	HTTPClient *http.Client
+	// BaseURL is the address of a GitHub Enterprise Server, such as
+	// https://github.example.com. Public GitHub is used when it is empty.
+	BaseURL string
+
+	// GistID is the Gist to read from
+	GistID string
+	// Filename is the Gist file to read (default DefaultFilename)
+	Filename string
+	// Key, if set, is used to decrypt encrypted or masked content. Without it the
+	// content is returned as stored.
+	Key []byte
+}
+
+// Pull downloads a file from a Gist, decrypting it if a key is given
+func Pull(ctx context.Context, opts PullOptions) ([]byte, error) {
+	filename := opts.Filename
+	if filename == "" {
+		filename = DefaultFilename
+	}
+
+	// Only the requested file needs decrypting
+	key := opts.Key
+	opts.Key = nil
+	files, err := PullFiles(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.Name != filename {
+			continue
+		}
+		if file.Truncated {
+			return nil, fmt.Errorf("%s is %d bytes: %w", filename, file.Size, ErrFileTruncated)
+		}
+		if len(key) == 0 {
+			return file.Content, nil
+		}
+		return Decrypt(file.Content, key)
+	}
+	return nil, fmt.Errorf("%s: %w", filename, ErrFileNotFound)
+}
+
+// File is a file downloaded from a Gist
+type File struct {
+	Name    string
+	Content []byte
+	// Size is the size of the whole file in bytes
+	Size int
+	// Truncated is set when the file is larger than the GitHub API returns. Content
+	// then holds only the start of the file and is never decrypted.
+	Truncated bool
+}
+
+// PullFiles downloads every file in a Gist, in alphabetical order, decrypting the
+// encrypted and masked ones if a key is given. Filename is ignored.
+func PullFiles(ctx context.Context, opts PullOptions) ([]File, error) {
+	if opts.GistID == "" {
+		return nil, errors.New("no Gist ID given")
+	}
+
+	client, err := newClient(ctx, opts.Token, opts.HTTPClient, opts.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	gist, _, err := client.Gists.Get(ctx, opts.GistID)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for name, gistFile := range gist.Files {
+		if gistFile.Content == nil {
+			continue
+		}
+		file := File{
+			Name:    string(name),
+			Content: []byte(gistFile.GetContent()),
+			Size:    gistFile.GetSize(),
+		}
+		if file.Size < len(file.Content) {
+			file.Size = len(file.Content)
+		}
+		file.Truncated = len(file.Content) < file.Size
+
+		if len(opts.Key) > 0 && !file.Truncated {
+			if file.Content, err = Decrypt(file.Content, opts.Key); err != nil {
+				return nil, fmt.Errorf("decrypting %s: %w", file.Name, err)
+			}
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// newClient returns a GitHub client using httpClient, or one authenticated with token.
+// With a baseURL, the client talks to that GitHub Enterprise Server instead of github.com.
+func newClient(ctx context.Context, token string, httpClient *http.Client, baseURL string) (*github.Client, error) {
+	if httpClient == nil {
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+		httpClient = oauth2.NewClient(ctx, ts)
+	}
+	if baseURL == "" {
+		return github.NewClient(httpClient), nil
+	}
+	return github.NewEnterpriseClient(baseURL, baseURL, httpClient)
+}
@@ -0,0 +1,67 @@
+package envi
+
+import (
+	"sort"
+	"strings"
+)
+
+// MergeOptions configures Merge
+type MergeOptions struct {
+	// PreferLast keeps the value from the last source that defines a variable.
+	// By default the first value is kept.
+	PreferLast bool
+	// Sort writes variables in alphabetical order instead of the order they first appear in
+	Sort bool
+	// Parse configures how the sources are parsed
+	Parse ParseOptions
+}
+
+// Conflict is a variable defined with different values in two sources
+type Conflict struct {
+	Key       string
+	Kept      string // Value written to the merged content
+	Discarded string // Value that was dropped
+}
+
+// Merge combines the variables of several .env contents, parsed with Parse, into one.
+// Comments are dropped and export/set prefixes are kept. Variables defined with
+// different values are reported as conflicts.
+func Merge(sources [][]byte, opts MergeOptions) ([]byte, []Conflict) {
+	values := make(map[string]string)
+	prefixes := make(map[string]string)
+	var order []string
+	var conflicts []Conflict
+
+	for _, source := range sources {
+		variables, _ := Parse(source, opts.Parse)
+		for _, variable := range variables {
+			key, value, prefix := variable.Key, variable.Value, variable.Prefix
+
+			current, exists := values[key]
+			switch {
+			case !exists:
+				values[key] = value
+				prefixes[key] = prefix
+				order = append(order, key)
+			case current == value:
+				// Same value, nothing to resolve
+			case opts.PreferLast:
+				conflicts = append(conflicts, Conflict{Key: key, Kept: value, Discarded: current})
+				values[key] = value
+				prefixes[key] = prefix
+			default:
+				conflicts = append(conflicts, Conflict{Key: key, Kept: current, Discarded: value})
+			}
+		}
+	}
+
+	if opts.Sort {
+		sort.Strings(order)
+	}
+
+	var b strings.Builder
+	for _, key := range order {
+		b.WriteString(prefixes[key] + key + "=" + values[key] + "\n")
+	}
+	return []byte(b.String()), conflicts
+}
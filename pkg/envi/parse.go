@@ -0,0 +1,161 @@
+package envi
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dexterity-inc/envi/internal/encryption"
+)
+
+// Variable is a variable defined in .env content
+type Variable struct {
+	Key     string
+	Value   string // Value as written, including any quotes; see Unquote
+	Prefix  string // export/set prefix the variable was declared with, if any
+	Comment string // Inline comment stripped from the value, if any
+	Line    int    // 1-based line number
+}
+
+// ParseOptions configures Parse and ParseLine
+type ParseOptions struct {
+	// InlineComments treats a `#` preceded by whitespace in an unquoted value as the
+	// start of a comment, which is stripped into Variable.Comment. By default it is
+	// part of the value.
+	InlineComments bool
+}
+
+// variableRegex matches a KEY=value line once any shell prefix has been stripped
+var variableRegex = regexp.MustCompile(`^([A-Za-z0-9_]+)=(.*)$`)
+
+// Shell prefixes that may precede a variable so the file can be sourced directly
+var linePrefixes = []string{"export ", "set "}
+
+// Parse parses .env content into its variables in file order, keeping every definition
+// of a key, and returns the comment lines separately. Blank lines and lines that don't
+// define a variable are skipped. Lines may be of any length, such as a certificate
+// encoded in base64 on one line.
+func Parse(content []byte, opts ParseOptions) ([]Variable, []string) {
+	var variables []Variable
+	comments := []string{}
+
+	lines, _ := encryption.SplitLines(content)
+	for i, line := range lines {
+		lineNum := i + 1
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") {
+			comments = append(comments, line)
+			continue
+		}
+		if variable, ok := ParseLine(line, opts); ok {
+			variable.Line = lineNum
+			variables = append(variables, variable)
+		}
+	}
+
+	return variables, comments
+}
+
+// ParseLine parses a single KEY=value line, which may start with `export ` or `set `.
+// It reports false for blank lines, comments and anything else that doesn't define a
+// variable. The returned Line is 0.
+func ParseLine(line string, opts ParseOptions) (Variable, bool) {
+	stripped, prefix := SplitPrefix(line)
+	matches := variableRegex.FindStringSubmatch(stripped)
+	if matches == nil {
+		return Variable{}, false
+	}
+
+	value, comment := matches[2], ""
+	if opts.InlineComments {
+		value, comment = splitInlineComment(value)
+	}
+	return Variable{Key: matches[1], Value: value, Prefix: prefix, Comment: comment}, true
+}
+
+// SplitPrefix removes a leading `export ` or `set ` from a line and returns the rest
+// of the line and the prefix that was removed. Lines without a prefix are returned
+// unchanged with an empty prefix.
+func SplitPrefix(line string) (string, string) {
+	trimmed := strings.TrimLeft(line, " \t")
+	for _, prefix := range linePrefixes {
+		if strings.HasPrefix(trimmed, prefix) {
+			return strings.TrimLeft(trimmed[len(prefix):], " \t"), prefix
+		}
+	}
+	return line, ""
+}
+
+// splitInlineComment separates a trailing inline comment from a value. In unquoted
+// values a `#` preceded by whitespace starts the comment; inside quotes `#` is kept
+// literally. It returns the value and the comment (including its `#`), or the value
+// unchanged and "" if there is none.
+func splitInlineComment(value string) (string, string) {
+	if value == "" {
+		return value, ""
+	}
+
+	// Quoted value: only text after the closing quote can be a comment
+	if quote := value[0]; quote == '"' || quote == '\'' {
+		end := strings.IndexByte(value[1:], quote)
+		if end < 0 {
+			return value, ""
+		}
+		end += 2
+		rest := strings.TrimSpace(value[end:])
+		if strings.HasPrefix(rest, "#") {
+			return value[:end], rest
+		}
+		return value, ""
+	}
+
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return strings.TrimRight(value[:i], " \t"), value[i:]
+		}
+	}
+	return value, ""
+}
+
+// Unquote returns the value a Variable.Value stands for: single-quoted values are
+// taken literally, double-quoted values have their escapes undone and unquoted values
+// are returned as they are
+func Unquote(value string) string {
+	if !isQuoted(value) {
+		return value
+	}
+	inner := value[1 : len(value)-1]
+	if value[0] == '\'' {
+		return inner
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] != '\\' || i == len(inner)-1 {
+			b.WriteByte(inner[i])
+			continue
+		}
+		i++
+		switch inner[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$', '`':
+			b.WriteByte(inner[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(inner[i])
+		}
+	}
+	return b.String()
+}
+
+// isQuoted reports whether a value is wrapped in matching single or double quotes
+func isQuoted(value string) bool {
+	if len(value) < 2 {
+		return false
+	}
+	first, last := value[0], value[len(value)-1]
+	return (first == '"' || first == '\'') && first == last
+}
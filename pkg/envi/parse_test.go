@@ -0,0 +1,97 @@
+package envi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	content := []byte("# Database\n" +
+		"DB_HOST=localhost\n" +
+		"\n" +
+		"export API_KEY=abc123\n" +
+		"  set DEBUG=true\n" +
+		`NAME="a #b" # quoted` + "\n" +
+		"PORT=8080 # default port\n" +
+		"not a variable\n" +
+		"  INDENTED=skipped\n" +
+		"DB_HOST=override\n")
+
+	variables, comments := Parse(content, ParseOptions{})
+	want := []Variable{
+		{Key: "DB_HOST", Value: "localhost", Line: 2},
+		{Key: "API_KEY", Value: "abc123", Prefix: "export ", Line: 4},
+		{Key: "DEBUG", Value: "true", Prefix: "set ", Line: 5},
+		{Key: "NAME", Value: `"a #b" # quoted`, Line: 6},
+		{Key: "PORT", Value: "8080 # default port", Line: 7},
+		{Key: "DB_HOST", Value: "override", Line: 10},
+	}
+	if !reflect.DeepEqual(variables, want) {
+		t.Errorf("Parse() variables = %+v, want %+v", variables, want)
+	}
+	if wantComments := []string{"# Database"}; !reflect.DeepEqual(comments, wantComments) {
+		t.Errorf("Parse() comments = %q, want %q", comments, wantComments)
+	}
+
+	// With inline comments, they are split off the values
+	variables, _ = Parse(content, ParseOptions{InlineComments: true})
+	if got := variables[3]; got.Value != `"a #b"` || got.Comment != "# quoted" {
+		t.Errorf("NAME = %q with comment %q, want %q with comment %q", got.Value, got.Comment, `"a #b"`, "# quoted")
+	}
+	if got := variables[4]; got.Value != "8080" || got.Comment != "# default port" {
+		t.Errorf("PORT = %q with comment %q, want %q with comment %q", got.Value, got.Comment, "8080", "# default port")
+	}
+}
+
+func TestParseLongLines(t *testing.T) {
+	// A certificate in base64 on one line is longer than bufio.Scanner's default limit
+	cert := strings.Repeat("TUlJQ2pEQ0NBWFNnQXdJQkFnSUo=", 10000)
+	content := []byte("BEFORE=1\r\nCERT=" + cert + "\r\nAFTER=2\r\n")
+
+	variables, _ := Parse(content, ParseOptions{})
+	want := []Variable{
+		{Key: "BEFORE", Value: "1", Line: 1},
+		{Key: "CERT", Value: cert, Line: 2},
+		{Key: "AFTER", Value: "2", Line: 3},
+	}
+	if !reflect.DeepEqual(variables, want) {
+		t.Errorf("Parse() found %d variables, want BEFORE, a %d-byte CERT and AFTER", len(variables), len(cert))
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"${HOST}/db", "${HOST}/db"},
+		{`'single $literal \n'`, `single $literal \n`},
+		{`"a\nb"`, "a\nb"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"\$HOME \\ \` + "`" + `"`, "$HOME \\ `"},
+		{`"unknown \q escape"`, `unknown \q escape`},
+		{`"unterminated`, `"unterminated`},
+		{`""`, ""},
+	}
+	for _, tt := range tests {
+		if got := Unquote(tt.value); got != tt.want {
+			t.Errorf("Unquote(%s) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMergeUsesParse(t *testing.T) {
+	merged, conflicts := Merge([][]byte{
+		[]byte("# comment\nexport A=1\n  B=indented\nC=3 # note\n"),
+		[]byte("A=2\nD=4\n"),
+	}, MergeOptions{Parse: ParseOptions{InlineComments: true}})
+
+	if want := "export A=1\nC=3\nD=4\n"; string(merged) != want {
+		t.Errorf("Merge() = %q, want %q", merged, want)
+	}
+	if want := []Conflict{{Key: "A", Kept: "1", Discarded: "2"}}; !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("Merge() conflicts = %+v, want %+v", conflicts, want)
+	}
+}